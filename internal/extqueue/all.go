@@ -1,6 +1,6 @@
 // Package extqueue contains many different concurrent queue algorithms, tests and benchmarks
 //
-// All names follow  a convention: "[SM]P[SM]C[sw]?i?p?[rnacq]<variant>""
+// All names follow  a convention: "[SM]P[SM]C[sw]?i?p?[rnacqh]<variant>""
 //
 //     [SM]P:
 //        supports either single `S` or multiple `M` concurrent producers
@@ -8,12 +8,13 @@
 //     [SM]C:
 //        supports either single `S` or multiple `M` concurrent consumers
 //
-//     [rnacq]: buffer implementation
+//     [rnacqh]: buffer implementation
 //        `r` dynamically sized ring buffer
 //        `n` node based,
 //        `a` fixed size array based,
 //        `c` channel based,
 //        `q` dynamically sized ring buffer with sequence number.
+//        `h` dynamically sized binary heap.
 //
 //     [sw]?: waiting behavior
 //        `` : when it is a waiting implementation (no CPU burn)
@@ -32,6 +33,9 @@
 //        special variant identifier for a particular implementation,
 //        which indicates either base implementation author / paper / code.
 //
+// Work-stealing deques and broadcast queues don't fit the producer/consumer shapes
+// and are named "Deque<variant>" and "Broadcast<variant>".
+//
 // Guideline for selecting an implementation:
 //
 // 1. Select the minimal producers and consumers that you need.
@@ -42,6 +46,8 @@
 // they do burn CPU while they spin, this can start affecting everything else in the system.
 // If you do not have real-time requirements then using a non-spinning or partially spinning
 // implementation, is probably better, because the queue is a better citizen.
+// Bounded spinning queues accept WithWaitStrategy to tune this per deployment,
+// for example Hybrid spins for a while before parking the goroutine.
 //
 // 3. When you need to copy large values, then you using an intrusive implementation
 // can be helpful. It allows to allocate and fill the sent node by letting the producer
@@ -57,6 +63,16 @@
 // 6. Some queues here support batching. They tend to be faster, however care must be taken
 // to properly flush the batches with FlushSend and FlushRecv, otherwise the queue can deadlock.
 //
+// 7. When dropping old values is preferable to blocking producers, e.g. for telemetry,
+// use the overwriting OW queues. Dropped reports how many values the consumer missed.
+//
+// 8. When values have different priorities, use MPMCqsPL for a few fixed priority lanes,
+// optionally with WithLaneWeights to avoid starving the lower priorities,
+// or MPMCnsLS for arbitrary priorities.
+//
+// 9. When values should be received only after a deadline, e.g. for retries,
+// use MPMChDL with SendAt or SendAfter instead of sleeping in the consumer.
+//
 // However, the most reliable way is to write a realistic benchmark for your situtation and
 // see what works the best. This package contains a convenient way to implement them.
 //
@@ -66,16 +82,16 @@
 //    		b.Skip("does not support multiple producers")
 //    	}
 //    	b.Run("Basic", func(b *testing.B) {
-//    		q := create().(testsuite.MPSC)
+//    		q := create().(testsuite.MPSC[int64])
 //    		testsuite.ProducerConsumerBenchmark(b,
 //    			4, 1, // 4 producers and 1 consumer
 //    			func(int) {
 //    				for i := 0; i < b.N; i++ {
-//    					q.Send(T(i))
+//    					q.Send(int64(i))
 //    				}
 //    			}, func(int) {
 //    				for i := 0; i < 4*b.N; i++ {
-//    					var v T
+//    					var v int64
 //    					q.Recv(&v)
 //    				}
 //    			})
@@ -85,85 +101,294 @@
 package extqueue
 
 import (
+	"time"
+
 	"loov.dev/queue/internal/testsuite"
 )
 
 ////go:generate go run all_gen.go -out all_test.go
 
-var All = testsuite.Descs{
-	{
-		Name:   "MPMCcGo",
-		Param:  testsuite.ParamSize,
-		Create: func(bs, s int) testsuite.Queue { return NewMPMCcGo[testsuite.Value](s) }},
-	{
-		Name:   "MPMCqGo",
-		Param:  testsuite.ParamSize,
-		Create: func(bs, s int) testsuite.Queue { return NewMPMCqGo[testsuite.Value](s) }},
-	{
-		Name:   "MPMCqpGo",
-		Param:  testsuite.ParamSize,
-		Create: func(bs, s int) testsuite.Queue { return NewMPMCqpGo[testsuite.Value](s) }},
-
-	{
-		Name:   "SPSCrMC",
-		Param:  testsuite.ParamBatchSizeAndSize,
-		Create: func(bs, s int) testsuite.Queue { return NewSPSCrMC[testsuite.Value](bs, s) }},
-	{
-		Name:   "SPSCrsMC",
-		Param:  testsuite.ParamBatchSizeAndSize,
-		Create: func(bs, s int) testsuite.Queue { return NewSPSCrsMC[testsuite.Value](bs, s) }},
-	{
-		Name:   "MPSCrMC",
-		Param:  testsuite.ParamBatchSizeAndSize,
-		Create: func(bs, s int) testsuite.Queue { return NewMPSCrMC[testsuite.Value](bs, s) }},
-	{
-		Name:   "MPSCrsMC",
-		Param:  testsuite.ParamBatchSizeAndSize,
-		Create: func(bs, s int) testsuite.Queue { return NewMPSCrsMC[testsuite.Value](bs, s) }},
-
-	{
-		Name:   "SPSCnsDV",
-		Param:  testsuite.ParamNone,
-		Create: func(bs, s int) testsuite.Queue { return NewSPSCnsDV[testsuite.Value]() }},
-	{
-		Name:   "MPSCnsDV",
-		Param:  testsuite.ParamNone,
-		Create: func(bs, s int) testsuite.Queue { return NewMPSCnsDV[testsuite.Value]() }},
-	{
-		Name:   "MPSCnsiDV",
-		Param:  testsuite.ParamNone,
-		Create: func(bs, s int) testsuite.Queue { return NewMPSCnsiDV[testsuite.Value]() }},
-
-	{
-		Name:   "MPMCqsDV",
-		Param:  testsuite.ParamSize,
-		Create: func(bs, s int) testsuite.Queue { return NewMPMCqsDV[testsuite.Value](s) }},
-	{
-		Name:   "MPMCqspDV",
-		Param:  testsuite.ParamSize,
-		Create: func(bs, s int) testsuite.Queue { return NewMPMCqspDV[testsuite.Value](s) }},
-	{
-		Name:   "SPMCqsDV",
-		Param:  testsuite.ParamSize,
-		Create: func(bs, s int) testsuite.Queue { return NewSPMCqsDV[testsuite.Value](s) }},
-	{
-		Name:   "SPMCqspDV",
-		Param:  testsuite.ParamSize,
-		Create: func(bs, s int) testsuite.Queue { return NewSPMCqspDV[testsuite.Value](s) }},
-	{
-		Name:   "MPSCqsDV",
-		Param:  testsuite.ParamSize,
-		Create: func(bs, s int) testsuite.Queue { return NewMPSCqsDV[testsuite.Value](s) }},
-	{
-		Name:   "MPSCqspDV",
-		Param:  testsuite.ParamSize,
-		Create: func(bs, s int) testsuite.Queue { return NewMPSCqspDV[testsuite.Value](s) }},
-	{
-		Name:   "SPSCqsDV",
-		Param:  testsuite.ParamSize,
-		Create: func(bs, s int) testsuite.Queue { return NewSPSCqsDV[testsuite.Value](s) }},
-	{
-		Name:   "SPSCqspDV",
-		Param:  testsuite.ParamSize,
-		Create: func(bs, s int) testsuite.Queue { return NewSPSCqspDV[testsuite.Value](s) }},
+// All contains all queue implementations with int64 values.
+var All = AllOf[int64]()
+
+// AllOf returns all queue implementations with values of type T.
+func AllOf[T any]() testsuite.Descs[T] {
+	return testsuite.Descs[T]{
+		{
+			Name:   "MPMCcGo",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCcGo[T](s) }},
+		{
+			Name:   "MPMCqGo",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCqGo[T](s) }},
+		{
+			Name:   "MPMCqpGo",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCqpGo[T](s) }},
+
+		{
+			Name:   "SPSCrMC",
+			Param:  testsuite.ParamBatchSizeAndSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPSCrMC[T](bs, s) }},
+		{
+			Name:   "SPSCrsMC",
+			Param:  testsuite.ParamBatchSizeAndSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPSCrsMC[T](bs, s) }},
+		{
+			Name:   "MPSCrMC",
+			Param:  testsuite.ParamBatchSizeAndSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCrMC[T](bs, s) }},
+		{
+			Name:   "MPSCrsMC",
+			Param:  testsuite.ParamBatchSizeAndSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCrsMC[T](bs, s) }},
+
+		{
+			Name:   "SPSCnsDV",
+			Param:  testsuite.ParamNone,
+			Create: func(bs, s int) testsuite.Queue { return NewSPSCnsDV[T]() }},
+		{
+			Name:   "MPSCnsDV",
+			Param:  testsuite.ParamNone,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCnsDV[T]() }},
+		{
+			Name:   "MPSCnsiDV",
+			Param:  testsuite.ParamNone,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCnsiDV[T]() }},
+		{
+			Name:   "SPSCnsSG",
+			Param:  testsuite.ParamNone,
+			Create: func(bs, s int) testsuite.Queue { return NewSPSCnsSG[T]() }},
+		{
+			Name:   "MPSCnsSG",
+			Param:  testsuite.ParamNone,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCnsSG[T]() }},
+		{
+			Name:   "MPSCnwFL",
+			Param:  testsuite.ParamNone,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCnwFL[T]() }},
+		{
+			Name:   "MPMCnsMS",
+			Param:  testsuite.ParamNone,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCnsMS[T]() }},
+		{
+			Name:   "MPMCnsLSCQ",
+			Param:  testsuite.ParamNone,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCnsLSCQ[T]() }},
+
+		{
+			Name:   "MPMCqsDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCqsDV[T](s) }},
+		{
+			Name:   "MPMCqspDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCqspDV[T](s) }},
+		{
+			Name:   "SPMCqsDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPMCqsDV[T](s) }},
+		{
+			Name:   "SPMCqspDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPMCqspDV[T](s) }},
+		{
+			Name:   "MPSCqsDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCqsDV[T](s) }},
+		{
+			Name:   "MPSCqspDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCqspDV[T](s) }},
+		{
+			Name:   "SPSCqsDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPSCqsDV[T](s) }},
+		{
+			Name:   "SPSCqspDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPSCqspDV[T](s) }},
+		{
+			Name:   "MPMCqsSCQ",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCqsSCQ[T](s) }},
+		{
+			Name:   "MPSCqsOW",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCqsOW[T](s) }},
+		{
+			Name:   "SPSCqsOW",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPSCqsOW[T](s) }},
+		{
+			Name:   "MPMCqsPL",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCqsPL[T](4, s) }},
+		{
+			Name:   "MPMCnsLS",
+			Param:  testsuite.ParamNone,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCnsLS[T]() }},
+		{
+			Name:   "MPMChDL",
+			Param:  testsuite.ParamNone,
+			Create: func(bs, s int) testsuite.Queue { return newDelayDL[T]() }},
+
+		{
+			Name:   "MPMCqDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCqDV[T](s) }},
+		{
+			Name:   "MPMCqpDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCqpDV[T](s) }},
+		{
+			Name:   "SPMCqDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPMCqDV[T](s) }},
+		{
+			Name:   "SPMCqpDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPMCqpDV[T](s) }},
+		{
+			Name:   "MPSCqDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCqDV[T](s) }},
+		{
+			Name:   "MPSCqpDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCqpDV[T](s) }},
+		{
+			Name:   "SPSCqDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPSCqDV[T](s) }},
+		{
+			Name:   "SPSCqpDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPSCqpDV[T](s) }},
+
+		{
+			Name:   "MPMCrsOR",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCrsOR[T](s) }},
+		{
+			Name:   "MPSCrsOR",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCrsOR[T](s) }},
+		{
+			Name:   "SPMCrsOR",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPMCrsOR[T](s) }},
+		{
+			Name:   "SPSCrsOR",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPSCrsOR[T](s) }},
+
+		{
+			Name:   "DequeCL",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewDequeCL[T](s) }},
+		{
+			Name:   "BroadcastDR",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return broadcastDR[T]{NewBroadcastDR[T](s)} }},
+	}
 }
+
+// AllWith returns the queue implementations, which accept options,
+// with values of type T created with opts.
+// Creating a queue panics when it doesn't support one of the options.
+func AllWith[T any](opts ...Option) testsuite.Descs[T] {
+	return testsuite.Descs[T]{
+		{
+			Name:   "MPMCqGo",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCqGo[T](s, opts...) }},
+		{
+			Name:   "MPMCqpGo",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCqpGo[T](s, opts...) }},
+		{
+			Name:   "SPSCrMC",
+			Param:  testsuite.ParamBatchSizeAndSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPSCrMC[T](bs, s, opts...) }},
+		{
+			Name:   "MPMCqsDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCqsDV[T](s, opts...) }},
+		{
+			Name:   "MPSCqsDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCqsDV[T](s, opts...) }},
+		{
+			Name:   "SPMCqsDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPMCqsDV[T](s, opts...) }},
+		{
+			Name:   "SPSCqsDV",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPSCqsDV[T](s, opts...) }},
+		{
+			Name:   "MPMCqsSCQ",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCqsSCQ[T](s, opts...) }},
+		{
+			Name:   "MPSCqsOW",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCqsOW[T](s, opts...) }},
+		{
+			Name:   "MPMCqsPL",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCqsPL[T](plLanes(opts), s, opts...) }},
+		{
+			Name:   "MPMCnsLS",
+			Param:  testsuite.ParamNone,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCnsLS[T](opts...) }},
+		{
+			Name:   "MPMCrsOR",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPMCrsOR[T](s, opts...) }},
+		{
+			Name:   "MPSCrsOR",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewMPSCrsOR[T](s, opts...) }},
+		{
+			Name:   "SPMCrsOR",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPMCrsOR[T](s, opts...) }},
+		{
+			Name:   "SPSCrsOR",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return NewSPSCrsOR[T](s, opts...) }},
+		{
+			Name:   "BroadcastDR",
+			Param:  testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue { return broadcastDR[T]{NewBroadcastDR[T](s, opts...)} }},
+	}
+}
+
+// plLanes returns a lane for every weight in opts, otherwise 4 lanes.
+func plLanes(opts []Option) int {
+	if weights := newConfig(opts).laneWeights; len(weights) > 0 {
+		return len(weights)
+	}
+	return 4
+}
+
+// broadcastDR adapts BroadcastDR to testsuite.Broadcast.
+type broadcastDR[T any] struct{ *BroadcastDR[T] }
+
+func (q broadcastDR[T]) Subscribe() testsuite.Subscriber[T] { return q.BroadcastDR.Subscribe() }
+
+// delayDL runs MPMChDL with a fake clock, so that the tests can control time.
+type delayDL[T any] struct {
+	*MPMChDL[T]
+	clock *testsuite.FakeClock
+}
+
+func newDelayDL[T any]() delayDL[T] {
+	clock := testsuite.NewFakeClock(time.Unix(0, 0))
+	return delayDL[T]{NewMPMChDL[T](WithClock(clock)), clock}
+}
+
+func (q delayDL[T]) FakeClock() *testsuite.FakeClock { return q.clock }
@@ -26,6 +26,11 @@ var Impls = []Impl{
 	{"SPSCnsDV", Blocking | Nonblocking | Unbounded},
 	{"MPSCnsDV", Blocking | Nonblocking | Unbounded},
 	// {"MPSCnsiDV", Blocking | Nonblocking | Unbounded},
+	{"SPSCnsSG", Blocking | Nonblocking | Unbounded},
+	{"MPSCnsSG", Blocking | Nonblocking | Unbounded},
+	{"MPSCnwFL", Blocking | Nonblocking | Unbounded},
+	{"MPMCnsMS", Blocking | Nonblocking | Unbounded},
+	{"MPMCnsLSCQ", Blocking | Nonblocking | Unbounded},
 
 	{"MPMCqsDV", Blocking | Nonblocking},
 	{"MPMCqspDV", Blocking | Nonblocking},
@@ -33,6 +38,19 @@ var Impls = []Impl{
 	{"SPMCqspDV", Blocking | Nonblocking},
 	{"SPSCqsDV", Blocking | Nonblocking},
 	{"SPSCqspDV", Blocking | Nonblocking},
+	{"MPMCqsSCQ", Blocking | Nonblocking},
+
+	{"MPMCqDV", Blocking | Nonblocking},
+	{"MPMCqpDV", Blocking | Nonblocking},
+	{"SPMCqDV", Blocking | Nonblocking},
+	{"SPMCqpDV", Blocking | Nonblocking},
+	{"SPSCqDV", Blocking | Nonblocking},
+	{"SPSCqpDV", Blocking | Nonblocking},
+
+	{"MPMCrsOR", Blocking | Nonblocking},
+	{"MPSCrsOR", Blocking | Nonblocking},
+	{"SPMCrsOR", Blocking | Nonblocking},
+	{"SPSCrsOR", Blocking | Nonblocking},
 }
 
 type Flag int
@@ -67,15 +85,15 @@ func (impl *Impl) New() string {
 	batched, bounded := impl.Batched(), impl.Bounded()
 	switch {
 	case !batched && bounded:
-		return "New" + impl.Name + "(size)"
+		return "New" + impl.Name + "[int64](size)"
 	case batched && bounded:
-		return "New" + impl.Name + "(batchSize, size)"
+		return "New" + impl.Name + "[int64](batchSize, size)"
 	case !batched && !bounded:
-		return "New" + impl.Name + "()"
+		return "New" + impl.Name + "[int64]()"
 	case batched && !bounded:
-		return "New" + impl.Name + "(batchSize)"
+		return "New" + impl.Name + "[int64](batchSize)"
 	}
-	return "New" + impl.Name + "()"
+	return "New" + impl.Name + "[int64]()"
 }
 
 func (impl *Impl) Bounded() bool     { return !impl.Unbounded() }
@@ -132,7 +150,7 @@ import (
 {{ $impl := . }}
 
 {{ range .Faces -}}
-var _ testsuite.{{ . }} = (*{{$impl.Name}})(nil)
+var _ testsuite.{{ . }}[int64] = (*{{$impl.Name}}[int64])(nil)
 {{ end }}
 
 func Test{{.Name}}(t *testing.T) {
@@ -147,7 +165,7 @@ func Test{{.Name}}(t *testing.T) {
 		size := 0;
 		{{- end -}}
 			name := "b" + strconv.Itoa(batchSize) + "s" + strconv.Itoa(size)
-			t.Run(name, func(t *testing.T){ testsuite.Tests(t, func() testsuite.Queue { return {{.New}} }) })
+			t.Run(name, func(t *testing.T){ testsuite.Tests(t, testsuite.Int64, func() testsuite.Queue { return {{.New}} }) })
 		{{- if .Bounded -}}
 		}
 		{{- end -}}
@@ -168,7 +186,7 @@ func Benchmark{{.Name}}(b *testing.B) {
 		size := 0;
 		{{- end -}}
 			name := strconv.Itoa(batchSize) + "s" + strconv.Itoa(size)
-			b.Run(name, func(b *testing.B){ testsuite.Benchmarks(b, func() testsuite.Queue { return {{.New}} }) })
+			b.Run(name, func(b *testing.B){ testsuite.Benchmarks[int64](b, func() testsuite.Queue { return {{.New}} }) })
 		{{- if .Bounded -}}
 		}
 		{{- end -}}
@@ -1,8 +1,92 @@
 package extqueue
 
 import (
+	"fmt"
+	"runtime"
 	"testing"
+
+	"loov.dev/queue/internal/testsuite"
 )
 
-func Test(t *testing.T)      { All.TestDefault(t) }
+func Test(t *testing.T)      { All.TestDefault(t, testsuite.Int64) }
 func Benchmark(b *testing.B) { All.BenchmarkDefault(b) }
+
+func TestString(t *testing.T)      { AllOf[string]().TestDefault(t, testsuite.String) }
+func TestPointer(t *testing.T)     { AllOf[*int64]().TestDefault(t, testsuite.Pointer) }
+func TestLargeStruct(t *testing.T) { AllOf[testsuite.Large]().TestDefault(t, testsuite.LargeStruct) }
+
+func TestWaitStrategy(t *testing.T) {
+	strategies := []struct {
+		Name     string
+		Strategy WaitStrategy
+	}{
+		{"BusySpin", BusySpin},
+		{"Yield", Yield},
+		{"Backoff", Backoff},
+		{"Park", Park},
+		{"Hybrid", Hybrid(64)},
+	}
+
+	for _, strategy := range strategies {
+		if strategy.Name == "BusySpin" && runtime.GOMAXPROCS(0) < 4 {
+			// busy spinning producers starve the consumer with few processors
+			continue
+		}
+		descs := AllWith[int64](WithWaitStrategy(strategy.Strategy)).Select(
+			"MPMCqsDV", "MPSCqsDV", "SPMCqsDV", "SPSCqsDV", "MPMCqsSCQ", "MPSCqsOW",
+			"MPMCrsOR", "MPSCrsOR", "SPMCrsOR", "SPSCrsOR", "BroadcastDR", "MPMCqsPL", "MPMCnsLS")
+		t.Run(strategy.Name, func(t *testing.T) { descs.TestDefault(t, testsuite.Int64) })
+	}
+}
+
+func TestWaitStrategyUnsupported(t *testing.T) {
+	opt := WithWaitStrategy(Park)
+	for _, desc := range AllWith[int64](opt).Select("MPMCqGo", "MPMCqpGo") {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%v: expected panic", desc.Name)
+				}
+			}()
+			desc.Create(0, 8)
+		}()
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	policies := []struct {
+		Policy Overflow
+		Option Option
+	}{
+		{OverflowFail, WithOverflow(OverflowFail)},
+		{OverflowDropNewest, WithOverflow(OverflowDropNewest)},
+		{OverflowDropOldest, WithOverflow(OverflowDropOldest)},
+		{OverflowHandler, WithOverflowHandler(func(int64) {})},
+	}
+
+	for _, policy := range policies {
+		names := []string{"MPMCqGo", "MPMCqpGo", "MPMCqsDV"}
+		if policy.Policy != OverflowDropOldest {
+			names = append(names, "SPSCrMC")
+		}
+		descs := AllWith[int64](policy.Option).Select(names...)
+		t.Run(policy.Policy.String(), func(t *testing.T) { descs.TestDefault(t, testsuite.Int64) })
+	}
+}
+
+func TestLaneWeights(t *testing.T) {
+	weights := [][]int{
+		{1, 1},
+		{4, 2, 1},
+		{1, 3, 9, 1},
+	}
+
+	for _, w := range weights {
+		descs := AllWith[int64](WithLaneWeights(w...)).Select("MPMCqsPL")
+		t.Run(fmt.Sprint(w), func(t *testing.T) { descs.TestDefault(t, testsuite.Int64) })
+	}
+}
+
+func Fuzz(f *testing.F) { All.Fuzz(f, testsuite.Int64) }
+
+func TestRetention(t *testing.T) { AllOf[*testsuite.Tracked]().Test(t, testsuite.Retention) }
@@ -0,0 +1,129 @@
+package extqueue
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// DequeCL is a work-stealing deque based on
+// "Dynamic Circular Work-Stealing Deque" by David Chase and Yossi Lev and
+// "Correct and Efficient Work-Stealing for Weak Memory Models" by Nhat Minh Lê et al.
+//
+// The owner pushes and pops values at the bottom, thieves steal values from the top.
+// The array grows when it is full, old arrays are left to the garbage collector.
+//
+// A thief may read a slot at the same time as the owner overwrites it, the thief
+// then fails to claim the value. To keep such reads safe, values are boxed.
+type DequeCL[T any] struct {
+	_      [8]uint64
+	top    int64
+	_      [7]uint64
+	bottom int64
+	_      [7]uint64
+	array  unsafe.Pointer // *clArray
+	_      [7]uint64
+}
+
+type clArray struct {
+	mask  int64
+	slots []unsafe.Pointer
+}
+
+func newCLArray(size int64) *clArray {
+	return &clArray{
+		mask:  size - 1,
+		slots: make([]unsafe.Pointer, size),
+	}
+}
+
+func (a *clArray) load(i int64) unsafe.Pointer     { return atomic.LoadPointer(&a.slots[i&a.mask]) }
+func (a *clArray) store(i int64, p unsafe.Pointer) { atomic.StorePointer(&a.slots[i&a.mask], p) }
+func (a *clArray) cas(i int64, old, new unsafe.Pointer) bool {
+	return atomic.CompareAndSwapPointer(&a.slots[i&a.mask], old, new)
+}
+
+// NewDequeCL creates a DequeCL with the specified initial size
+func NewDequeCL[T any](size int) *DequeCL[T] {
+	if size <= 1 {
+		size = 2
+	}
+	size = int(nextPowerOfTwo(uint32(size - 1)))
+
+	q := &DequeCL[T]{}
+	q.array = unsafe.Pointer(newCLArray(int64(size)))
+	return q
+}
+
+// PushBottom adds a value to the bottom of the deque, must only be called by the owner
+func (q *DequeCL[T]) PushBottom(v T) {
+	b := atomic.LoadInt64(&q.bottom)
+	t := atomic.LoadInt64(&q.top)
+	a := (*clArray)(atomic.LoadPointer(&q.array))
+	if b-t > a.mask {
+		a = q.grow(a, t, b)
+	}
+
+	a.store(b, unsafe.Pointer(&v))
+	atomic.StoreInt64(&q.bottom, b+1)
+}
+
+// PopBottom takes the most recently pushed value from the bottom of the deque,
+// must only be called by the owner, returns false when the deque is empty
+func (q *DequeCL[T]) PopBottom(v *T) bool {
+	b := atomic.LoadInt64(&q.bottom) - 1
+	a := (*clArray)(atomic.LoadPointer(&q.array))
+	atomic.StoreInt64(&q.bottom, b)
+
+	t := atomic.LoadInt64(&q.top)
+	if t > b {
+		// deque was empty
+		atomic.StoreInt64(&q.bottom, b+1)
+		return false
+	}
+
+	p := a.load(b)
+	if t == b {
+		// last value, race against thieves
+		won := atomic.CompareAndSwapInt64(&q.top, t, t+1)
+		atomic.StoreInt64(&q.bottom, b+1)
+		if !won {
+			return false
+		}
+	}
+
+	// no thief can claim this slot anymore
+	a.store(b, nil)
+	*v = *(*T)(p)
+	return true
+}
+
+// Steal takes the least recently pushed value from the top of the deque,
+// returns false when the deque is empty
+func (q *DequeCL[T]) Steal(v *T) bool {
+	for try := 0; ; spin(&try) {
+		t := atomic.LoadInt64(&q.top)
+		b := atomic.LoadInt64(&q.bottom)
+		if t >= b {
+			return false
+		}
+
+		a := (*clArray)(atomic.LoadPointer(&q.array))
+		p := a.load(t)
+		if atomic.CompareAndSwapInt64(&q.top, t, t+1) {
+			// the owner may have already reused the slot
+			a.cas(t, p, nil)
+			*v = *(*T)(p)
+			return true
+		}
+	}
+}
+
+// grow doubles the array, must only be called by the owner.
+func (q *DequeCL[T]) grow(a *clArray, t, b int64) *clArray {
+	next := newCLArray(2 * (a.mask + 1))
+	for i := t; i < b; i++ {
+		next.store(i, a.load(i))
+	}
+	atomic.StorePointer(&q.array, unsafe.Pointer(next))
+	return next
+}
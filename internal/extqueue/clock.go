@@ -0,0 +1,31 @@
+package extqueue
+
+import "time"
+
+// Clock is the source of time for delay queues.
+//
+// It can be replaced with WithClock, e.g. to use a fake clock in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// AtFunc calls f once the time reaches at,
+	// stop cancels the call and returns whether it was canceled.
+	//
+	// The deadline is absolute, so that a clock moving between Now and
+	// AtFunc doesn't delay the call.
+	AtFunc(at time.Time, f func()) (stop func() bool)
+}
+
+// WithClock sets the clock for delay queues.
+func WithClock(clock Clock) Option {
+	return func(c *config) { c.clock = clock }
+}
+
+// systemClock uses the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) AtFunc(at time.Time, f func()) func() bool {
+	return time.AfterFunc(time.Until(at), f).Stop
+}
@@ -32,6 +32,15 @@ type seqPaddedValue32[T any] struct {
 	_        [8 - 2]int32
 }
 
+// sendClosed is added to the send position of a ring buffer by Close,
+// which is the position that senders claim or, with a single producer, commit.
+//
+// Senders racing with Close fail to claim the position, because it has changed,
+// and afterwards the position is far ahead of the receivers, hence the queue
+// looks full to the senders. The receivers know that the queue has been drained
+// when the send position is exactly sendClosed ahead of them.
+const sendClosed = 1 << 62
+
 func ceil(a, n int) int {
 	r := ((a + n - 1) / n) * n
 	if r <= n {
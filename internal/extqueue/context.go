@@ -0,0 +1,58 @@
+package extqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// canceled returns whether done has been closed, nil done is never closed.
+func canceled(done <-chan struct{}) bool {
+	if done == nil {
+		return false
+	}
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// sendTimeout calls sendContext with a context that expires after timeout.
+func sendTimeout[T any](sendContext func(context.Context, T) bool, v T, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return sendContext(ctx, v)
+}
+
+// recvTimeout calls recvContext with a context that expires after timeout.
+func recvTimeout[T any](recvContext func(context.Context, *T) bool, v *T, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return recvContext(ctx, v)
+}
+
+// waitCond waits on cond, additionally it wakes up when done is closed.
+//
+// The caller must check done while holding cond.L,
+// otherwise the wakeup may be missed.
+func waitCond(cond *sync.Cond, done <-chan struct{}) {
+	if done == nil {
+		cond.Wait()
+		return
+	}
+
+	release := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			cond.L.Lock()
+			cond.Broadcast()
+			cond.L.Unlock()
+		case <-release:
+		}
+	}()
+	cond.Wait()
+	close(release)
+}
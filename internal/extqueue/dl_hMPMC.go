@@ -0,0 +1,207 @@
+package extqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MPMChDL is an unbounded MPMC delay queue, where a value can be received
+// only after its deadline has passed. Values are received in the order of
+// their deadlines, values with the same deadline in the order they were sent.
+//
+// Send makes the value available immediately, SendAt and SendAfter delay it.
+// Receivers park until the earliest deadline, hence waiting for delayed
+// values doesn't burn CPU or need a goroutine per value.
+type MPMChDL[T any] struct {
+	clock Clock
+
+	mu      sync.Mutex
+	heap    []dlItem[T]
+	seq     uint64
+	closed  bool
+	waiting int
+	// wake is closed when the earliest deadline changes
+	wake chan struct{}
+}
+
+type dlItem[T any] struct {
+	at    time.Time
+	seq   uint64
+	value T
+}
+
+func (a *dlItem[T]) before(b *dlItem[T]) bool {
+	if !a.at.Equal(b.at) {
+		return a.at.Before(b.at)
+	}
+	return a.seq < b.seq
+}
+
+// NewMPMChDL creates a MPMChDL queue
+func NewMPMChDL[T any](opts ...Option) *MPMChDL[T] {
+	cfg := newConfig(opts)
+	blockOnOverflow(cfg)
+	return &MPMChDL[T]{clock: cfg.clock}
+}
+
+// MultipleConsumers makes this a MC queue
+func (q *MPMChDL[T]) MultipleConsumers() {}
+
+// MultipleProducers makes this a MP queue
+func (q *MPMChDL[T]) MultipleProducers() {}
+
+// Close closes the queue for sending, values that are already in the queue
+// can still be received once their deadline passes
+func (q *MPMChDL[T]) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.notify()
+	q.mu.Unlock()
+}
+
+// Send sends a value to the queue, which can be received immediately,
+// returns false when the queue has been closed
+func (q *MPMChDL[T]) Send(v T) bool { return q.send(v, time.Time{}, true) }
+
+// SendAt sends a value to the queue, which can be received after at,
+// returns false when the queue has been closed
+func (q *MPMChDL[T]) SendAt(v T, at time.Time) bool { return q.send(v, at, false) }
+
+// SendAfter sends a value to the queue, which can be received after d has elapsed,
+// returns false when the queue has been closed
+func (q *MPMChDL[T]) SendAfter(v T, d time.Duration) bool {
+	return q.send(v, q.clock.Now().Add(d), false)
+}
+
+// TrySend sends a value to the queue, always succeeds unless the queue has been closed
+func (q *MPMChDL[T]) TrySend(v T) bool { return q.Send(v) }
+
+// SendContext sends a value to the queue, the queue never blocks so it never waits for ctx
+func (q *MPMChDL[T]) SendContext(ctx context.Context, v T) bool { return q.Send(v) }
+
+// SendTimeout sends a value to the queue, the queue never blocks so it never waits for timeout
+func (q *MPMChDL[T]) SendTimeout(v T, timeout time.Duration) bool { return q.Send(v) }
+
+func (q *MPMChDL[T]) send(v T, at time.Time, now bool) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	if now {
+		// reading the clock under the lock keeps Send in FIFO order
+		at = q.clock.Now()
+	}
+
+	q.seq++
+	q.heap = append(q.heap, dlItem[T]{at: at, seq: q.seq, value: v})
+	if q.up(len(q.heap)-1) == 0 {
+		q.notify()
+	}
+	return true
+}
+
+// Recv receives the value with the earliest deadline and blocks until it passes,
+// returns false when the queue has been closed and drained
+func (q *MPMChDL[T]) Recv(v *T) bool { return q.recv(v, true, nil) }
+
+// RecvContext receives the value with the earliest deadline and blocks until it passes,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPMChDL[T]) RecvContext(ctx context.Context, v *T) bool {
+	return q.recv(v, true, ctx.Done())
+}
+
+// RecvTimeout receives the value with the earliest deadline and blocks until it passes,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPMChDL[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
+
+// TryRecv receives a value whose deadline has passed and returns when there is none
+func (q *MPMChDL[T]) TryRecv(v *T) bool { return q.recv(v, false, nil) }
+
+func (q *MPMChDL[T]) recv(v *T, block bool, done <-chan struct{}) bool {
+	q.mu.Lock()
+	for {
+		if len(q.heap) > 0 && !q.heap[0].at.After(q.clock.Now()) {
+			*v = q.pop()
+			q.mu.Unlock()
+			return true
+		}
+		if !block || (q.closed && len(q.heap) == 0) || canceled(done) {
+			q.mu.Unlock()
+			return false
+		}
+
+		if q.wake == nil {
+			q.wake = make(chan struct{})
+		}
+		wake := q.wake
+		var expired chan struct{}
+		stop := func() bool { return false }
+		if len(q.heap) > 0 {
+			expired = make(chan struct{})
+			stop = q.clock.AtFunc(q.heap[0].at, func() { close(expired) })
+		}
+		q.waiting++
+		q.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-expired:
+		case <-done:
+		}
+		stop()
+
+		q.mu.Lock()
+		q.waiting--
+	}
+}
+
+// notify wakes up receivers, must be called with q.mu held.
+func (q *MPMChDL[T]) notify() {
+	if q.waiting > 0 && q.wake != nil {
+		close(q.wake)
+		q.wake = nil
+	}
+}
+
+// up moves the item at i towards the root and returns its final position.
+func (q *MPMChDL[T]) up(i int) int {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !q.heap[i].before(&q.heap[parent]) {
+			break
+		}
+		q.heap[i], q.heap[parent] = q.heap[parent], q.heap[i]
+		i = parent
+	}
+	return i
+}
+
+// pop removes the item with the earliest deadline.
+func (q *MPMChDL[T]) pop() T {
+	last := len(q.heap) - 1
+	v := q.heap[0].value
+	q.heap[0] = q.heap[last]
+	q.heap[last] = dlItem[T]{}
+	q.heap = q.heap[:last]
+
+	i := 0
+	for {
+		min, left, right := i, 2*i+1, 2*i+2
+		if left < last && q.heap[left].before(&q.heap[min]) {
+			min = left
+		}
+		if right < last && q.heap[right].before(&q.heap[min]) {
+			min = right
+		}
+		if min == i {
+			break
+		}
+		q.heap[i], q.heap[min] = q.heap[min], q.heap[i]
+		i = min
+	}
+	return v
+}
@@ -0,0 +1,250 @@
+package extqueue
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// BroadcastDR is a bounded single-producer broadcast ring based on
+// "Disruptor: High performance alternative to bounded queues for exchanging data between concurrent threads"
+// by Martin Thompson et al.
+//
+// Every subscriber receives every value sent after it subscribed. Each subscriber
+// has its own cursor and the producer is gated by the slowest subscriber.
+// When there are no subscribers, sent values are dropped.
+//
+// Values stay in the ring until the producer overwrites them.
+type BroadcastDR[T any] struct {
+	_      [8]uint64
+	mask   uint64
+	buffer []T
+	closed uint32
+	// waiting
+	sendw, recvw Waiter
+	sendReady    func() bool
+	_            [2]uint64
+
+	// producer
+	sent    uint64
+	gate    uint64
+	sending int64
+	_       [5]uint64
+
+	mu          sync.Mutex
+	subscribers unsafe.Pointer // *[]*BroadcastReaderDR[T]
+	_           [6]uint64
+}
+
+// BroadcastReaderDR is a subscriber of BroadcastDR,
+// it must only be used by a single consumer.
+type BroadcastReaderDR[T any] struct {
+	_         [8]uint64
+	recvx     uint64
+	_         [7]uint64
+	sentCopy  uint64
+	closed    uint32
+	q         *BroadcastDR[T]
+	recvReady func() bool
+	_         [4]uint64
+}
+
+// NewBroadcastDR creates a BroadcastDR with the specified size
+func NewBroadcastDR[T any](size int, opts ...Option) *BroadcastDR[T] {
+	if size <= 1 {
+		size = 2
+	}
+	size = int(nextPowerOfTwo(uint32(size - 1)))
+
+	q := &BroadcastDR[T]{}
+	q.buffer = make([]T, size)
+	q.mask = uint64(size) - 1
+	subs := []*BroadcastReaderDR[T]{}
+	q.subscribers = unsafe.Pointer(&subs)
+
+	cfg := newConfig(opts)
+	blockOnOverflow(cfg)
+	q.sendw = cfg.wait.NewWaiter()
+	q.recvw = cfg.wait.NewWaiter()
+	q.sendReady = q.canSend
+
+	return q
+}
+
+// Cap returns number of elements this queue can hold before blocking
+func (q *BroadcastDR[T]) Cap() int { return len(q.buffer) }
+
+// Close closes the queue for sending, subscribers can still receive values that are already in the queue
+func (q *BroadcastDR[T]) Close() {
+	atomic.StoreUint32(&q.closed, 1)
+	q.sendw.Notify()
+	q.recvw.Notify()
+}
+
+// Subscribe adds a subscriber, which receives all values sent after the call
+func (q *BroadcastDR[T]) Subscribe() *BroadcastReaderDR[T] {
+	r := &BroadcastReaderDR[T]{q: q}
+	r.recvx = atomic.LoadUint64(&q.sent)
+	r.recvReady = r.canRecv
+
+	q.mu.Lock()
+	old := q.loadSubscribers()
+	subs := make([]*BroadcastReaderDR[T], 0, len(old)+1)
+	subs = append(subs, old...)
+	subs = append(subs, r)
+	atomic.StorePointer(&q.subscribers, unsafe.Pointer(&subs))
+	q.mu.Unlock()
+
+	// the producer may have computed the gate without this subscriber,
+	// however such gate cannot be past the values sent after this point
+	atomic.StoreUint64(&r.recvx, atomic.LoadUint64(&q.sent))
+	r.sentCopy = r.recvx
+	return r
+}
+
+func (q *BroadcastDR[T]) unsubscribe(r *BroadcastReaderDR[T]) {
+	q.mu.Lock()
+	old := q.loadSubscribers()
+	subs := make([]*BroadcastReaderDR[T], 0, len(old))
+	for _, s := range old {
+		if s != r {
+			subs = append(subs, s)
+		}
+	}
+	atomic.StorePointer(&q.subscribers, unsafe.Pointer(&subs))
+	q.mu.Unlock()
+}
+
+func (q *BroadcastDR[T]) loadSubscribers() []*BroadcastReaderDR[T] {
+	return *(*[]*BroadcastReaderDR[T])(atomic.LoadPointer(&q.subscribers))
+}
+
+// minCursor returns the position of the slowest subscriber,
+// pos when there are no subscribers.
+func (q *BroadcastDR[T]) minCursor(pos uint64) uint64 {
+	min := pos
+	for _, r := range q.loadSubscribers() {
+		if x := atomic.LoadUint64(&r.recvx); x < min {
+			min = x
+		}
+	}
+	return min
+}
+
+// Send sends a value to all subscribers and blocks when the slowest subscriber is full,
+// returns false when the queue has been closed
+func (q *BroadcastDR[T]) Send(v T) bool { return q.send(v, nil) }
+
+// SendContext sends a value to all subscribers and blocks when the slowest subscriber is full,
+// returns false when the queue has been closed or the context is done
+func (q *BroadcastDR[T]) SendContext(ctx context.Context, v T) bool { return q.send(v, ctx.Done()) }
+
+// SendTimeout sends a value to all subscribers and blocks when the slowest subscriber is full,
+// returns false when the queue has been closed or the timeout elapsed
+func (q *BroadcastDR[T]) SendTimeout(v T, timeout time.Duration) bool {
+	return sendTimeout(q.SendContext, v, timeout)
+}
+
+func (q *BroadcastDR[T]) send(v T, done <-chan struct{}) bool {
+	for wait := 0; ; wait++ {
+		if q.TrySend(v) {
+			return true
+		}
+		if atomic.LoadUint32(&q.closed) != 0 || canceled(done) {
+			return false
+		}
+		q.sendw.Wait(wait, q.sendReady, done)
+	}
+}
+
+// TrySend tries to send a value to all subscribers and returns immediately
+// when the slowest subscriber is full or the queue is closed
+func (q *BroadcastDR[T]) TrySend(v T) bool {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+
+	pos := q.sent
+	if pos-q.gate > q.mask {
+		q.gate = q.minCursor(pos)
+		if pos-q.gate > q.mask {
+			return false
+		}
+	}
+
+	q.buffer[pos&q.mask] = v
+	atomic.StoreUint64(&q.sent, pos+1)
+	q.recvw.Notify()
+	return true
+}
+
+func (q *BroadcastDR[T]) canSend() bool {
+	return atomic.LoadUint32(&q.closed) != 0 || q.sent-q.minCursor(q.sent) <= q.mask
+}
+
+// Close unsubscribes the reader, after which it doesn't receive any values,
+// must be called by the consumer
+func (r *BroadcastReaderDR[T]) Close() {
+	atomic.StoreUint32(&r.closed, 1)
+	r.q.unsubscribe(r)
+	r.q.sendw.Notify()
+}
+
+// Recv receives the next value and blocks when there is none,
+// returns false when the queue has been closed and drained or the reader has been closed
+func (r *BroadcastReaderDR[T]) Recv(v *T) bool { return r.recv(v, nil) }
+
+// RecvContext receives the next value and blocks when there is none,
+// returns false when the queue has been closed and drained, the reader has been closed or the context is done
+func (r *BroadcastReaderDR[T]) RecvContext(ctx context.Context, v *T) bool {
+	return r.recv(v, ctx.Done())
+}
+
+// RecvTimeout receives the next value and blocks when there is none,
+// returns false when the queue has been closed and drained, the reader has been closed or the timeout elapsed
+func (r *BroadcastReaderDR[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(r.RecvContext, v, timeout)
+}
+
+func (r *BroadcastReaderDR[T]) recv(v *T, done <-chan struct{}) bool {
+	for wait := 0; ; wait++ {
+		drained := atomic.LoadUint32(&r.q.closed) != 0 && atomic.LoadInt64(&r.q.sending) == 0
+		if r.TryRecv(v) {
+			return true
+		}
+		if drained || atomic.LoadUint32(&r.closed) != 0 || canceled(done) {
+			return false
+		}
+		r.q.recvw.Wait(wait, r.recvReady, done)
+	}
+}
+
+// TryRecv receives the next value and returns immediately when there is none
+func (r *BroadcastReaderDR[T]) TryRecv(v *T) bool {
+	if atomic.LoadUint32(&r.closed) != 0 {
+		return false
+	}
+
+	pos := r.recvx
+	if pos == r.sentCopy {
+		r.sentCopy = atomic.LoadUint64(&r.q.sent)
+		if pos == r.sentCopy {
+			return false
+		}
+	}
+
+	*v = r.q.buffer[pos&r.q.mask]
+	atomic.StoreUint64(&r.recvx, pos+1)
+	r.q.sendw.Notify()
+	return true
+}
+
+func (r *BroadcastReaderDR[T]) canRecv() bool {
+	return atomic.LoadUint32(&r.q.closed) != 0 || atomic.LoadUint32(&r.closed) != 0 ||
+		atomic.LoadUint64(&r.q.sent) != r.recvx
+}
@@ -1,18 +1,22 @@
 package extqueue
 
 import (
+	"context"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
 // MPSCnsDV is a MPSC queue based on http://www.1024cores.net/home/lock-free-algorithms/queues/non-intrusive-mpsc-node-based-queue
 type MPSCnsDV[T any] struct {
-	stub Node[T]
-	_    [7]uint64
-	head unsafe.Pointer
-	_    [7]uint64
-	tail unsafe.Pointer
-	_    [7]uint64
+	stub    Node[T]
+	closed  uint32
+	sending int64
+	_       [6]uint64
+	head    unsafe.Pointer
+	_       [7]uint64
+	tail    unsafe.Pointer
+	_       [7]uint64
 }
 
 // NewMPSCnsDV creates a MPSCnsDV queue
@@ -26,8 +30,18 @@ func NewMPSCnsDV[T any]() *MPSCnsDV[T] {
 // MultipleProducers makes this a MP queue
 func (q *MPSCnsDV[T]) MultipleProducers() {}
 
-// Send sends a value to the queue, always suceeds
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPSCnsDV[T]) Close() { atomic.StoreUint32(&q.closed, 1) }
+
+// Send sends a value to the queue, always succeeds unless the queue has been closed
 func (q *MPSCnsDV[T]) Send(value T) bool {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+
 	n := &Node[T]{Value: value}
 	prev := atomic.SwapPointer(&q.head, unsafe.Pointer(n))
 	prevn := (*Node[T])(prev)
@@ -35,15 +49,40 @@ func (q *MPSCnsDV[T]) Send(value T) bool {
 	return true
 }
 
-// TrySend sends a value to the queue, always suceeds
+// TrySend sends a value to the queue, always succeeds unless the queue has been closed
 func (q *MPSCnsDV[T]) TrySend(value T) bool { return q.Send(value) }
 
-// Recv receives a value from the queue and blocks when it is empty
-func (q *MPSCnsDV[T]) Recv(value *T) bool {
+// SendContext sends a value to the queue, the queue is unbounded so it never waits for ctx
+func (q *MPSCnsDV[T]) SendContext(ctx context.Context, value T) bool { return q.Send(value) }
+
+// SendTimeout sends a value to the queue, the queue is unbounded so it never waits for timeout
+func (q *MPSCnsDV[T]) SendTimeout(value T, timeout time.Duration) bool { return q.Send(value) }
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPSCnsDV[T]) Recv(value *T) bool { return q.recv(value, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPSCnsDV[T]) RecvContext(ctx context.Context, value *T) bool {
+	return q.recv(value, ctx.Done())
+}
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPSCnsDV[T]) RecvTimeout(value *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, value, timeout)
+}
+
+func (q *MPSCnsDV[T]) recv(value *T, done <-chan struct{}) bool {
 	for wait := 0; ; spin(&wait) {
+		drained := atomic.LoadUint32(&q.closed) != 0 && atomic.LoadInt64(&q.sending) == 0
 		if q.TryRecv(value) {
 			return true
 		}
+		if drained || canceled(done) {
+			return false
+		}
 	}
 }
 
@@ -53,9 +92,11 @@ func (q *MPSCnsDV[T]) TryRecv(value *T) bool {
 	next := atomic.LoadPointer(&tail.next)
 	if next == nil {
 		return false
-
 	}
+	// next becomes the new stub, which must not keep the value reachable
+	node := (*Node[T])(next)
+	var zero T
+	*value, node.Value = node.Value, zero
 	q.tail = next
-	*value = (*Node[T])(next).Value
 	return true
 }
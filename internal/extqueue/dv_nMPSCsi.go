@@ -1,18 +1,21 @@
 package extqueue
 
 import (
-	"sync/atomic"
+	"context"
+	"time"
 	"unsafe"
 )
 
 // MPSCnsiDV[T] is a MPSC queue based on http://www.1024cores.net/home/lock-free-algorithms/queues/intrusive-mpsc-node-based-queue
 type MPSCnsiDV[T any] struct {
-	stub Node[T]
-	_    [7]uint64
-	head unsafe.Pointer
-	_    [7]uint64
-	tail unsafe.Pointer
-	_    [7]uint64
+	stub    Node[T]
+	closed  uint32
+	sending int64
+	_       [6]uint64
+	head    unsafe.Pointer
+	_       [7]uint64
+	tail    unsafe.Pointer
+	_       [7]uint64
 }
 
 // NewMPSCnsiDV creates a MPSCnsDV queue
@@ -26,22 +29,42 @@ func NewMPSCnsiDV[T any]() *MPSCnsiDV[T] {
 // MultipleProducers makes this a MP queue
 func (q *MPSCnsiDV[T]) MultipleProducers() {}
 
-// Send sends a value to the queue, always suceeds
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPSCnsiDV[T]) Close() { storeUint32(&q.closed, 1) }
+
+// Send sends a value to the queue, always succeeds unless the queue has been closed
 func (q *MPSCnsiDV[T]) Send(value T) bool { return q.SendNode(&Node[T]{Value: value}) }
 
-// TrySend sends a value to the queue, always suceeds
+// TrySend sends a value to the queue, always succeeds unless the queue has been closed
 func (q *MPSCnsiDV[T]) TrySend(value T) bool { return q.SendNode(&Node[T]{Value: value}) }
 
-// SendNode sends a node to the queue, always suceeds
+// SendContext sends a value to the queue, the queue is unbounded so it never waits for ctx
+func (q *MPSCnsiDV[T]) SendContext(ctx context.Context, value T) bool { return q.Send(value) }
+
+// SendTimeout sends a value to the queue, the queue is unbounded so it never waits for timeout
+func (q *MPSCnsiDV[T]) SendTimeout(value T, timeout time.Duration) bool { return q.Send(value) }
+
+// SendNode sends a node to the queue, always succeeds unless the queue has been closed
 func (q *MPSCnsiDV[T]) SendNode(node *Node[T]) bool {
+	addInt64(&q.sending, 1)
+	defer addInt64(&q.sending, -1)
+
+	if loadUint32(&q.closed) != 0 {
+		return false
+	}
+	q.sendNode(node)
+	return true
+}
+
+func (q *MPSCnsiDV[T]) sendNode(node *Node[T]) {
 	node.next = nil
-	prev := atomic.SwapPointer(&q.head, unsafe.Pointer(node))
+	prev := swapPointer(&q.head, unsafe.Pointer(node))
 	prevn := (*Node[T])(prev)
-	atomic.StorePointer(&prevn.next, unsafe.Pointer(node))
-	return true
+	storePointer(&prevn.next, unsafe.Pointer(node))
 }
 
-// Recv receives a value from the queue and blocks when it is empty
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
 func (q *MPSCnsiDV[T]) Recv(value *T) bool {
 	node, ok := q.RecvNode()
 	if ok {
@@ -51,6 +74,23 @@ func (q *MPSCnsiDV[T]) Recv(value *T) bool {
 	return false
 }
 
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPSCnsiDV[T]) RecvContext(ctx context.Context, value *T) bool {
+	node, ok := q.recvNode(ctx.Done())
+	if ok {
+		*value = node.Value
+		return true
+	}
+	return false
+}
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPSCnsiDV[T]) RecvTimeout(value *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, value, timeout)
+}
+
 // TryRecv receives a value from the queue and returns when it is empty
 func (q *MPSCnsiDV[T]) TryRecv(value *T) bool {
 	node, ok := q.TryRecvNode()
@@ -61,26 +101,33 @@ func (q *MPSCnsiDV[T]) TryRecv(value *T) bool {
 	return false
 }
 
-// RecvNode receives a node from the queue and blocks when it is empty
-func (q *MPSCnsiDV[T]) RecvNode() (*Node[T], bool) {
+// RecvNode receives a node from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPSCnsiDV[T]) RecvNode() (*Node[T], bool) { return q.recvNode(nil) }
+
+func (q *MPSCnsiDV[T]) recvNode(done <-chan struct{}) (*Node[T], bool) {
 	for wait := 0; ; spin(&wait) {
+		drained := loadUint32(&q.closed) != 0 && loadInt64(&q.sending) == 0
 		if node, ok := q.TryRecvNode(); ok {
 			return node, true
 		}
+		if drained || canceled(done) {
+			return nil, false
+		}
 	}
 }
 
 // TryRecvNode receives a node from the queue and returns when it is empty
 func (q *MPSCnsiDV[T]) TryRecvNode() (*Node[T], bool) {
 	tail := (*Node[T])(q.tail)
-	next := atomic.LoadPointer(&tail.next)
+	next := loadPointer(&tail.next)
 	if tail == &q.stub {
 		if next == nil {
 			return nil, false
 		}
 		q.tail = next
 		tail = (*Node[T])(next)
-		next = atomic.LoadPointer(&tail.next)
+		next = loadPointer(&tail.next)
 	}
 	if next != nil {
 		q.tail = next
@@ -88,13 +135,13 @@ func (q *MPSCnsiDV[T]) TryRecvNode() (*Node[T], bool) {
 		return tail, true
 	}
 
-	head := atomic.LoadPointer(&q.head)
+	head := loadPointer(&q.head)
 	if q.tail != head {
 		return nil, false
 	}
 
-	q.SendNode(&q.stub)
-	next = atomic.LoadPointer(&tail.next)
+	q.sendNode(&q.stub)
+	next = loadPointer(&tail.next)
 	if next != nil {
 		q.tail = next
 		tail.next = nil
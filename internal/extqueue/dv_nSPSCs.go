@@ -1,14 +1,18 @@
 package extqueue
 
 import (
+	"context"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
 // SPSCnsDV is a SPSC queue based on http://www.1024cores.net/home/lock-free-algorithms/queues/unbounded-spsc-queue
 type SPSCnsDV[T any] struct {
-	stub Node[T]
-	_    [7]uint64
+	stub    Node[T]
+	closed  uint32
+	sending int64
+	_       [6]uint64
 	// producer
 	head     unsafe.Pointer
 	first    unsafe.Pointer
@@ -29,8 +33,18 @@ func NewSPSCnsDV[T any]() *SPSCnsDV[T] {
 	return q
 }
 
-// Send sends a value to the queue, always succeeds
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *SPSCnsDV[T]) Close() { atomic.StoreUint32(&q.closed, 1) }
+
+// Send sends a value to the queue, always succeeds unless the queue has been closed
 func (q *SPSCnsDV[T]) Send(value T) bool {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+
 	n := q.alloc()
 	n.Value = value
 	n.next = nil
@@ -39,15 +53,40 @@ func (q *SPSCnsDV[T]) Send(value T) bool {
 	return true
 }
 
-// TrySend tries to send a value to the queue, always succeeds
+// TrySend tries to send a value to the queue, always succeeds unless the queue has been closed
 func (q *SPSCnsDV[T]) TrySend(value T) bool { return q.Send(value) }
 
-// Recv receives a value from the queue and blocks when it is empty
-func (q *SPSCnsDV[T]) Recv(value *T) bool {
+// SendContext sends a value to the queue, the queue is unbounded so it never waits for ctx
+func (q *SPSCnsDV[T]) SendContext(ctx context.Context, value T) bool { return q.Send(value) }
+
+// SendTimeout sends a value to the queue, the queue is unbounded so it never waits for timeout
+func (q *SPSCnsDV[T]) SendTimeout(value T, timeout time.Duration) bool { return q.Send(value) }
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *SPSCnsDV[T]) Recv(value *T) bool { return q.recv(value, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *SPSCnsDV[T]) RecvContext(ctx context.Context, value *T) bool {
+	return q.recv(value, ctx.Done())
+}
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *SPSCnsDV[T]) RecvTimeout(value *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, value, timeout)
+}
+
+func (q *SPSCnsDV[T]) recv(value *T, done <-chan struct{}) bool {
 	for wait := 0; ; spin(&wait) {
+		drained := atomic.LoadUint32(&q.closed) != 0 && atomic.LoadInt64(&q.sending) == 0
 		if q.TryRecv(value) {
 			return true
 		}
+		if drained || canceled(done) {
+			return false
+		}
 	}
 }
 
@@ -58,8 +97,11 @@ func (q *SPSCnsDV[T]) TryRecv(value *T) bool {
 	if next == nil {
 		return false
 	}
+	// next becomes the new stub, which must not keep the value reachable
+	node := (*Node[T])(next)
+	var zero T
+	*value, node.Value = node.Value, zero
 	q.tail = next
-	*value = (*Node[T])(next).Value
 	return true
 }
 
@@ -0,0 +1,12 @@
+package extqueue
+
+// MPMCqDV is a MPMC queue based on http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// It behaves like MPMCqsDV, however it spins only briefly and then parks
+// the goroutine until the opposite side wakes it up.
+type MPMCqDV[T any] struct{ *MPMCqsDV[T] }
+
+// NewMPMCqDV creates a new MPMCqDV queue
+func NewMPMCqDV[T any](size int, opts ...Option) *MPMCqDV[T] {
+	opts = append([]Option{WithWaitStrategy(spinThenPark)}, opts...)
+	return &MPMCqDV[T]{NewMPMCqsDV[T](size, opts...)}
+}
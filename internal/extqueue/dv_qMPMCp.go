@@ -0,0 +1,13 @@
+package extqueue
+
+// MPMCqpDV is a MPMC queue based on http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// It behaves like MPMCqspDV, however it spins only briefly and then parks
+// the goroutine until the opposite side wakes it up.
+// Values are padded to a cacheline.
+type MPMCqpDV[T any] struct{ *MPMCqspDV[T] }
+
+// NewMPMCqpDV creates a new MPMCqpDV queue
+func NewMPMCqpDV[T any](size int, opts ...Option) *MPMCqpDV[T] {
+	opts = append([]Option{WithWaitStrategy(spinThenPark)}, opts...)
+	return &MPMCqpDV[T]{NewMPMCqspDV[T](size, opts...)}
+}
@@ -1,7 +1,9 @@
 package extqueue
 
 import (
+	"context"
 	"sync/atomic"
+	"time"
 )
 
 // MPMCqsDV is a MPMC queue based on http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
@@ -9,15 +11,28 @@ type MPMCqsDV[T any] struct {
 	_      [8]int64
 	mask   int64
 	buffer []seqValue[T]
-	_      [4]int64
-	sendx  int64
-	_      [7]int64
-	recvx  int64
-	_      [7]int64
+	closed uint32
+	// waiting
+	sendw, recvw         Waiter
+	sendReady, recvReady func() bool
+	overflow             overflow[T]
+	_                    [2]int64
+
+	sendx int64
+	_     [7]int64
+	recvx int64
+	_     [7]int64
 }
 
 // NewMPMCqsDV creates a NewMPMCqsDV queue
-func NewMPMCqsDV[T any](size int) *MPMCqsDV[T] {
+func NewMPMCqsDV[T any](size int, opts ...Option) *MPMCqsDV[T] {
+	cfg := newConfig(opts)
+	return newMPMCqsDV[T](size, cfg, cfg.wait.NewWaiter())
+}
+
+// newMPMCqsDV creates a MPMCqsDV queue which notifies recvw after sending,
+// MPMCqsPL shares its receive waiter between the lanes.
+func newMPMCqsDV[T any](size int, cfg config, recvw Waiter) *MPMCqsDV[T] {
 	if size <= 1 {
 		size = 2
 	}
@@ -30,28 +45,67 @@ func NewMPMCqsDV[T any](size int) *MPMCqsDV[T] {
 		q.buffer[i].sequence = int64(i)
 	}
 
+	q.sendw = cfg.wait.NewWaiter()
+	q.recvw = recvw
+	q.sendReady = q.canSend
+	q.recvReady = q.canRecv
+	q.overflow = newOverflow[T](cfg, true)
+
 	return q
 }
 
 // Cap returns number of elements this queue can hold before blocking
 func (q *MPMCqsDV[T]) Cap() int { return len(q.buffer) }
 
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPMCqsDV[T]) Close() {
+	if atomic.CompareAndSwapUint32(&q.closed, 0, 1) {
+		atomic.AddInt64(&q.sendx, sendClosed)
+	}
+	q.sendw.Notify()
+	q.recvw.Notify()
+}
+
 // MultipleConsumers makes this a MC queue
 func (q *MPMCqsDV[T]) MultipleConsumers() {}
 
 // MultipleProducers makes this a MP queue
 func (q *MPMCqsDV[T]) MultipleProducers() {}
 
-// Send sends a value to the queue and blocks when it is full
-func (q *MPMCqsDV[T]) Send(v T) bool {
-	for wait := 0; ; spin(&wait) {
+// Send sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed
+func (q *MPMCqsDV[T]) Send(v T) bool { return q.send(v, nil) }
+
+// SendContext sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the context is done
+func (q *MPMCqsDV[T]) SendContext(ctx context.Context, v T) bool { return q.send(v, ctx.Done()) }
+
+// SendTimeout sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the timeout elapsed
+func (q *MPMCqsDV[T]) SendTimeout(v T, timeout time.Duration) bool {
+	return sendTimeout(q.SendContext, v, timeout)
+}
+
+func (q *MPMCqsDV[T]) send(v T, done <-chan struct{}) bool {
+	for wait := 0; ; wait++ {
 		if q.TrySend(v) {
 			return true
 		}
+		if atomic.LoadUint32(&q.closed) != 0 || canceled(done) {
+			return false
+		}
+		switch q.overflow.policy {
+		case OverflowBlock:
+			q.sendw.Wait(wait, q.sendReady, done)
+		case OverflowDropOldest:
+			q.overflow.evict(q.TryRecv)
+		default:
+			return q.overflow.reject(v)
+		}
 	}
 }
 
-// TrySend tries to send a value to the queue and returns immediately when it is full
+// TrySend tries to send a value to the queue and returns immediately when it is full or closed
 func (q *MPMCqsDV[T]) TrySend(v T) bool {
 	var cell *seqValue[T]
 	pos := atomic.LoadInt64(&q.sendx)
@@ -63,8 +117,10 @@ func (q *MPMCqsDV[T]) TrySend(v T) bool {
 			if atomic.CompareAndSwapInt64(&q.sendx, pos, pos+1) {
 				break
 			}
+			// another sender or Close moved sendx
+			pos = atomic.LoadInt64(&q.sendx)
 		} else if df < 0 {
-			// full
+			// full or closed
 			return false
 		} else {
 			pos = atomic.LoadInt64(&q.sendx)
@@ -73,15 +129,33 @@ func (q *MPMCqsDV[T]) TrySend(v T) bool {
 
 	cell.value = v
 	atomic.StoreInt64(&cell.sequence, pos+1)
+	q.recvw.Notify()
 	return true
 }
 
-// Recv receives a value from the queue and blocks when it is empty
-func (q *MPMCqsDV[T]) Recv(v *T) bool {
-	for wait := 0; ; spin(&wait) {
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPMCqsDV[T]) Recv(v *T) bool { return q.recv(v, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPMCqsDV[T]) RecvContext(ctx context.Context, v *T) bool { return q.recv(v, ctx.Done()) }
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPMCqsDV[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
+
+func (q *MPMCqsDV[T]) recv(v *T, done <-chan struct{}) bool {
+	for wait := 0; ; wait++ {
 		if q.TryRecv(v) {
 			return true
 		}
+		if q.drained() || canceled(done) {
+			return false
+		}
+		q.recvw.Wait(wait, q.recvReady, done)
 	}
 }
 
@@ -105,7 +179,44 @@ func (q *MPMCqsDV[T]) TryRecv(v *T) bool {
 		}
 	}
 
-	*v = cell.value
+	var zero T
+	*v, cell.value = cell.value, zero
 	atomic.StoreInt64(&cell.sequence, pos+q.mask+1)
+	q.sendw.Notify()
 	return true
 }
+
+// OverflowPolicy returns the name of the policy for sending to a full queue
+func (q *MPMCqsDV[T]) OverflowPolicy() string { return q.overflow.policy.String() }
+
+// Overflowed returns number of values that were dropped because the queue was full
+func (q *MPMCqsDV[T]) Overflowed() uint64 { return atomic.LoadUint64(&q.overflow.dropped) }
+
+// canSend returns whether sending might succeed without waiting
+func (q *MPMCqsDV[T]) canSend() bool {
+	pos := atomic.LoadInt64(&q.sendx)
+	seq := atomic.LoadInt64(&q.buffer[pos&q.mask].sequence)
+	return seq-pos >= 0 || atomic.LoadUint32(&q.closed) != 0
+}
+
+// canRecv returns whether receiving might succeed without waiting
+func (q *MPMCqsDV[T]) canRecv() bool {
+	pos := atomic.LoadInt64(&q.recvx)
+	seq := atomic.LoadInt64(&q.buffer[pos&q.mask].sequence)
+	return seq-(pos+1) >= 0 || atomic.LoadUint32(&q.closed) != 0
+}
+
+// drained returns whether the queue has been closed and all the values have been received
+func (q *MPMCqsDV[T]) drained() bool {
+	return atomic.LoadInt64(&q.sendx)-atomic.LoadInt64(&q.recvx) == sendClosed
+}
+
+// startAt moves an empty queue to the specified position,
+// which allows testing sequence wraparound
+func (q *MPMCqsDV[T]) startAt(pos int64) {
+	q.sendx = pos
+	q.recvx = pos
+	for i := int64(0); i <= q.mask; i++ {
+		q.buffer[(pos+i)&q.mask].sequence = pos + i
+	}
+}
@@ -0,0 +1,12 @@
+package extqueue
+
+// MPSCqDV is a MPSC queue based on http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// It behaves like MPSCqsDV, however it spins only briefly and then parks
+// the goroutine until the opposite side wakes it up.
+type MPSCqDV[T any] struct{ *MPSCqsDV[T] }
+
+// NewMPSCqDV creates a new MPSCqDV queue
+func NewMPSCqDV[T any](size int, opts ...Option) *MPSCqDV[T] {
+	opts = append([]Option{WithWaitStrategy(spinThenPark)}, opts...)
+	return &MPSCqDV[T]{NewMPSCqsDV[T](size, opts...)}
+}
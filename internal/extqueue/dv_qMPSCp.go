@@ -0,0 +1,13 @@
+package extqueue
+
+// MPSCqpDV is a MPSC queue based on http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// It behaves like MPSCqspDV, however it spins only briefly and then parks
+// the goroutine until the opposite side wakes it up.
+// Values are padded to a cacheline.
+type MPSCqpDV[T any] struct{ *MPSCqspDV[T] }
+
+// NewMPSCqpDV creates a new MPSCqpDV queue
+func NewMPSCqpDV[T any](size int, opts ...Option) *MPSCqpDV[T] {
+	opts = append([]Option{WithWaitStrategy(spinThenPark)}, opts...)
+	return &MPSCqpDV[T]{NewMPSCqspDV[T](size, opts...)}
+}
@@ -1,7 +1,9 @@
 package extqueue
 
 import (
+	"context"
 	"sync/atomic"
+	"time"
 )
 
 // MPSCqspDV is a MPMC queue based on http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
@@ -9,15 +11,20 @@ type MPSCqspDV[T any] struct {
 	_      [8]int64
 	mask   int64
 	buffer []seqPaddedValue[T]
-	_      [4]int64
-	sendx  int64
-	_      [7]int64
-	recvx  int64
-	_      [7]int64
+	closed uint32
+	// waiting
+	sendw, recvw         Waiter
+	sendReady, recvReady func() bool
+	_                    [5]int64
+
+	sendx int64
+	_     [7]int64
+	recvx int64
+	_     [7]int64
 }
 
 // NewMPSCqspDV creates a new queue.
-func NewMPSCqspDV[T any](size int) *MPSCqspDV[T] {
+func NewMPSCqspDV[T any](size int, opts ...Option) *MPSCqspDV[T] {
 	if size <= 1 {
 		size = 2
 	}
@@ -30,25 +37,58 @@ func NewMPSCqspDV[T any](size int) *MPSCqspDV[T] {
 		q.buffer[i].sequence = int64(i)
 	}
 
+	cfg := newConfig(opts)
+	blockOnOverflow(cfg)
+	q.sendw = cfg.wait.NewWaiter()
+	q.recvw = cfg.wait.NewWaiter()
+	q.sendReady = q.canSend
+	q.recvReady = q.canRecv
+
 	return q
 }
 
 // Cap returns number of elements this queue can hold before blocking
 func (q *MPSCqspDV[T]) Cap() int { return len(q.buffer) }
 
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPSCqspDV[T]) Close() {
+	if atomic.CompareAndSwapUint32(&q.closed, 0, 1) {
+		atomic.AddInt64(&q.sendx, sendClosed)
+	}
+	q.sendw.Notify()
+	q.recvw.Notify()
+}
+
 // MultipleProducers makes this a MP queue
 func (q *MPSCqspDV[T]) MultipleProducers() {}
 
-// Send sends a value to the queue and blocks when it is full
-func (q *MPSCqspDV[T]) Send(v T) bool {
-	for wait := 0; ; spin(&wait) {
+// Send sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed
+func (q *MPSCqspDV[T]) Send(v T) bool { return q.send(v, nil) }
+
+// SendContext sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the context is done
+func (q *MPSCqspDV[T]) SendContext(ctx context.Context, v T) bool { return q.send(v, ctx.Done()) }
+
+// SendTimeout sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the timeout elapsed
+func (q *MPSCqspDV[T]) SendTimeout(v T, timeout time.Duration) bool {
+	return sendTimeout(q.SendContext, v, timeout)
+}
+
+func (q *MPSCqspDV[T]) send(v T, done <-chan struct{}) bool {
+	for wait := 0; ; wait++ {
 		if q.TrySend(v) {
 			return true
 		}
+		if atomic.LoadUint32(&q.closed) != 0 || canceled(done) {
+			return false
+		}
+		q.sendw.Wait(wait, q.sendReady, done)
 	}
 }
 
-// TrySend tries to send a value to the queue and returns immediately when it is full
+// TrySend tries to send a value to the queue and returns immediately when it is full or closed
 func (q *MPSCqspDV[T]) TrySend(v T) bool {
 	var cell *seqPaddedValue[T]
 	pos := atomic.LoadInt64(&q.sendx)
@@ -60,8 +100,10 @@ func (q *MPSCqspDV[T]) TrySend(v T) bool {
 			if atomic.CompareAndSwapInt64(&q.sendx, pos, pos+1) {
 				break
 			}
+			// another sender or Close moved sendx
+			pos = atomic.LoadInt64(&q.sendx)
 		} else if df < 0 {
-			// full
+			// full or closed
 			return false
 		} else {
 			pos = atomic.LoadInt64(&q.sendx)
@@ -70,15 +112,33 @@ func (q *MPSCqspDV[T]) TrySend(v T) bool {
 
 	cell.value = v
 	atomic.StoreInt64(&cell.sequence, pos+1)
+	q.recvw.Notify()
 	return true
 }
 
-// Recv receives a value from the queue and blocks when it is empty
-func (q *MPSCqspDV[T]) Recv(v *T) bool {
-	for wait := 0; ; spin(&wait) {
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPSCqspDV[T]) Recv(v *T) bool { return q.recv(v, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPSCqspDV[T]) RecvContext(ctx context.Context, v *T) bool { return q.recv(v, ctx.Done()) }
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPSCqspDV[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
+
+func (q *MPSCqspDV[T]) recv(v *T, done <-chan struct{}) bool {
+	for wait := 0; ; wait++ {
 		if q.TryRecv(v) {
 			return true
 		}
+		if q.drained() || canceled(done) {
+			return false
+		}
+		q.recvw.Wait(wait, q.recvReady, done)
 	}
 }
 
@@ -99,7 +159,38 @@ func (q *MPSCqspDV[T]) TryRecv(v *T) bool {
 		}
 	}
 
-	*v = cell.value
+	var zero T
+	*v, cell.value = cell.value, zero
 	atomic.StoreInt64(&cell.sequence, pos+q.mask+1)
+	q.sendw.Notify()
 	return true
 }
+
+// canSend returns whether sending might succeed without waiting
+func (q *MPSCqspDV[T]) canSend() bool {
+	pos := atomic.LoadInt64(&q.sendx)
+	seq := atomic.LoadInt64(&q.buffer[pos&q.mask].sequence)
+	return seq-pos >= 0 || atomic.LoadUint32(&q.closed) != 0
+}
+
+// canRecv returns whether receiving might succeed without waiting
+func (q *MPSCqspDV[T]) canRecv() bool {
+	pos := atomic.LoadInt64(&q.recvx)
+	seq := atomic.LoadInt64(&q.buffer[pos&q.mask].sequence)
+	return seq-(pos+1) >= 0 || atomic.LoadUint32(&q.closed) != 0
+}
+
+// drained returns whether the queue has been closed and all the values have been received
+func (q *MPSCqspDV[T]) drained() bool {
+	return atomic.LoadInt64(&q.sendx)-atomic.LoadInt64(&q.recvx) == sendClosed
+}
+
+// startAt moves an empty queue to the specified position,
+// which allows testing sequence wraparound
+func (q *MPSCqspDV[T]) startAt(pos int64) {
+	q.sendx = pos
+	q.recvx = pos
+	for i := int64(0); i <= q.mask; i++ {
+		q.buffer[(pos+i)&q.mask].sequence = pos + i
+	}
+}
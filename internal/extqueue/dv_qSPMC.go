@@ -0,0 +1,12 @@
+package extqueue
+
+// SPMCqDV is a SPMC queue based on http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// It behaves like SPMCqsDV, however it spins only briefly and then parks
+// the goroutine until the opposite side wakes it up.
+type SPMCqDV[T any] struct{ *SPMCqsDV[T] }
+
+// NewSPMCqDV creates a new SPMCqDV queue
+func NewSPMCqDV[T any](size int, opts ...Option) *SPMCqDV[T] {
+	opts = append([]Option{WithWaitStrategy(spinThenPark)}, opts...)
+	return &SPMCqDV[T]{NewSPMCqsDV[T](size, opts...)}
+}
@@ -0,0 +1,13 @@
+package extqueue
+
+// SPMCqpDV is a SPMC queue based on http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// It behaves like SPMCqspDV, however it spins only briefly and then parks
+// the goroutine until the opposite side wakes it up.
+// Values are padded to a cacheline.
+type SPMCqpDV[T any] struct{ *SPMCqspDV[T] }
+
+// NewSPMCqpDV creates a new SPMCqpDV queue
+func NewSPMCqpDV[T any](size int, opts ...Option) *SPMCqpDV[T] {
+	opts = append([]Option{WithWaitStrategy(spinThenPark)}, opts...)
+	return &SPMCqpDV[T]{NewSPMCqspDV[T](size, opts...)}
+}
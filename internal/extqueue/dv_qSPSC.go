@@ -0,0 +1,12 @@
+package extqueue
+
+// SPSCqDV is a SPSC queue based on http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// It behaves like SPSCqsDV, however it spins only briefly and then parks
+// the goroutine until the opposite side wakes it up.
+type SPSCqDV[T any] struct{ *SPSCqsDV[T] }
+
+// NewSPSCqDV creates a new SPSCqDV queue
+func NewSPSCqDV[T any](size int, opts ...Option) *SPSCqDV[T] {
+	opts = append([]Option{WithWaitStrategy(spinThenPark)}, opts...)
+	return &SPSCqDV[T]{NewSPSCqsDV[T](size, opts...)}
+}
@@ -0,0 +1,13 @@
+package extqueue
+
+// SPSCqpDV is a SPSC queue based on http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// It behaves like SPSCqspDV, however it spins only briefly and then parks
+// the goroutine until the opposite side wakes it up.
+// Values are padded to a cacheline.
+type SPSCqpDV[T any] struct{ *SPSCqspDV[T] }
+
+// NewSPSCqpDV creates a new SPSCqpDV queue
+func NewSPSCqpDV[T any](size int, opts ...Option) *SPSCqpDV[T] {
+	opts = append([]Option{WithWaitStrategy(spinThenPark)}, opts...)
+	return &SPSCqpDV[T]{NewSPSCqspDV[T](size, opts...)}
+}
@@ -0,0 +1,149 @@
+package extqueue
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// MPSCnwFL is a MPSC queue based on MPSCnsDV, where the consumer parks
+// after spinning for a while, similarly to a futex lock (FL).
+//
+// Producers wake up the consumer only when it has announced that it's sleeping,
+// so the uncontended path doesn't need any additional synchronization.
+type MPSCnwFL[T any] struct {
+	stub    Node[T]
+	closed  uint32
+	sending int64
+	_       [6]uint64
+	head    unsafe.Pointer
+	_       [7]uint64
+	tail    unsafe.Pointer
+	_       [7]uint64
+	// sleeping
+	sleeping uint32
+	wake     chan struct{}
+}
+
+// NewMPSCnwFL creates a MPSCnwFL queue
+func NewMPSCnwFL[T any]() *MPSCnwFL[T] {
+	q := &MPSCnwFL[T]{}
+	q.head = unsafe.Pointer(&q.stub)
+	q.tail = unsafe.Pointer(&q.stub)
+	q.wake = make(chan struct{}, 1)
+	return q
+}
+
+// MultipleProducers makes this a MP queue
+func (q *MPSCnwFL[T]) MultipleProducers() {}
+
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPSCnwFL[T]) Close() {
+	atomic.StoreUint32(&q.closed, 1)
+	q.wakeup()
+}
+
+// Send sends a value to the queue, always succeeds unless the queue has been closed
+func (q *MPSCnwFL[T]) Send(value T) bool {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+
+	n := &Node[T]{Value: value}
+	prev := atomic.SwapPointer(&q.head, unsafe.Pointer(n))
+	prevn := (*Node[T])(prev)
+	atomic.StorePointer(&prevn.next, unsafe.Pointer(n))
+
+	q.wakeup()
+	return true
+}
+
+// TrySend sends a value to the queue, always succeeds unless the queue has been closed
+func (q *MPSCnwFL[T]) TrySend(value T) bool { return q.Send(value) }
+
+// SendContext sends a value to the queue, the queue is unbounded so it never waits for ctx
+func (q *MPSCnwFL[T]) SendContext(ctx context.Context, value T) bool { return q.Send(value) }
+
+// SendTimeout sends a value to the queue, the queue is unbounded so it never waits for timeout
+func (q *MPSCnwFL[T]) SendTimeout(value T, timeout time.Duration) bool { return q.Send(value) }
+
+// wakeup wakes the consumer when it is sleeping
+func (q *MPSCnwFL[T]) wakeup() {
+	if atomic.LoadUint32(&q.sleeping) == 0 {
+		return
+	}
+	if atomic.CompareAndSwapUint32(&q.sleeping, 1, 0) {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+			// consumer hasn't picked up the previous wakeup
+		}
+	}
+}
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPSCnwFL[T]) Recv(value *T) bool { return q.recv(value, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPSCnwFL[T]) RecvContext(ctx context.Context, value *T) bool {
+	return q.recv(value, ctx.Done())
+}
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPSCnwFL[T]) RecvTimeout(value *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, value, timeout)
+}
+
+func (q *MPSCnwFL[T]) recv(value *T, done <-chan struct{}) bool {
+	for try := 0; ; try++ {
+		drained := atomic.LoadUint32(&q.closed) != 0 && atomic.LoadInt64(&q.sending) == 0
+		if q.TryRecv(value) {
+			return true
+		}
+		if drained || canceled(done) {
+			return false
+		}
+
+		if try < 64 {
+			wait()
+			continue
+		}
+		try = 0
+
+		// announce that we are going to sleep,
+		// and check whether anything arrived in the mean time
+		atomic.StoreUint32(&q.sleeping, 1)
+		if atomic.LoadUint32(&q.closed) != 0 || atomic.LoadPointer(&q.head) != q.tail {
+			atomic.StoreUint32(&q.sleeping, 0)
+			continue
+		}
+
+		select {
+		case <-q.wake:
+		case <-done:
+		}
+		atomic.StoreUint32(&q.sleeping, 0)
+	}
+}
+
+// TryRecv receives a value from the queue and returns when it is empty
+func (q *MPSCnwFL[T]) TryRecv(value *T) bool {
+	tail := (*Node[T])(q.tail)
+	next := atomic.LoadPointer(&tail.next)
+	if next == nil {
+		return false
+	}
+	// next becomes the new stub, which must not keep the value reachable
+	node := (*Node[T])(next)
+	var zero T
+	*value, node.Value = node.Value, zero
+	q.tail = next
+	return true
+}
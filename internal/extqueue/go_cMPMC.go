@@ -1,13 +1,20 @@
 package extqueue
 
+import (
+	"context"
+	"sync"
+	"time"
+)
+
 // MPMCcGo is a wrapper around go standard channel implementing Queue interfaces
 type MPMCcGo[T any] struct {
-	ch chan T
+	ch    chan T
+	close sync.Once
 }
 
 // NewMPMCcGo creates a new MPMCcGo queue
 func NewMPMCcGo[T any](size int) *MPMCcGo[T] {
-	return &MPMCcGo[T]{make(chan T, size)}
+	return &MPMCcGo[T]{ch: make(chan T, size)}
 }
 
 // Cap returns number of elements this queue can hold before blocking
@@ -19,23 +26,68 @@ func (q *MPMCcGo[T]) MultipleProducers() {}
 // MultipleConsumers makes this a MC queue
 func (q *MPMCcGo[T]) MultipleConsumers() {}
 
-// Close closes this queue
-func (q *MPMCcGo[T]) Close() { close(q.ch) }
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPMCcGo[T]) Close() { q.close.Do(func() { close(q.ch) }) }
 
-// Send sends a value to the queue and blocks when it is full
-func (q *MPMCcGo[T]) Send(v T) bool {
+// Send sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed
+func (q *MPMCcGo[T]) Send(v T) (ok bool) {
+	defer recoverClosed(&ok)
 	q.ch <- v
 	return true
 }
 
-// Recv receives a value from the queue and blocks when it is empty
+// SendContext sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the context is done
+func (q *MPMCcGo[T]) SendContext(ctx context.Context, v T) (ok bool) {
+	defer recoverClosed(&ok)
+	select {
+	case q.ch <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// SendTimeout sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the timeout elapsed
+func (q *MPMCcGo[T]) SendTimeout(v T, timeout time.Duration) bool {
+	return sendTimeout(q.SendContext, v, timeout)
+}
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
 func (q *MPMCcGo[T]) Recv(v *T) bool {
-	*v = <-q.ch
-	return true
+	x, ok := <-q.ch
+	if ok {
+		*v = x
+	}
+	return ok
 }
 
-// TrySend tries to send a value to the queue and returns immediately when it is full
-func (q *MPMCcGo[T]) TrySend(v T) bool {
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPMCcGo[T]) RecvContext(ctx context.Context, v *T) bool {
+	select {
+	case x, ok := <-q.ch:
+		if ok {
+			*v = x
+		}
+		return ok
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPMCcGo[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
+
+// TrySend tries to send a value to the queue and returns immediately when it is full or closed
+func (q *MPMCcGo[T]) TrySend(v T) (ok bool) {
+	defer recoverClosed(&ok)
 	select {
 	case q.ch <- v:
 		return true
@@ -47,10 +99,19 @@ func (q *MPMCcGo[T]) TrySend(v T) bool {
 // TryRecv receives a value from the queue and returns when it is empty
 func (q *MPMCcGo[T]) TryRecv(v *T) bool {
 	select {
-	case x := <-q.ch:
-		*v = x
-		return true
+	case x, ok := <-q.ch:
+		if ok {
+			*v = x
+		}
+		return ok
 	default:
 		return false
 	}
 }
+
+// recoverClosed recovers from sending to a closed channel.
+func recoverClosed(ok *bool) {
+	if recover() != nil {
+		*ok = false
+	}
+}
@@ -1,8 +1,10 @@
 package extqueue
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // MPMCqpGo is an lock-free MPMC queue based on https://docs.google.com/document/d/1yIAYmbvL3JxOKOjuCyon7JhW4cSv1wy5hC0ApeGMV9s/pub
@@ -17,14 +19,20 @@ type MPMCqpGo[T any] struct {
 	sendq sync.Cond
 	recvq sync.Cond
 
-	sendw, recvw int
+	// number of sleeping senders and receivers,
+	// modified under mu, but can be read without it
+	sendw, recvw int32
+	closed       uint32
+
+	overflow overflow[T]
 }
 
 // NewMPMCqpGo creates a new MPMCqpGo queue
-func NewMPMCqpGo[T any](size int) *MPMCqpGo[T] {
+func NewMPMCqpGo[T any](size int, opts ...Option) *MPMCqpGo[T] {
 	if size < 2 {
 		size = 2
 	}
+	sleepOnWait(opts)
 	q := &MPMCqpGo[T]{
 		sendx:  0,
 		recvx:  0,
@@ -32,6 +40,7 @@ func NewMPMCqpGo[T any](size int) *MPMCqpGo[T] {
 	}
 	q.sendq.L = &q.mu
 	q.recvq.L = &q.mu
+	q.overflow = newOverflow[T](newConfig(opts), true)
 	return q
 }
 
@@ -44,23 +53,91 @@ func (q *MPMCqpGo[T]) MultipleConsumers() {}
 // MultipleProducers makes this a MP queue
 func (q *MPMCqpGo[T]) MultipleProducers() {}
 
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPMCqpGo[T]) Close() {
+	atomic.StoreUint32(&q.closed, 1)
+	for {
+		x := atomic.LoadUint64(&q.sendx)
+		if x&goClosed != 0 || atomic.CompareAndSwapUint64(&q.sendx, x, x|goClosed) {
+			break
+		}
+	}
+	q.mu.Lock()
+	q.sendq.Broadcast()
+	q.recvq.Broadcast()
+	q.mu.Unlock()
+}
+
 func (q *MPMCqpGo[T]) cap() uint32 { return uint32(len(q.buffer)) }
 
-// Send sends a value to the queue and blocks when it is full
-func (q *MPMCqpGo[T]) Send(value T) bool { return q.trySend(&value, true) }
+// Send sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed
+func (q *MPMCqpGo[T]) Send(value T) bool { return q.send(&value, nil) }
 
-// TrySend tries to send a value to the queue and returns immediately when it is full
-func (q *MPMCqpGo[T]) TrySend(value T) bool { return q.trySend(&value, false) }
+// SendContext sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the context is done
+func (q *MPMCqpGo[T]) SendContext(ctx context.Context, value T) bool {
+	return q.send(&value, ctx.Done())
+}
+
+// SendTimeout sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the timeout elapsed
+func (q *MPMCqpGo[T]) SendTimeout(value T, timeout time.Duration) bool {
+	return sendTimeout(q.SendContext, value, timeout)
+}
 
-// Recv receives a value from the queue and blocks when it is empty
-func (q *MPMCqpGo[T]) Recv(value *T) bool { return q.tryRecv(value, true) }
+// TrySend tries to send a value to the queue and returns immediately when it is full or closed
+func (q *MPMCqpGo[T]) TrySend(value T) bool { return q.trySend(&value, false, nil) }
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPMCqpGo[T]) Recv(value *T) bool { return q.tryRecv(value, true, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPMCqpGo[T]) RecvContext(ctx context.Context, value *T) bool {
+	return q.tryRecv(value, true, ctx.Done())
+}
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPMCqpGo[T]) RecvTimeout(value *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, value, timeout)
+}
 
 // TryRecv receives a value from the queue and returns when it is empty
-func (q *MPMCqpGo[T]) TryRecv(value *T) bool { return q.tryRecv(value, false) }
+func (q *MPMCqpGo[T]) TryRecv(value *T) bool { return q.tryRecv(value, false, nil) }
+
+// OverflowPolicy returns the name of the policy for sending to a full queue
+func (q *MPMCqpGo[T]) OverflowPolicy() string { return q.overflow.policy.String() }
+
+// Overflowed returns number of values that were dropped because the queue was full
+func (q *MPMCqpGo[T]) Overflowed() uint64 { return atomic.LoadUint64(&q.overflow.dropped) }
+
+func (q *MPMCqpGo[T]) send(value *T, done <-chan struct{}) bool {
+	if q.overflow.policy == OverflowBlock {
+		return q.trySend(value, true, done)
+	}
+	for {
+		if q.trySend(value, false, nil) {
+			return true
+		}
+		if atomic.LoadUint32(&q.closed) != 0 || canceled(done) {
+			return false
+		}
+		if q.overflow.policy != OverflowDropOldest {
+			return q.overflow.reject(*value)
+		}
+		q.overflow.evict(q.TryRecv)
+	}
+}
 
-func (q *MPMCqpGo[T]) trySend(value *T, block bool) bool {
+func (q *MPMCqpGo[T]) trySend(value *T, block bool, done <-chan struct{}) bool {
 	for loopCount := 0; ; backoff(&loopCount) {
 		x := atomic.LoadUint64(&q.sendx)
+		if x&goClosed != 0 {
+			return false
+		}
 		seq, pos := uint32(x>>32), uint32(x)
 		elem := &q.buffer[pos]
 		eseq := atomic.LoadUint32(&elem.sequence)
@@ -82,16 +159,16 @@ func (q *MPMCqpGo[T]) trySend(value *T, block bool) bool {
 				atomic.StoreUint32(&elem.sequence, eseq+1)
 
 				// try to release a receiver
-				q.mu.Lock()
-				if q.recvw > 0 {
+				if atomic.LoadInt32(&q.recvw) > 0 {
+					q.mu.Lock()
 					q.recvq.Signal()
+					q.mu.Unlock()
 				}
-				q.mu.Unlock()
 				return true
 			}
 			// Lost the race, retry
 		} else if int32(seq-eseq) > 0 {
-			if !block {
+			if !block || canceled(done) {
 				return false
 			}
 
@@ -105,14 +182,26 @@ func (q *MPMCqpGo[T]) trySend(value *T, block bool) bool {
 			}
 
 			q.mu.Lock()
-			if x-atomic.LoadUint64(&q.recvx) != 2<<32 {
+			// announce sleeping before checking the condition,
+			// otherwise a receiver may miss the sleeping sender
+			atomic.AddInt32(&q.sendw, 1)
+			if atomic.LoadUint32(&q.closed) != 0 || x-atomic.LoadUint64(&q.recvx) != 2<<32 {
+				atomic.AddInt32(&q.sendw, -1)
 				q.mu.Unlock()
 				continue
 			}
-			q.sendw++
+			if canceled(done) {
+				atomic.AddInt32(&q.sendw, -1)
+				q.mu.Unlock()
+				return false
+			}
 			//fmt.Printf("send: sleep %v\n", pos)
-			q.sendq.Wait()
-			q.sendw--
+			waitCond(&q.sendq, done)
+			if canceled(done) {
+				// pass on the wakeup that might have been meant for this waiter
+				q.sendq.Signal()
+			}
+			atomic.AddInt32(&q.sendw, -1)
 			q.mu.Unlock()
 		}
 		// The element has already been written on this seq,
@@ -121,11 +210,9 @@ func (q *MPMCqpGo[T]) trySend(value *T, block bool) bool {
 	}
 }
 
-func (q *MPMCqpGo[T]) tryRecv(result *T, block bool) bool {
+func (q *MPMCqpGo[T]) tryRecv(result *T, block bool, done <-chan struct{}) bool {
 	var empty T
 	for loopCount := 0; ; backoff(&loopCount) {
-		// if closed return false
-
 		x := atomic.LoadUint64(&q.recvx)
 		seq, pos := uint32(x>>32), uint32(x)
 		elem := &q.buffer[pos]
@@ -145,11 +232,11 @@ func (q *MPMCqpGo[T]) tryRecv(result *T, block bool) bool {
 				*result, elem.value = elem.value, empty
 				atomic.StoreUint32(&elem.sequence, eseq+2)
 				// try to release a sender
-				q.mu.Lock()
-				if q.sendw > 0 {
+				if atomic.LoadInt32(&q.sendw) > 0 {
+					q.mu.Lock()
 					q.sendq.Signal()
+					q.mu.Unlock()
 				}
-				q.mu.Unlock()
 				return true
 			}
 			// Lost the race, retry
@@ -158,7 +245,8 @@ func (q *MPMCqpGo[T]) tryRecv(result *T, block bool) bool {
 				return false
 			}
 
-			if x != atomic.LoadUint64(&q.sendx) {
+			sendx := atomic.LoadUint64(&q.sendx)
+			if x != sendx&^goClosed {
 				waitcount := 0
 				//fmt.Printf("recv: busy wait %v\n", pos)
 				for int32(seq-atomic.LoadUint32(&elem.sequence)+1) > 0 {
@@ -166,16 +254,31 @@ func (q *MPMCqpGo[T]) tryRecv(result *T, block bool) bool {
 				}
 				continue
 			}
+			if sendx&goClosed != 0 || canceled(done) {
+				return false
+			}
 
 			//fmt.Printf("recv: sleep %v\n", pos)
 			q.mu.Lock()
-			if x != atomic.LoadUint64(&q.sendx) {
+			// announce sleeping before checking the condition,
+			// otherwise a sender may miss the sleeping receiver
+			atomic.AddInt32(&q.recvw, 1)
+			if atomic.LoadUint32(&q.closed) != 0 || x != atomic.LoadUint64(&q.sendx) {
+				atomic.AddInt32(&q.recvw, -1)
 				q.mu.Unlock()
 				continue
 			}
-			q.recvw++
-			q.recvq.Wait()
-			q.recvw--
+			if canceled(done) {
+				atomic.AddInt32(&q.recvw, -1)
+				q.mu.Unlock()
+				return false
+			}
+			waitCond(&q.recvq, done)
+			if canceled(done) {
+				// pass on the wakeup that might have been meant for this waiter
+				q.recvq.Signal()
+			}
+			atomic.AddInt32(&q.recvw, -1)
 			q.mu.Unlock()
 		}
 		// The element has already been read on this seq,
@@ -183,3 +286,13 @@ func (q *MPMCqpGo[T]) tryRecv(result *T, block bool) bool {
 		// retry.
 	}
 }
+
+// startAt moves an empty queue to the specified sequence number,
+// which allows testing sequence wraparound
+func (q *MPMCqpGo[T]) startAt(seq uint32) {
+	q.sendx = uint64(seq) << 32
+	q.recvx = uint64(seq) << 32
+	for i := range q.buffer {
+		q.buffer[i].sequence = seq
+	}
+}
@@ -0,0 +1,277 @@
+package extqueue
+
+import (
+	"context"
+	"math/bits"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// MPMCnsLS is an unbounded MPMC priority queue based on the lock-free SkipQueue from
+// "The Art of Multiprocessor Programming" by Maurice Herlihy and Nir Shavit,
+// which builds on "Skiplist-Based Concurrent Priority Queues" by Itay Lotan and Nir Shavit.
+//
+// Values with a lower priority are received first, values with the same priority
+// are received in the order they were sent. The ordering is only quiescently consistent,
+// a receive concurrent with sends may skip a value that is still being inserted,
+// hence the queue only supports sending with an explicit priority.
+//
+// Next pointers are immutable references combining the node with the removal mark,
+// every update allocates a new reference, which avoids ABA on compare-and-swap.
+// Removed nodes are unlinked by searches and collected once no search holds them.
+type MPMCnsLS[T any] struct {
+	head *lsNode[T]
+	tail *lsNode[T]
+
+	closed uint32
+	// waiting
+	recvw     Waiter
+	recvReady func() bool
+	_         [4]uint64
+
+	seq     uint64
+	sending int64
+	_       [6]uint64
+
+	count int64
+	_     [7]uint64
+}
+
+const lsMaxLevel = 24
+
+type lsNode[T any] struct {
+	priority int
+	seq      uint64
+	value    T
+	claimed  uint32
+	// next contains *lsRef[T] for each level
+	next []unsafe.Pointer
+}
+
+// lsRef is an immutable reference to the next node,
+// marked references belong to nodes that are being removed.
+type lsRef[T any] struct {
+	node   *lsNode[T]
+	marked bool
+}
+
+func (n *lsNode[T]) load(level int) *lsRef[T] {
+	return (*lsRef[T])(atomic.LoadPointer(&n.next[level]))
+}
+
+func (n *lsNode[T]) cas(level int, old, new *lsRef[T]) bool {
+	return atomic.CompareAndSwapPointer(&n.next[level], unsafe.Pointer(old), unsafe.Pointer(new))
+}
+
+// casNode replaces an unmarked reference to expect with a reference to update.
+func (n *lsNode[T]) casNode(level int, expect, update *lsNode[T]) bool {
+	ref := n.load(level)
+	if ref.marked || ref.node != expect {
+		return false
+	}
+	return n.cas(level, ref, &lsRef[T]{node: update})
+}
+
+func (n *lsNode[T]) less(key *lsNode[T]) bool {
+	if n.priority != key.priority {
+		return n.priority < key.priority
+	}
+	return n.seq < key.seq
+}
+
+// NewMPMCnsLS creates a MPMCnsLS queue
+func NewMPMCnsLS[T any](opts ...Option) *MPMCnsLS[T] {
+	q := &MPMCnsLS[T]{}
+	q.head = &lsNode[T]{next: make([]unsafe.Pointer, lsMaxLevel)}
+	q.tail = &lsNode[T]{next: make([]unsafe.Pointer, lsMaxLevel)}
+	end := unsafe.Pointer(&lsRef[T]{})
+	toTail := unsafe.Pointer(&lsRef[T]{node: q.tail})
+	for level := 0; level < lsMaxLevel; level++ {
+		q.head.next[level] = toTail
+		q.tail.next[level] = end
+	}
+
+	cfg := newConfig(opts)
+	blockOnOverflow(cfg)
+	q.recvw = cfg.wait.NewWaiter()
+	q.recvReady = q.canRecv
+
+	return q
+}
+
+// Priorities returns 0, any int can be used as a priority
+func (q *MPMCnsLS[T]) Priorities() int { return 0 }
+
+// MultipleConsumers makes this a MC queue
+func (q *MPMCnsLS[T]) MultipleConsumers() {}
+
+// MultipleProducers makes this a MP queue
+func (q *MPMCnsLS[T]) MultipleProducers() {}
+
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPMCnsLS[T]) Close() {
+	atomic.StoreUint32(&q.closed, 1)
+	q.recvw.Notify()
+}
+
+// SendPriority sends a value to the queue, always succeeds unless the queue has been closed
+func (q *MPMCnsLS[T]) SendPriority(v T, priority int) bool {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+
+	seq := atomic.AddUint64(&q.seq, 1)
+	n := &lsNode[T]{
+		priority: priority,
+		seq:      seq,
+		value:    v,
+		next:     make([]unsafe.Pointer, lsLevel(seq)),
+	}
+	q.add(n)
+	atomic.AddInt64(&q.count, 1)
+	q.recvw.Notify()
+	return true
+}
+
+// lsLevel picks a level with geometric distribution using a hash of seq.
+func lsLevel(seq uint64) int {
+	// splitmix64 finalizer
+	h := seq * 0x9E3779B97F4A7C15
+	h = (h ^ h>>30) * 0xBF58476D1CE4E5B9
+	h = (h ^ h>>27) * 0x94D049BB133111EB
+	h ^= h >> 31
+	return 1 + bits.TrailingZeros64(h|1<<(lsMaxLevel-1))
+}
+
+// find finds the predecessors and successors of key on each level
+// and unlinks marked nodes along the way.
+func (q *MPMCnsLS[T]) find(key *lsNode[T], preds, succs *[lsMaxLevel]*lsNode[T]) {
+retry:
+	for {
+		pred := q.head
+		for level := lsMaxLevel - 1; level >= 0; level-- {
+			predRef := pred.load(level)
+			if predRef.marked {
+				// pred is being removed, snipping after it would
+				// link to a node that's no longer reachable
+				continue retry
+			}
+			curr := predRef.node
+			for {
+				currRef := curr.load(level)
+				for currRef.marked {
+					snip := &lsRef[T]{node: currRef.node}
+					if !pred.cas(level, predRef, snip) {
+						continue retry
+					}
+					predRef, curr = snip, snip.node
+					currRef = curr.load(level)
+				}
+				if curr == q.tail || !curr.less(key) {
+					break
+				}
+				pred, predRef, curr = curr, currRef, currRef.node
+			}
+			preds[level], succs[level] = pred, curr
+		}
+		return
+	}
+}
+
+func (q *MPMCnsLS[T]) add(n *lsNode[T]) {
+	var preds, succs [lsMaxLevel]*lsNode[T]
+	for try := 0; ; spin(&try) {
+		q.find(n, &preds, &succs)
+		for level := range n.next {
+			n.next[level] = unsafe.Pointer(&lsRef[T]{node: succs[level]})
+		}
+		if preds[0].casNode(0, succs[0], n) {
+			break
+		}
+	}
+
+	for level := 1; level < len(n.next); level++ {
+		for try := 0; ; spin(&try) {
+			ref := n.load(level)
+			if ref.marked {
+				// the node was already received
+				return
+			}
+			if ref.node != succs[level] && !n.cas(level, ref, &lsRef[T]{node: succs[level]}) {
+				continue
+			}
+			if preds[level].casNode(level, succs[level], n) {
+				break
+			}
+			q.find(n, &preds, &succs)
+		}
+	}
+}
+
+// remove marks the node as removed and unlinks it.
+func (q *MPMCnsLS[T]) remove(n *lsNode[T]) {
+	for level := len(n.next) - 1; level >= 0; level-- {
+		for {
+			ref := n.load(level)
+			if ref.marked || n.cas(level, ref, &lsRef[T]{node: ref.node, marked: true}) {
+				break
+			}
+		}
+	}
+
+	var preds, succs [lsMaxLevel]*lsNode[T]
+	q.find(n, &preds, &succs)
+}
+
+// Recv receives a value with the lowest priority and blocks when the queue is empty,
+// returns false when the queue has been closed and drained
+func (q *MPMCnsLS[T]) Recv(v *T) bool { return q.recv(v, nil) }
+
+// RecvContext receives a value with the lowest priority and blocks when the queue is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPMCnsLS[T]) RecvContext(ctx context.Context, v *T) bool { return q.recv(v, ctx.Done()) }
+
+// RecvTimeout receives a value with the lowest priority and blocks when the queue is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPMCnsLS[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
+
+func (q *MPMCnsLS[T]) recv(v *T, done <-chan struct{}) bool {
+	for wait := 0; ; wait++ {
+		drained := atomic.LoadUint32(&q.closed) != 0 && atomic.LoadInt64(&q.sending) == 0
+		if q.TryRecv(v) {
+			return true
+		}
+		if drained || canceled(done) {
+			return false
+		}
+		q.recvw.Wait(wait, q.recvReady, done)
+	}
+}
+
+// TryRecv receives a value with the lowest priority and returns when the queue is empty
+func (q *MPMCnsLS[T]) TryRecv(v *T) bool {
+	curr := q.head.load(0).node
+	for curr != q.tail {
+		ref := curr.load(0)
+		if !ref.marked && atomic.CompareAndSwapUint32(&curr.claimed, 0, 1) {
+			atomic.AddInt64(&q.count, -1)
+			*v = curr.value
+			var zero T
+			curr.value = zero
+			q.remove(curr)
+			return true
+		}
+		curr = ref.node
+	}
+	return false
+}
+
+func (q *MPMCnsLS[T]) canRecv() bool {
+	return atomic.LoadInt64(&q.count) > 0 || atomic.LoadUint32(&q.closed) != 0
+}
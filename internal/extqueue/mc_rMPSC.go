@@ -1,8 +1,8 @@
 package extqueue
 
 import (
-	"sync"
-	"sync/atomic"
+	"context"
+	"time"
 )
 
 // MPSCrMC is a MPSC queue using condition variables on the producer side
@@ -15,7 +15,9 @@ type MPSCrMC[T any] struct {
 	writeTo   int64
 	nextRead  int64
 	unwritten int64
-	_         [8 - 3]uint64
+	sending   int64
+	closed    uint32
+	_         [8 - 5]uint64
 	// consumer
 	localUnwritten int64
 	localNextRead  int64
@@ -26,10 +28,13 @@ type MPSCrMC[T any] struct {
 	mask      int64
 	buffer    []T
 	// sleeping
-	mu      sync.Mutex
-	reader  sync.Cond
-	writers sync.Cond
-	drain   sync.Cond
+	mu      mutex
+	reader  cond
+	writers cond
+	drain   cond
+	// a writer grabbed a location and gave up because the queue was closed,
+	// hence the following locations can never be published
+	abandoned bool
 }
 
 // NewMPSCrMC creates a new MPSCrMC queue
@@ -54,28 +59,152 @@ func (q *MPSCrMC[T]) Cap() int { return len(q.buffer) }
 // MultipleProducers makes this a MP queue
 func (q *MPSCrMC[T]) MultipleProducers() {}
 
-// Send sends a value to the queue and blocks when it is full
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPSCrMC[T]) Close() {
+	storeUint32(&q.closed, 1)
+	q.mu.Lock()
+	q.reader.Broadcast()
+	q.writers.Broadcast()
+	q.drain.Broadcast()
+	q.mu.Unlock()
+}
+
+// Send sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed
 func (q *MPSCrMC[T]) Send(v T) bool {
+	addInt64(&q.sending, 1)
+	defer q.sendDone()
+	if loadUint32(&q.closed) != 0 {
+		return false
+	}
+
 	// grab a write location
-	writeTo := atomic.AddInt64(&q.writeTo, 1) - 1
+	writeTo := addInt64(&q.writeTo, 1) - 1
 
 	// channel is full, wait for it to drain
-	if atomic.LoadInt64(&q.nextRead)+q.mask < writeTo {
+	if loadInt64(&q.nextRead)+q.mask < writeTo {
 		q.mu.Lock()
 		for q.nextRead+q.mask < writeTo {
+			if loadUint32(&q.closed) != 0 {
+				q.abandon()
+				q.mu.Unlock()
+				return false
+			}
 			q.writers.Wait()
 		}
 		q.mu.Unlock()
 	}
 
-	// now we can write
 	q.buffer[writeTo&q.mask] = v
+	return q.publish(writeTo, 1)
+}
+
+// SendContext sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the context is done
+func (q *MPSCrMC[T]) SendContext(ctx context.Context, v T) bool {
+	addInt64(&q.sending, 1)
+	defer q.sendDone()
+	if loadUint32(&q.closed) != 0 {
+		return false
+	}
+
+	// grab a write location only when there's space for it,
+	// because a grabbed location cannot be abandoned
+	done := ctx.Done()
+	var writeTo int64
+	for {
+		writeTo = loadInt64(&q.writeTo)
+		if loadInt64(&q.nextRead)+q.mask >= writeTo {
+			if casInt64(&q.writeTo, writeTo, writeTo+1) {
+				break
+			}
+			continue
+		}
+
+		q.mu.Lock()
+		if loadUint32(&q.closed) != 0 || canceled(done) {
+			q.mu.Unlock()
+			return false
+		}
+		if q.nextRead+q.mask < loadInt64(&q.writeTo) {
+			waitOn(&q.writers, done)
+		}
+		q.mu.Unlock()
+	}
+
+	q.buffer[writeTo&q.mask] = v
+	return q.publish(writeTo, 1)
+}
+
+// SendTimeout sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the timeout elapsed
+func (q *MPSCrMC[T]) SendTimeout(v T, timeout time.Duration) bool {
+	return sendTimeout(q.SendContext, v, timeout)
+}
+
+// SendBatch sends values to the queue and blocks when it is full,
+// returns the number of values sent, which is less than len(vs) only when the queue has been closed
+func (q *MPSCrMC[T]) SendBatch(vs []T) int {
+	// the receiver propagates reads in batches, so grabbing
+	// more than cap-batchSize+1 locations at once could wait forever
+	maxCount := q.mask + 2 - q.batchSize
+
+	addInt64(&q.sending, 1)
+	defer q.sendDone()
+
+	sent := 0
+	for sent < len(vs) {
+		if loadUint32(&q.closed) != 0 {
+			return sent
+		}
+
+		count := int64(len(vs) - sent)
+		if count > maxCount {
+			count = maxCount
+		}
+
+		// grab write locations
+		writeTo := addInt64(&q.writeTo, count) - count
+		last := writeTo + count - 1
+
+		// channel is full, wait for it to drain
+		if loadInt64(&q.nextRead)+q.mask < last {
+			q.mu.Lock()
+			for q.nextRead+q.mask < last {
+				if loadUint32(&q.closed) != 0 {
+					q.abandon()
+					q.mu.Unlock()
+					return sent
+				}
+				q.writers.Wait()
+			}
+			q.mu.Unlock()
+		}
 
+		// now we can write
+		for i, v := range vs[sent : sent+int(count)] {
+			q.buffer[(writeTo+int64(i))&q.mask] = v
+		}
+		if !q.publish(writeTo, count) {
+			return sent
+		}
+		sent += int(count)
+	}
+	return sent
+}
+
+// publish waits for previous writes to complete and
+// makes count written values starting from writeTo visible to the receiver
+func (q *MPSCrMC[T]) publish(writeTo, count int64) bool {
 	q.mu.Lock()
 	for writeTo != q.unwritten {
+		if q.abandoned {
+			q.mu.Unlock()
+			return false
+		}
 		q.drain.Wait()
 	}
-	q.unwritten = writeTo + 1
+	q.unwritten = writeTo + count
 	q.reader.Signal()
 	q.drain.Broadcast()
 	q.mu.Unlock()
@@ -83,36 +212,69 @@ func (q *MPSCrMC[T]) Send(v T) bool {
 	return true
 }
 
+// abandon marks the grabbed locations as never published, must be called with mu held.
+func (q *MPSCrMC[T]) abandon() {
+	q.abandoned = true
+	q.drain.Broadcast()
+}
+
+// sendDone finishes a send, after closing the receiver
+// may be waiting for it to decide whether the queue is drained
+func (q *MPSCrMC[T]) sendDone() {
+	if addInt64(&q.sending, -1) == 0 && loadUint32(&q.closed) != 0 {
+		q.mu.Lock()
+		q.reader.Signal()
+		q.mu.Unlock()
+	}
+}
+
+// drained returns whether the queue has been closed and no send is in progress,
+// the caller must check that there are no values to read
+func (q *MPSCrMC[T]) drained() bool {
+	return loadUint32(&q.closed) != 0 && loadInt64(&q.sending) == 0
+}
+
 // FlushSend is to implement interface, on this queue this is a nop
 func (q *MPSCrMC[T]) FlushSend() {}
 
-// Recv receives a value from the queue and blocks when it is empty
-func (q *MPSCrMC[T]) Recv(v *T) bool { return q.recv(v, true) }
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPSCrMC[T]) Recv(v *T) bool { return q.recv(v, true, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPSCrMC[T]) RecvContext(ctx context.Context, v *T) bool { return q.recv(v, true, ctx.Done()) }
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPSCrMC[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
 
 // TryRecv receives a value from the queue and returns when it is empty
-func (q *MPSCrMC[T]) TryRecv(v *T) bool { return q.recv(v, false) }
+func (q *MPSCrMC[T]) TryRecv(v *T) bool { return q.recv(v, false, nil) }
 
-func (q *MPSCrMC[T]) recv(v *T, block bool) bool {
+func (q *MPSCrMC[T]) recv(v *T, block bool, done <-chan struct{}) bool {
 	localUnwritten := q.localUnwritten
 
 	if q.localNextRead >= localUnwritten {
 		q.mu.Lock()
-		localUnwritten = atomic.LoadInt64(&q.unwritten)
+		localUnwritten = loadInt64(&q.unwritten)
 		for q.localNextRead >= localUnwritten {
-			if !block {
+			if !block || q.drained() || canceled(done) {
 				q.mu.Unlock()
 				return false
 			}
-			q.reader.Wait()
-			localUnwritten = atomic.LoadInt64(&q.unwritten)
+			waitOn(&q.reader, done)
+			localUnwritten = loadInt64(&q.unwritten)
 		}
 		q.mu.Unlock()
 	}
 
 	q.localUnwritten = localUnwritten
 
-	*v = q.buffer[q.localNextRead&q.mask]
-	// q.buffer[q.localNextRead] = 0 // clear value, only needed for pointers
+	var zero T
+	*v, q.buffer[q.localNextRead&q.mask] = q.buffer[q.localNextRead&q.mask], zero
 
 	q.localNextRead++
 	q.localReadBatch++
@@ -123,10 +285,64 @@ func (q *MPSCrMC[T]) recv(v *T, block bool) bool {
 	return true
 }
 
+// RecvBatch receives values to vs and blocks when the queue is empty,
+// returns the number of values received or 0 when the queue has been closed and drained
+func (q *MPSCrMC[T]) RecvBatch(vs []T) int {
+	if len(vs) == 0 {
+		return 0
+	}
+
+	n := q.readable()
+	if n > int64(len(vs)) {
+		n = int64(len(vs))
+	}
+	var zero T
+	for i := range vs[:n] {
+		at := (q.localNextRead + int64(i)) & q.mask
+		vs[i], q.buffer[at] = q.buffer[at], zero
+	}
+	q.localNextRead += n
+	q.FlushRecv()
+	return int(n)
+}
+
+// RecvBatchFunc calls fn for all values in the queue and blocks when the queue is empty,
+// returns the number of values received or 0 when the queue has been closed and drained
+func (q *MPSCrMC[T]) RecvBatchFunc(fn func(v T)) int {
+	n := q.readable()
+	var zero T
+	for i := int64(0); i < n; i++ {
+		at := (q.localNextRead + i) & q.mask
+		v := q.buffer[at]
+		q.buffer[at] = zero
+		fn(v)
+	}
+	q.localNextRead += n
+	q.FlushRecv()
+	return int(n)
+}
+
+// readable waits until there are values to read,
+// returns the number of values or 0 when the queue has been closed and drained
+func (q *MPSCrMC[T]) readable() int64 {
+	q.mu.Lock()
+	q.localUnwritten = loadInt64(&q.unwritten)
+	for q.localNextRead >= q.localUnwritten {
+		if q.drained() {
+			q.mu.Unlock()
+			return 0
+		}
+		q.reader.Wait()
+		q.localUnwritten = loadInt64(&q.unwritten)
+	}
+	q.mu.Unlock()
+	return q.localUnwritten - q.localNextRead
+}
+
 // FlushRecv propagates pending receive operations to the sender.
 func (q *MPSCrMC[T]) FlushRecv() {
 	q.mu.Lock()
-	atomic.StoreInt64(&q.nextRead, q.localNextRead)
+	storeInt64(&q.nextRead, q.localNextRead)
 	q.localReadBatch = 0
 	q.writers.Broadcast()
 	q.mu.Unlock()
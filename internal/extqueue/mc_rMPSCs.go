@@ -1,7 +1,9 @@
 package extqueue
 
 import (
+	"context"
 	"sync/atomic"
+	"time"
 )
 
 // MPSCrwMC is a MPSC queue using disruptor style waiting on the producer side
@@ -12,7 +14,9 @@ type MPSCrsMC[T any] struct {
 	writeTo   int64
 	nextRead  int64
 	unwritten int64
-	_         [8 - 3]uint64
+	closed    uint32
+	sending   int64
+	_         [8 - 5]uint64
 	// consumer
 	localUnwritten int64
 	localNextRead  int64
@@ -43,23 +47,126 @@ func (q *MPSCrsMC[T]) Cap() int { return len(q.buffer) }
 // MultipleProducers makes this a MP queue
 func (q *MPSCrsMC[T]) MultipleProducers() {}
 
-// Send sends a value to the queue and blocks when it is full
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPSCrsMC[T]) Close() { atomic.StoreUint32(&q.closed, 1) }
+
+// Send sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed
 func (q *MPSCrsMC[T]) Send(v T) bool {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+
 	// grab a write location
 	writeTo := atomic.AddInt64(&q.writeTo, 1) - 1
 
 	// channel is full, wait for it to drain
 	for try := 0; atomic.LoadInt64(&q.nextRead)+q.mask < writeTo; spin(&try) {
+		if atomic.LoadUint32(&q.closed) != 0 {
+			return false
+		}
 	}
 
-	// now we can write
 	q.buffer[writeTo&q.mask] = v
+	return q.publish(writeTo, 1)
+}
+
+// SendContext sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the context is done
+func (q *MPSCrsMC[T]) SendContext(ctx context.Context, v T) bool {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+
+	// grab a write location only when there's space for it,
+	// because a grabbed location cannot be abandoned
+	done := ctx.Done()
+	var writeTo int64
+	for try := 0; ; spin(&try) {
+		writeTo = atomic.LoadInt64(&q.writeTo)
+		if atomic.LoadInt64(&q.nextRead)+q.mask >= writeTo {
+			if atomic.CompareAndSwapInt64(&q.writeTo, writeTo, writeTo+1) {
+				break
+			}
+			continue
+		}
+		if atomic.LoadUint32(&q.closed) != 0 || canceled(done) {
+			return false
+		}
+	}
+
+	q.buffer[writeTo&q.mask] = v
+	return q.publish(writeTo, 1)
+}
+
+// SendTimeout sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the timeout elapsed
+func (q *MPSCrsMC[T]) SendTimeout(v T, timeout time.Duration) bool {
+	return sendTimeout(q.SendContext, v, timeout)
+}
+
+// SendBatch sends values to the queue and blocks when it is full,
+// returns the number of values sent, which is less than len(vs) only when the queue has been closed
+func (q *MPSCrsMC[T]) SendBatch(vs []T) int {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	// the receiver propagates reads in batches, so grabbing
+	// more than cap-batchSize+1 locations at once could wait forever
+	maxCount := q.mask + 2 - q.batchSize
+
+	sent := 0
+	for sent < len(vs) {
+		if atomic.LoadUint32(&q.closed) != 0 {
+			return sent
+		}
+
+		count := int64(len(vs) - sent)
+		if count > maxCount {
+			count = maxCount
+		}
+
+		// grab write locations
+		writeTo := atomic.AddInt64(&q.writeTo, count) - count
+		last := writeTo + count - 1
 
+		// channel is full, wait for it to drain
+		for try := 0; atomic.LoadInt64(&q.nextRead)+q.mask < last; spin(&try) {
+			if atomic.LoadUint32(&q.closed) != 0 {
+				return sent
+			}
+		}
+
+		// now we can write
+		for i, v := range vs[sent : sent+int(count)] {
+			q.buffer[(writeTo+int64(i))&q.mask] = v
+		}
+		if !q.publish(writeTo, count) {
+			return sent
+		}
+		sent += int(count)
+	}
+	return sent
+}
+
+// publish waits for previous writes to complete and
+// makes count written values starting from writeTo visible to the receiver
+func (q *MPSCrsMC[T]) publish(writeTo, count int64) bool {
 	// wait for previous writes to complete
 	for try := 0; writeTo != atomic.LoadInt64(&q.unwritten); spin(&try) {
+		// previous writer gave up because the queue was closed
+		if atomic.LoadUint32(&q.closed) != 0 {
+			return false
+		}
 	}
 
-	atomic.StoreInt64(&q.unwritten, writeTo+1)
+	atomic.StoreInt64(&q.unwritten, writeTo+count)
 
 	return true
 }
@@ -67,24 +174,39 @@ func (q *MPSCrsMC[T]) Send(v T) bool {
 // FlushSend is to implement interface, on this queue this is a nop
 func (q *MPSCrsMC[T]) FlushSend() {}
 
-// Recv receives a value from the queue and blocks when it is empty
-func (q *MPSCrsMC[T]) Recv(v *T) bool { return q.recv(v, true) }
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPSCrsMC[T]) Recv(v *T) bool { return q.recv(v, true, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPSCrsMC[T]) RecvContext(ctx context.Context, v *T) bool { return q.recv(v, true, ctx.Done()) }
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPSCrsMC[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
 
 // TryRecv receives a value from the queue and returns when it is empty
-func (q *MPSCrsMC[T]) TryRecv(v *T) bool { return q.recv(v, false) }
+func (q *MPSCrsMC[T]) TryRecv(v *T) bool { return q.recv(v, false, nil) }
 
-func (q *MPSCrsMC[T]) recv(v *T, block bool) bool {
+func (q *MPSCrsMC[T]) recv(v *T, block bool, done <-chan struct{}) bool {
 	localUnwritten := q.localUnwritten
 	for try := 0; q.localNextRead >= localUnwritten; spin(&try) {
+		drained := atomic.LoadUint32(&q.closed) != 0 && atomic.LoadInt64(&q.sending) == 0
 		localUnwritten = atomic.LoadInt64(&q.unwritten)
-		if !block {
+		if q.localNextRead < localUnwritten {
+			break
+		}
+		if !block || drained || canceled(done) {
 			return false
 		}
 	}
 	q.localUnwritten = localUnwritten
 
-	*v = q.buffer[q.localNextRead&q.mask]
-	// q.buffer[q.localNextRead] = 0 // clear value, only needed for pointers
+	var zero T
+	*v, q.buffer[q.localNextRead&q.mask] = q.buffer[q.localNextRead&q.mask], zero
 
 	q.localNextRead++
 	q.localReadBatch++
@@ -95,6 +217,58 @@ func (q *MPSCrsMC[T]) recv(v *T, block bool) bool {
 	return true
 }
 
+// RecvBatch receives values to vs and blocks when the queue is empty,
+// returns the number of values received or 0 when the queue has been closed and drained
+func (q *MPSCrsMC[T]) RecvBatch(vs []T) int {
+	if len(vs) == 0 {
+		return 0
+	}
+
+	n := q.readable()
+	if n > int64(len(vs)) {
+		n = int64(len(vs))
+	}
+	var zero T
+	for i := range vs[:n] {
+		at := (q.localNextRead + int64(i)) & q.mask
+		vs[i], q.buffer[at] = q.buffer[at], zero
+	}
+	q.localNextRead += n
+	q.FlushRecv()
+	return int(n)
+}
+
+// RecvBatchFunc calls fn for all values in the queue and blocks when the queue is empty,
+// returns the number of values received or 0 when the queue has been closed and drained
+func (q *MPSCrsMC[T]) RecvBatchFunc(fn func(v T)) int {
+	n := q.readable()
+	var zero T
+	for i := int64(0); i < n; i++ {
+		at := (q.localNextRead + i) & q.mask
+		v := q.buffer[at]
+		q.buffer[at] = zero
+		fn(v)
+	}
+	q.localNextRead += n
+	q.FlushRecv()
+	return int(n)
+}
+
+// readable waits until there are values to read,
+// returns the number of values or 0 when the queue has been closed and drained
+func (q *MPSCrsMC[T]) readable() int64 {
+	for try := 0; ; spin(&try) {
+		drained := atomic.LoadUint32(&q.closed) != 0 && atomic.LoadInt64(&q.sending) == 0
+		q.localUnwritten = atomic.LoadInt64(&q.unwritten)
+		if q.localNextRead < q.localUnwritten {
+			return q.localUnwritten - q.localNextRead
+		}
+		if drained {
+			return 0
+		}
+	}
+}
+
 // FlushRecv propagates pending receive operations to the sender.
 func (q *MPSCrsMC[T]) FlushRecv() {
 	atomic.StoreInt64(&q.nextRead, q.localNextRead)
@@ -1,16 +1,21 @@
 package extqueue
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // SPSCrMC is a SPSC queue based on MCRingBuffer http://citeseerx.ist.psu.edu/viewdoc/download?doi=10.1.1.577.960&rep=rep1&type=pdf
 type SPSCrMC[T any] struct {
 	_ [8]uint64
 	// volatile
-	read  int64
-	write int64
-	_     [8 - 2]uint64
+	read    int64
+	write   int64
+	sending int64
+	closed  uint32
+	_       [8 - 4]uint64
 	// consumer
 	localWrite int64
 	nextRead   int64
@@ -28,21 +33,35 @@ type SPSCrMC[T any] struct {
 	mu     sync.Mutex
 	reader sync.Cond
 	writer sync.Cond
+
+	overflow overflow[T]
 }
 
 // NewSPSCrMC creates a new SPSCrMC queue
-func NewSPSCrMC[T any](batchSize, size int) *SPSCrMC[T] {
+func NewSPSCrMC[T any](batchSize, size int, opts ...Option) *SPSCrMC[T] {
 	q := &SPSCrMC[T]{}
 	q.reader.L = &q.mu
 	q.writer.L = &q.mu
 	q.batchSize = int64(batchSize)
 	q.buffer = make([]T, ceil(size+1, batchSize))
+	q.overflow = newOverflow[T](newConfig(opts), false)
 	return q
 }
 
 // Cap returns number of elements this queue can hold before blocking
 func (q *SPSCrMC[T]) Cap() int { return len(q.buffer) - 1 }
 
+// Close closes the queue for sending, values that are already in the queue can still be received.
+// Pending sends become visible when they are flushed, even after closing,
+// however receivers may see the queue drained before that.
+func (q *SPSCrMC[T]) Close() {
+	atomic.StoreUint32(&q.closed, 1)
+	q.mu.Lock()
+	q.reader.Broadcast()
+	q.writer.Broadcast()
+	q.mu.Unlock()
+}
+
 func (q *SPSCrMC[T]) next(i int64) int64 {
 	r := i + 1
 	if r >= int64(len(q.buffer)) {
@@ -51,22 +70,102 @@ func (q *SPSCrMC[T]) next(i int64) int64 {
 	return r
 }
 
-// Send sends a value to the queue and blocks when it is full
-func (q *SPSCrMC[T]) Send(v T) bool { return q.send(v, true) }
+// Send sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed
+func (q *SPSCrMC[T]) Send(v T) bool { return q.sendOverflow(v, nil) }
+
+// SendContext sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the context is done
+func (q *SPSCrMC[T]) SendContext(ctx context.Context, v T) bool { return q.sendOverflow(v, ctx.Done()) }
+
+// SendTimeout sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the timeout elapsed
+func (q *SPSCrMC[T]) SendTimeout(v T, timeout time.Duration) bool {
+	return sendTimeout(q.SendContext, v, timeout)
+}
+
+// TrySend tries to send a value to the queue and returns immediately when it is full or closed
+func (q *SPSCrMC[T]) TrySend(v T) bool { return q.send(v, false, nil) }
+
+// SendBatch sends values to the queue and applies the overflow policy to each value
+// that doesn't fit, returns the number of values sent or dropped, which is less than len(vs)
+// only when the queue has been closed or the policy is OverflowFail
+func (q *SPSCrMC[T]) SendBatch(vs []T) int {
+	atomic.AddInt64(&q.sending, 1)
+	defer q.sendDone()
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return 0
+	}
+
+	for i, v := range vs {
+		afterNextWrite := q.next(q.nextWrite)
+		if afterNextWrite == q.localRead {
+			// make the written values visible before waiting for space
+			q.FlushSend()
+			q.mu.Lock()
+			for afterNextWrite == q.read {
+				if atomic.LoadUint32(&q.closed) != 0 {
+					q.mu.Unlock()
+					return i
+				}
+				if q.overflow.policy != OverflowBlock {
+					break
+				}
+				waitCond(&q.writer, nil)
+			}
+			q.localRead = q.read
+			q.mu.Unlock()
+
+			if afterNextWrite == q.localRead {
+				if !q.overflow.reject(v) {
+					return i
+				}
+				continue
+			}
+		}
+
+		q.buffer[q.nextWrite] = v
+		q.nextWrite = afterNextWrite
+	}
+	q.FlushSend()
+	return len(vs)
+}
+
+// OverflowPolicy returns the name of the policy for sending to a full queue
+func (q *SPSCrMC[T]) OverflowPolicy() string { return q.overflow.policy.String() }
 
-// TrySend tries to send a value to the queue and returns immediately when it is full
-func (q *SPSCrMC[T]) TrySend(v T) bool { return q.send(v, false) }
+// Overflowed returns number of values that were dropped because the queue was full
+func (q *SPSCrMC[T]) Overflowed() uint64 { return atomic.LoadUint64(&q.overflow.dropped) }
+
+func (q *SPSCrMC[T]) sendOverflow(v T, done <-chan struct{}) bool {
+	if q.overflow.policy == OverflowBlock {
+		return q.send(v, true, done)
+	}
+	if q.send(v, false, nil) {
+		return true
+	}
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+	return q.overflow.reject(v)
+}
+
+func (q *SPSCrMC[T]) send(v T, block bool, done <-chan struct{}) bool {
+	atomic.AddInt64(&q.sending, 1)
+	defer q.sendDone()
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
 
-func (q *SPSCrMC[T]) send(v T, block bool) bool {
 	afterNextWrite := q.next(q.nextWrite)
 	if afterNextWrite == q.localRead {
 		q.mu.Lock()
-		if afterNextWrite == q.read {
-			if !block {
+		for afterNextWrite == q.read {
+			if !block || atomic.LoadUint32(&q.closed) != 0 || canceled(done) {
 				q.mu.Unlock()
 				return false
 			}
-			q.writer.Wait()
+			waitCond(&q.writer, done)
 		}
 		q.localRead = q.read
 		q.mu.Unlock()
@@ -81,6 +180,8 @@ func (q *SPSCrMC[T]) send(v T, block bool) bool {
 	return true
 }
 
+// FlushSend makes written values visible to the receiver,
+// also after closing, because the producer is the only writer.
 func (q *SPSCrMC[T]) FlushSend() {
 	q.mu.Lock()
 	q.write = q.nextWrite
@@ -89,28 +190,111 @@ func (q *SPSCrMC[T]) FlushSend() {
 	q.mu.Unlock()
 }
 
-// Recv receives a value from the queue and blocks when it is empty
-func (q *SPSCrMC[T]) Recv(v *T) bool { return q.recv(v, true) }
+// sendDone finishes a send, after closing the receiver
+// may be waiting for it to decide whether the queue is drained
+func (q *SPSCrMC[T]) sendDone() {
+	if atomic.AddInt64(&q.sending, -1) == 0 && atomic.LoadUint32(&q.closed) != 0 {
+		q.mu.Lock()
+		q.reader.Signal()
+		q.mu.Unlock()
+	}
+}
+
+// drained returns whether the queue has been closed and no send is in progress,
+// the caller must check that there are no values to read
+func (q *SPSCrMC[T]) drained() bool {
+	return atomic.LoadUint32(&q.closed) != 0 && atomic.LoadInt64(&q.sending) == 0
+}
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *SPSCrMC[T]) Recv(v *T) bool { return q.recv(v, true, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *SPSCrMC[T]) RecvContext(ctx context.Context, v *T) bool { return q.recv(v, true, ctx.Done()) }
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *SPSCrMC[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
 
 // TryRecv receives a value from the queue and returns when it is empty
-func (q *SPSCrMC[T]) TryRecv(v *T) bool { return q.recv(v, false) }
+func (q *SPSCrMC[T]) TryRecv(v *T) bool { return q.recv(v, false, nil) }
+
+// RecvBatch receives values to vs and blocks when the queue is empty,
+// returns the number of values received or 0 when the queue has been closed and drained
+func (q *SPSCrMC[T]) RecvBatch(vs []T) int {
+	if len(vs) == 0 {
+		return 0
+	}
+
+	n := q.readable()
+	if n > int64(len(vs)) {
+		n = int64(len(vs))
+	}
+	var zero T
+	for i := range vs[:n] {
+		vs[i], q.buffer[q.nextRead] = q.buffer[q.nextRead], zero
+		q.nextRead = q.next(q.nextRead)
+	}
+	q.FlushRecv()
+	return int(n)
+}
+
+// RecvBatchFunc calls fn for all values in the queue and blocks when the queue is empty,
+// returns the number of values received or 0 when the queue has been closed and drained
+func (q *SPSCrMC[T]) RecvBatchFunc(fn func(v T)) int {
+	n := q.readable()
+	var zero T
+	for i := int64(0); i < n; i++ {
+		v := q.buffer[q.nextRead]
+		q.buffer[q.nextRead] = zero
+		fn(v)
+		q.nextRead = q.next(q.nextRead)
+	}
+	q.FlushRecv()
+	return int(n)
+}
+
+// readable waits until there are values to read,
+// returns the number of values or 0 when the queue has been closed and drained
+func (q *SPSCrMC[T]) readable() int64 {
+	q.mu.Lock()
+	for q.nextRead == q.write {
+		if q.drained() {
+			q.mu.Unlock()
+			return 0
+		}
+		q.reader.Wait()
+	}
+	q.localWrite = q.write
+	q.mu.Unlock()
+
+	n := q.localWrite - q.nextRead
+	if n < 0 {
+		n += int64(len(q.buffer))
+	}
+	return n
+}
 
-func (q *SPSCrMC[T]) recv(v *T, block bool) bool {
+func (q *SPSCrMC[T]) recv(v *T, block bool, done <-chan struct{}) bool {
 	if q.nextRead == q.localWrite {
 		q.mu.Lock()
-		if q.nextRead == q.write {
-			if !block {
+		for q.nextRead == q.write {
+			if !block || q.drained() || canceled(done) {
 				q.mu.Unlock()
 				return false
 			}
-			q.reader.Wait()
+			waitCond(&q.reader, done)
 		}
 		q.localWrite = q.write
 		q.mu.Unlock()
 	}
 
-	*v = q.buffer[q.nextRead]
-	// q.buffer[q.nextRead] = 0 clear value, only needed for pointers
+	var zero T
+	*v, q.buffer[q.nextRead] = q.buffer[q.nextRead], zero
 
 	q.nextRead = q.next(q.nextRead)
 	q.readBatch++
@@ -1,16 +1,19 @@
 package extqueue
 
 import (
+	"context"
 	"sync/atomic"
+	"time"
 )
 
 // SPSCrsMC is a SPSC queue based on MCRingBuffer http://citeseerx.ist.psu.edu/viewdoc/download?doi=10.1.1.577.960&rep=rep1&type=pdf
 type SPSCrsMC[T any] struct {
 	_ [8]uint64
 	// volatile
-	read  int64
-	write int64
-	_     [8 - 2]uint64
+	read   int64
+	write  int64
+	closed uint32
+	_      [8 - 3]uint64
 	// consumer
 	localWrite int64
 	nextRead   int64
@@ -20,7 +23,8 @@ type SPSCrsMC[T any] struct {
 	localRead  int64
 	nextWrite  int64
 	writeBatch int64
-	_          [8 - 3]uint64
+	sending    int64
+	_          [8 - 4]uint64
 	// constant
 	batchSize int64
 	buffer    []T
@@ -37,6 +41,10 @@ func NewSPSCrsMC[T any](batchSize, size int) *SPSCrsMC[T] {
 // Cap returns number of elements this queue can hold before blocking
 func (q *SPSCrsMC[T]) Cap() int { return len(q.buffer) - 1 }
 
+// Close closes the queue for sending, values that are already in the queue can still be received.
+// Pending sends must be flushed before closing.
+func (q *SPSCrsMC[T]) Close() { atomic.StoreUint32(&q.closed, 1) }
+
 func (q *SPSCrsMC[T]) next(i int64) int64 {
 	r := i + 1
 	if r >= int64(len(q.buffer)) {
@@ -45,23 +53,82 @@ func (q *SPSCrsMC[T]) next(i int64) int64 {
 	return r
 }
 
-// Send sends a value to the queue and blocks when it is full
-func (q *SPSCrsMC[T]) Send(v T) bool { return q.send(v, true) }
+// Send sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed
+func (q *SPSCrsMC[T]) Send(v T) bool { return q.send(v, true, nil) }
+
+// SendContext sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the context is done
+func (q *SPSCrsMC[T]) SendContext(ctx context.Context, v T) bool { return q.send(v, true, ctx.Done()) }
+
+// SendTimeout sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the timeout elapsed
+func (q *SPSCrsMC[T]) SendTimeout(v T, timeout time.Duration) bool {
+	return sendTimeout(q.SendContext, v, timeout)
+}
+
+// TrySend tries to send a value to the queue and returns immediately when it is full or closed
+func (q *SPSCrsMC[T]) TrySend(v T) bool { return q.send(v, false, nil) }
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *SPSCrsMC[T]) Recv(v *T) bool { return q.recv(v, true, nil) }
 
-// TrySend tries to send a value to the queue and returns immediately when it is full
-func (q *SPSCrsMC[T]) TrySend(v T) bool { return q.send(v, false) }
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *SPSCrsMC[T]) RecvContext(ctx context.Context, v *T) bool { return q.recv(v, true, ctx.Done()) }
 
-// Recv receives a value from the queue and blocks when it is empty
-func (q *SPSCrsMC[T]) Recv(v *T) bool { return q.recv(v, true) }
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *SPSCrsMC[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
 
 // TryRecv receives a value from the queue and returns when it is empty
-func (q *SPSCrsMC[T]) TryRecv(v *T) bool { return q.recv(v, false) }
+func (q *SPSCrsMC[T]) TryRecv(v *T) bool { return q.recv(v, false, nil) }
+
+// SendBatch sends values to the queue and blocks when it is full,
+// returns the number of values sent, which is less than len(vs) only when the queue has been closed
+func (q *SPSCrsMC[T]) SendBatch(vs []T) int {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return 0
+	}
+
+	for i, v := range vs {
+		afterNextWrite := q.next(q.nextWrite)
+		if afterNextWrite == q.localRead {
+			// make the written values visible before waiting for space
+			q.FlushSend()
+			for try := 0; afterNextWrite == atomic.LoadInt64(&q.read); spin(&try) {
+				if atomic.LoadUint32(&q.closed) != 0 {
+					return i
+				}
+			}
+			q.localRead = atomic.LoadInt64(&q.read)
+		}
+
+		q.buffer[q.nextWrite] = v
+		q.nextWrite = afterNextWrite
+	}
+	q.FlushSend()
+	return len(vs)
+}
+
+func (q *SPSCrsMC[T]) send(v T, block bool, done <-chan struct{}) bool {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
 
-func (q *SPSCrsMC[T]) send(v T, block bool) bool {
 	afterNextWrite := q.next(q.nextWrite)
 	if afterNextWrite == q.localRead {
 		for try := 0; afterNextWrite == atomic.LoadInt64(&q.read); spin(&try) {
-			if !block {
+			if !block || atomic.LoadUint32(&q.closed) != 0 || canceled(done) {
 				return false
 			}
 		}
@@ -83,18 +150,78 @@ func (q *SPSCrsMC[T]) FlushSend() {
 	q.writeBatch = 0
 }
 
-func (q *SPSCrsMC[T]) recv(v *T, block bool) bool {
+// RecvBatch receives values to vs and blocks when the queue is empty,
+// returns the number of values received or 0 when the queue has been closed and drained
+func (q *SPSCrsMC[T]) RecvBatch(vs []T) int {
+	if len(vs) == 0 {
+		return 0
+	}
+
+	n := q.readable()
+	if n > int64(len(vs)) {
+		n = int64(len(vs))
+	}
+	var zero T
+	for i := range vs[:n] {
+		vs[i], q.buffer[q.nextRead] = q.buffer[q.nextRead], zero
+		q.nextRead = q.next(q.nextRead)
+	}
+	q.FlushRecv()
+	return int(n)
+}
+
+// RecvBatchFunc calls fn for all values in the queue and blocks when the queue is empty,
+// returns the number of values received or 0 when the queue has been closed and drained
+func (q *SPSCrsMC[T]) RecvBatchFunc(fn func(v T)) int {
+	n := q.readable()
+	var zero T
+	for i := int64(0); i < n; i++ {
+		v := q.buffer[q.nextRead]
+		q.buffer[q.nextRead] = zero
+		fn(v)
+		q.nextRead = q.next(q.nextRead)
+	}
+	q.FlushRecv()
+	return int(n)
+}
+
+// readable waits until there are values to read,
+// returns the number of values or 0 when the queue has been closed and drained
+func (q *SPSCrsMC[T]) readable() int64 {
+	for try := 0; ; spin(&try) {
+		drained := atomic.LoadUint32(&q.closed) != 0 && atomic.LoadInt64(&q.sending) == 0
+		q.localWrite = atomic.LoadInt64(&q.write)
+		if q.nextRead != q.localWrite {
+			break
+		}
+		if drained {
+			return 0
+		}
+	}
+
+	n := q.localWrite - q.nextRead
+	if n < 0 {
+		n += int64(len(q.buffer))
+	}
+	return n
+}
+
+func (q *SPSCrsMC[T]) recv(v *T, block bool, done <-chan struct{}) bool {
 	if q.nextRead == q.localWrite {
-		for try := 0; q.nextRead == atomic.LoadInt64(&q.write); spin(&try) {
-			if !block {
+		for try := 0; ; spin(&try) {
+			drained := atomic.LoadUint32(&q.closed) != 0 && atomic.LoadInt64(&q.sending) == 0
+			if q.nextRead != atomic.LoadInt64(&q.write) {
+				break
+			}
+			if !block || drained || canceled(done) {
 				return false
 			}
 		}
 		q.localWrite = atomic.LoadInt64(&q.write)
 	}
 
-	*v = q.buffer[q.nextRead]
-	// q.buffer[q.nextRead] = 0 clear value, only needed for pointers
+	var zero T
+	*v, q.buffer[q.nextRead] = q.buffer[q.nextRead], zero
 
 	q.nextRead = q.next(q.nextRead)
 	q.readBatch++
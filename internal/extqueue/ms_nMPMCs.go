@@ -0,0 +1,139 @@
+package extqueue
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// MPMCnsMS is an unbounded MPMC queue based on
+// "Simple, Fast, and Practical Non-Blocking and Blocking Concurrent Queue Algorithms"
+// by Maged M. Michael and Michael L. Scott.
+//
+// Nodes are never reused, so the garbage collector takes care of
+// memory reclamation and the ABA problem.
+type MPMCnsMS[T any] struct {
+	stub    Node[T]
+	closed  uint32
+	sending int64
+	_       [6]uint64
+	head    unsafe.Pointer
+	_       [7]uint64
+	tail    unsafe.Pointer
+	_       [7]uint64
+}
+
+// NewMPMCnsMS creates a MPMCnsMS queue
+func NewMPMCnsMS[T any]() *MPMCnsMS[T] {
+	q := &MPMCnsMS[T]{}
+	q.head = unsafe.Pointer(&q.stub)
+	q.tail = unsafe.Pointer(&q.stub)
+	return q
+}
+
+// MultipleProducers makes this a MP queue
+func (q *MPMCnsMS[T]) MultipleProducers() {}
+
+// MultipleConsumers makes this a MC queue
+func (q *MPMCnsMS[T]) MultipleConsumers() {}
+
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPMCnsMS[T]) Close() { atomic.StoreUint32(&q.closed, 1) }
+
+// Send sends a value to the queue, always succeeds unless the queue has been closed
+func (q *MPMCnsMS[T]) Send(value T) bool {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+
+	n := unsafe.Pointer(&Node[T]{Value: value})
+	for try := 0; ; spin(&try) {
+		tail := atomic.LoadPointer(&q.tail)
+		next := atomic.LoadPointer(&(*Node[T])(tail).next)
+		if tail != atomic.LoadPointer(&q.tail) {
+			continue
+		}
+
+		if next != nil {
+			// tail is lagging behind, help to move it forward
+			atomic.CompareAndSwapPointer(&q.tail, tail, next)
+			continue
+		}
+
+		if atomic.CompareAndSwapPointer(&(*Node[T])(tail).next, nil, n) {
+			// it's fine to fail, someone else has already moved the tail
+			atomic.CompareAndSwapPointer(&q.tail, tail, n)
+			return true
+		}
+	}
+}
+
+// TrySend sends a value to the queue, always succeeds unless the queue has been closed
+func (q *MPMCnsMS[T]) TrySend(value T) bool { return q.Send(value) }
+
+// SendContext sends a value to the queue, the queue is unbounded so it never waits for ctx
+func (q *MPMCnsMS[T]) SendContext(ctx context.Context, value T) bool { return q.Send(value) }
+
+// SendTimeout sends a value to the queue, the queue is unbounded so it never waits for timeout
+func (q *MPMCnsMS[T]) SendTimeout(value T, timeout time.Duration) bool { return q.Send(value) }
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPMCnsMS[T]) Recv(value *T) bool { return q.recv(value, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPMCnsMS[T]) RecvContext(ctx context.Context, value *T) bool {
+	return q.recv(value, ctx.Done())
+}
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPMCnsMS[T]) RecvTimeout(value *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, value, timeout)
+}
+
+func (q *MPMCnsMS[T]) recv(value *T, done <-chan struct{}) bool {
+	for wait := 0; ; spin(&wait) {
+		drained := atomic.LoadUint32(&q.closed) != 0 && atomic.LoadInt64(&q.sending) == 0
+		if q.TryRecv(value) {
+			return true
+		}
+		if drained || canceled(done) {
+			return false
+		}
+	}
+}
+
+// TryRecv receives a value from the queue and returns when it is empty
+func (q *MPMCnsMS[T]) TryRecv(value *T) bool {
+	for try := 0; ; spin(&try) {
+		head := atomic.LoadPointer(&q.head)
+		tail := atomic.LoadPointer(&q.tail)
+		next := atomic.LoadPointer(&(*Node[T])(head).next)
+		if head != atomic.LoadPointer(&q.head) {
+			continue
+		}
+
+		if head == tail {
+			if next == nil {
+				return false
+			}
+			// tail is lagging behind, help to move it forward
+			atomic.CompareAndSwapPointer(&q.tail, tail, next)
+			continue
+		}
+
+		if atomic.CompareAndSwapPointer(&q.head, head, next) {
+			// next is the new dummy node, only the winner of the CAS reads its value
+			node := (*Node[T])(next)
+			var zero T
+			*value, node.Value = node.Value, zero
+			return true
+		}
+	}
+}
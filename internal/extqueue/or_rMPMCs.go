@@ -0,0 +1,179 @@
+package extqueue
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// MPMCrsOR is a MPMC ring buffer queue using ordered reservations.
+//
+// Producers and consumers reserve a slot by advancing a reserve counter,
+// and after writing or reading the slot commit it in the reservation order.
+// A slot is reserved only when it's available, hence committing never
+// waits for the other side.
+type MPMCrsOR[T any] struct {
+	_ [8]uint64
+	// producer
+	sendReserve int64
+	sendCommit  int64
+	_           [8 - 2]uint64
+	// consumer
+	recvReserve int64
+	recvCommit  int64
+	_           [8 - 2]uint64
+	// closing
+	closed uint32
+	_      [8 - 1]uint64
+	// constant
+	mask   int64
+	buffer []T
+	// waiting
+	sendw, recvw         Waiter
+	sendReady, recvReady func() bool
+}
+
+// NewMPMCrsOR creates a new MPMCrsOR queue
+func NewMPMCrsOR[T any](size int, opts ...Option) *MPMCrsOR[T] {
+	q := &MPMCrsOR[T]{}
+	q.buffer = make([]T, int(nextPowerOfTwo(uint32(size))))
+	q.mask = int64(len(q.buffer) - 1)
+
+	cfg := newConfig(opts)
+	blockOnOverflow(cfg)
+	q.sendw = cfg.wait.NewWaiter()
+	q.recvw = cfg.wait.NewWaiter()
+	q.sendReady = q.canSend
+	q.recvReady = q.canRecv
+	return q
+}
+
+// Cap returns number of elements this queue can hold before blocking
+func (q *MPMCrsOR[T]) Cap() int { return len(q.buffer) }
+
+// MultipleConsumers makes this a MC queue
+func (q *MPMCrsOR[T]) MultipleConsumers() {}
+
+// MultipleProducers makes this a MP queue
+func (q *MPMCrsOR[T]) MultipleProducers() {}
+
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPMCrsOR[T]) Close() {
+	if atomic.CompareAndSwapUint32(&q.closed, 0, 1) {
+		atomic.AddInt64(&q.sendReserve, sendClosed)
+	}
+	q.sendw.Notify()
+	q.recvw.Notify()
+}
+
+// Send sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed
+func (q *MPMCrsOR[T]) Send(v T) bool { return q.send(v, true, nil) }
+
+// SendContext sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the context is done
+func (q *MPMCrsOR[T]) SendContext(ctx context.Context, v T) bool { return q.send(v, true, ctx.Done()) }
+
+// SendTimeout sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the timeout elapsed
+func (q *MPMCrsOR[T]) SendTimeout(v T, timeout time.Duration) bool {
+	return sendTimeout(q.SendContext, v, timeout)
+}
+
+// TrySend tries to send a value to the queue and returns immediately when it is full or closed
+func (q *MPMCrsOR[T]) TrySend(v T) bool { return q.send(v, false, nil) }
+
+func (q *MPMCrsOR[T]) send(v T, block bool, done <-chan struct{}) bool {
+	var pos int64
+	for wait := 0; ; wait++ {
+		if atomic.LoadUint32(&q.closed) != 0 {
+			return false
+		}
+
+		pos = atomic.LoadInt64(&q.sendReserve)
+		if pos-atomic.LoadInt64(&q.recvCommit) <= q.mask {
+			if atomic.CompareAndSwapInt64(&q.sendReserve, pos, pos+1) {
+				break
+			}
+			continue
+		}
+
+		// full
+		if !block || canceled(done) {
+			return false
+		}
+		q.sendw.Wait(wait, q.sendReady, done)
+	}
+
+	q.buffer[pos&q.mask] = v
+
+	// wait for previous writes to complete
+	for try := 0; atomic.LoadInt64(&q.sendCommit) != pos; spin(&try) {
+	}
+	atomic.StoreInt64(&q.sendCommit, pos+1)
+	q.recvw.Notify()
+	return true
+}
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPMCrsOR[T]) Recv(v *T) bool { return q.recv(v, true, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPMCrsOR[T]) RecvContext(ctx context.Context, v *T) bool { return q.recv(v, true, ctx.Done()) }
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPMCrsOR[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
+
+// TryRecv receives a value from the queue and returns when it is empty
+func (q *MPMCrsOR[T]) TryRecv(v *T) bool { return q.recv(v, false, nil) }
+
+func (q *MPMCrsOR[T]) recv(v *T, block bool, done <-chan struct{}) bool {
+	var pos int64
+	for wait := 0; ; wait++ {
+		pos = atomic.LoadInt64(&q.recvReserve)
+		if pos < atomic.LoadInt64(&q.sendCommit) {
+			if atomic.CompareAndSwapInt64(&q.recvReserve, pos, pos+1) {
+				break
+			}
+			continue
+		}
+
+		// empty
+		if !block || q.drained() || canceled(done) {
+			return false
+		}
+		q.recvw.Wait(wait, q.recvReady, done)
+	}
+
+	var zero T
+	*v, q.buffer[pos&q.mask] = q.buffer[pos&q.mask], zero
+
+	// wait for previous reads to complete
+	for try := 0; atomic.LoadInt64(&q.recvCommit) != pos; spin(&try) {
+	}
+	atomic.StoreInt64(&q.recvCommit, pos+1)
+	q.sendw.Notify()
+	return true
+}
+
+// canSend returns whether sending might succeed without waiting
+func (q *MPMCrsOR[T]) canSend() bool {
+	return atomic.LoadInt64(&q.sendReserve)-atomic.LoadInt64(&q.recvCommit) <= q.mask ||
+		atomic.LoadUint32(&q.closed) != 0
+}
+
+// canRecv returns whether receiving might succeed without waiting
+func (q *MPMCrsOR[T]) canRecv() bool {
+	return atomic.LoadInt64(&q.recvReserve) < atomic.LoadInt64(&q.sendCommit) ||
+		atomic.LoadUint32(&q.closed) != 0
+}
+
+// drained returns whether the queue has been closed and all the values have been received
+func (q *MPMCrsOR[T]) drained() bool {
+	return atomic.LoadInt64(&q.sendReserve)-atomic.LoadInt64(&q.recvReserve) == sendClosed
+}
@@ -0,0 +1,167 @@
+package extqueue
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// MPSCrsOR is a MPSC ring buffer queue using ordered reservations.
+//
+// Producers reserve a slot by advancing a reserve counter,
+// and after writing the slot commit it in the reservation order.
+// A slot is reserved only when it's available, hence committing never
+// waits for the consumer.
+type MPSCrsOR[T any] struct {
+	_ [8]uint64
+	// producer
+	sendReserve int64
+	sendCommit  int64
+	_           [8 - 2]uint64
+	// consumer
+	recvCommit int64
+	_          [8 - 1]uint64
+	// closing
+	closed uint32
+	_      [8 - 1]uint64
+	// constant
+	mask   int64
+	buffer []T
+	// waiting
+	sendw, recvw         Waiter
+	sendReady, recvReady func() bool
+}
+
+// NewMPSCrsOR creates a new MPSCrsOR queue
+func NewMPSCrsOR[T any](size int, opts ...Option) *MPSCrsOR[T] {
+	q := &MPSCrsOR[T]{}
+	q.buffer = make([]T, int(nextPowerOfTwo(uint32(size))))
+	q.mask = int64(len(q.buffer) - 1)
+
+	cfg := newConfig(opts)
+	blockOnOverflow(cfg)
+	q.sendw = cfg.wait.NewWaiter()
+	q.recvw = cfg.wait.NewWaiter()
+	q.sendReady = q.canSend
+	q.recvReady = q.canRecv
+	return q
+}
+
+// Cap returns number of elements this queue can hold before blocking
+func (q *MPSCrsOR[T]) Cap() int { return len(q.buffer) }
+
+// MultipleProducers makes this a MP queue
+func (q *MPSCrsOR[T]) MultipleProducers() {}
+
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPSCrsOR[T]) Close() {
+	if atomic.CompareAndSwapUint32(&q.closed, 0, 1) {
+		atomic.AddInt64(&q.sendReserve, sendClosed)
+	}
+	q.sendw.Notify()
+	q.recvw.Notify()
+}
+
+// Send sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed
+func (q *MPSCrsOR[T]) Send(v T) bool { return q.send(v, true, nil) }
+
+// SendContext sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the context is done
+func (q *MPSCrsOR[T]) SendContext(ctx context.Context, v T) bool { return q.send(v, true, ctx.Done()) }
+
+// SendTimeout sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the timeout elapsed
+func (q *MPSCrsOR[T]) SendTimeout(v T, timeout time.Duration) bool {
+	return sendTimeout(q.SendContext, v, timeout)
+}
+
+// TrySend tries to send a value to the queue and returns immediately when it is full or closed
+func (q *MPSCrsOR[T]) TrySend(v T) bool { return q.send(v, false, nil) }
+
+func (q *MPSCrsOR[T]) send(v T, block bool, done <-chan struct{}) bool {
+	var pos int64
+	for wait := 0; ; wait++ {
+		if atomic.LoadUint32(&q.closed) != 0 {
+			return false
+		}
+
+		pos = atomic.LoadInt64(&q.sendReserve)
+		if pos-atomic.LoadInt64(&q.recvCommit) <= q.mask {
+			if atomic.CompareAndSwapInt64(&q.sendReserve, pos, pos+1) {
+				break
+			}
+			continue
+		}
+
+		// full
+		if !block || canceled(done) {
+			return false
+		}
+		q.sendw.Wait(wait, q.sendReady, done)
+	}
+
+	q.buffer[pos&q.mask] = v
+
+	// wait for previous writes to complete
+	for try := 0; atomic.LoadInt64(&q.sendCommit) != pos; spin(&try) {
+	}
+	atomic.StoreInt64(&q.sendCommit, pos+1)
+	q.recvw.Notify()
+	return true
+}
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPSCrsOR[T]) Recv(v *T) bool { return q.recv(v, true, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPSCrsOR[T]) RecvContext(ctx context.Context, v *T) bool { return q.recv(v, true, ctx.Done()) }
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPSCrsOR[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
+
+// TryRecv receives a value from the queue and returns when it is empty
+func (q *MPSCrsOR[T]) TryRecv(v *T) bool { return q.recv(v, false, nil) }
+
+func (q *MPSCrsOR[T]) recv(v *T, block bool, done <-chan struct{}) bool {
+	pos := q.recvCommit
+	for wait := 0; ; wait++ {
+		if pos < atomic.LoadInt64(&q.sendCommit) {
+			break
+		}
+
+		// empty
+		if !block || q.drained() || canceled(done) {
+			return false
+		}
+		q.recvw.Wait(wait, q.recvReady, done)
+	}
+
+	var zero T
+	*v, q.buffer[pos&q.mask] = q.buffer[pos&q.mask], zero
+	atomic.StoreInt64(&q.recvCommit, pos+1)
+	q.sendw.Notify()
+	return true
+}
+
+// canSend returns whether sending might succeed without waiting
+func (q *MPSCrsOR[T]) canSend() bool {
+	return atomic.LoadInt64(&q.sendReserve)-atomic.LoadInt64(&q.recvCommit) <= q.mask ||
+		atomic.LoadUint32(&q.closed) != 0
+}
+
+// canRecv returns whether receiving might succeed without waiting
+func (q *MPSCrsOR[T]) canRecv() bool {
+	return atomic.LoadInt64(&q.recvCommit) < atomic.LoadInt64(&q.sendCommit) ||
+		atomic.LoadUint32(&q.closed) != 0
+}
+
+// drained returns whether the queue has been closed and all the values have been received
+func (q *MPSCrsOR[T]) drained() bool {
+	return atomic.LoadInt64(&q.sendReserve)-atomic.LoadInt64(&q.recvCommit) == sendClosed
+}
@@ -0,0 +1,181 @@
+package extqueue
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// SPMCrsOR is a SPMC ring buffer queue using ordered reservations.
+//
+// Consumers reserve a slot by advancing a reserve counter,
+// and after reading the slot commit it in the reservation order.
+// A slot is reserved only when it's available, hence committing never
+// waits for the producer.
+type SPMCrsOR[T any] struct {
+	_ [8]uint64
+	// producer
+	sendCommit int64
+	_          [8 - 1]uint64
+	// consumer
+	recvReserve int64
+	recvCommit  int64
+	_           [8 - 2]uint64
+	// closing
+	closed uint32
+	_      [8 - 1]uint64
+	// constant
+	mask   int64
+	buffer []T
+	// waiting
+	sendw, recvw         Waiter
+	sendReady, recvReady func() bool
+}
+
+// NewSPMCrsOR creates a new SPMCrsOR queue
+func NewSPMCrsOR[T any](size int, opts ...Option) *SPMCrsOR[T] {
+	q := &SPMCrsOR[T]{}
+	q.buffer = make([]T, int(nextPowerOfTwo(uint32(size))))
+	q.mask = int64(len(q.buffer) - 1)
+
+	cfg := newConfig(opts)
+	blockOnOverflow(cfg)
+	q.sendw = cfg.wait.NewWaiter()
+	q.recvw = cfg.wait.NewWaiter()
+	q.sendReady = q.canSend
+	q.recvReady = q.canRecv
+	return q
+}
+
+// Cap returns number of elements this queue can hold before blocking
+func (q *SPMCrsOR[T]) Cap() int { return len(q.buffer) }
+
+// MultipleConsumers makes this a MC queue
+func (q *SPMCrsOR[T]) MultipleConsumers() {}
+
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *SPMCrsOR[T]) Close() {
+	if atomic.CompareAndSwapUint32(&q.closed, 0, 1) {
+		atomic.AddInt64(&q.sendCommit, sendClosed)
+	}
+	q.sendw.Notify()
+	q.recvw.Notify()
+}
+
+// Send sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed
+func (q *SPMCrsOR[T]) Send(v T) bool { return q.send(v, true, nil) }
+
+// SendContext sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the context is done
+func (q *SPMCrsOR[T]) SendContext(ctx context.Context, v T) bool { return q.send(v, true, ctx.Done()) }
+
+// SendTimeout sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the timeout elapsed
+func (q *SPMCrsOR[T]) SendTimeout(v T, timeout time.Duration) bool {
+	return sendTimeout(q.SendContext, v, timeout)
+}
+
+// TrySend tries to send a value to the queue and returns immediately when it is full or closed
+func (q *SPMCrsOR[T]) TrySend(v T) bool { return q.send(v, false, nil) }
+
+func (q *SPMCrsOR[T]) send(v T, block bool, done <-chan struct{}) bool {
+	pos := atomic.LoadInt64(&q.sendCommit)
+	for wait := 0; ; wait++ {
+		if atomic.LoadUint32(&q.closed) != 0 {
+			return false
+		}
+		if pos-atomic.LoadInt64(&q.recvCommit) <= q.mask {
+			break
+		}
+
+		// full
+		if !block || canceled(done) {
+			return false
+		}
+		q.sendw.Wait(wait, q.sendReady, done)
+	}
+
+	q.buffer[pos&q.mask] = v
+	if !atomic.CompareAndSwapInt64(&q.sendCommit, pos, pos+1) {
+		// closed concurrently, the value won't be received
+		var zero T
+		q.buffer[pos&q.mask] = zero
+		return false
+	}
+	q.recvw.Notify()
+	return true
+}
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *SPMCrsOR[T]) Recv(v *T) bool { return q.recv(v, true, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *SPMCrsOR[T]) RecvContext(ctx context.Context, v *T) bool { return q.recv(v, true, ctx.Done()) }
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *SPMCrsOR[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
+
+// TryRecv receives a value from the queue and returns when it is empty
+func (q *SPMCrsOR[T]) TryRecv(v *T) bool { return q.recv(v, false, nil) }
+
+func (q *SPMCrsOR[T]) recv(v *T, block bool, done <-chan struct{}) bool {
+	var pos int64
+	for wait := 0; ; wait++ {
+		pos = atomic.LoadInt64(&q.recvReserve)
+		if pos < q.committed() {
+			if atomic.CompareAndSwapInt64(&q.recvReserve, pos, pos+1) {
+				break
+			}
+			continue
+		}
+
+		// empty
+		if !block || q.drained() || canceled(done) {
+			return false
+		}
+		q.recvw.Wait(wait, q.recvReady, done)
+	}
+
+	var zero T
+	*v, q.buffer[pos&q.mask] = q.buffer[pos&q.mask], zero
+
+	// wait for previous reads to complete
+	for try := 0; atomic.LoadInt64(&q.recvCommit) != pos; spin(&try) {
+	}
+	atomic.StoreInt64(&q.recvCommit, pos+1)
+	q.sendw.Notify()
+	return true
+}
+
+// canSend returns whether sending might succeed without waiting
+func (q *SPMCrsOR[T]) canSend() bool {
+	return atomic.LoadInt64(&q.sendCommit)-atomic.LoadInt64(&q.recvCommit) <= q.mask ||
+		atomic.LoadUint32(&q.closed) != 0
+}
+
+// canRecv returns whether receiving might succeed without waiting
+func (q *SPMCrsOR[T]) canRecv() bool {
+	return atomic.LoadInt64(&q.recvReserve) < q.committed() ||
+		atomic.LoadUint32(&q.closed) != 0
+}
+
+// committed returns the position up to which the values have been sent
+func (q *SPMCrsOR[T]) committed() int64 {
+	// positions don't wrap around, so only Close moves sendCommit this far
+	commit := atomic.LoadInt64(&q.sendCommit)
+	if commit >= sendClosed {
+		commit -= sendClosed
+	}
+	return commit
+}
+
+// drained returns whether the queue has been closed and all the values have been received
+func (q *SPMCrsOR[T]) drained() bool {
+	return atomic.LoadInt64(&q.sendCommit)-atomic.LoadInt64(&q.recvReserve) == sendClosed
+}
@@ -0,0 +1,123 @@
+package extqueue
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Overflow decides what Send does when a bounded queue is full.
+//
+// TrySend is not affected by the policy, it always fails when the queue is full.
+type Overflow int
+
+const (
+	// OverflowBlock waits until the queue has space, this is the default.
+	OverflowBlock Overflow = iota
+	// OverflowFail makes Send return false.
+	OverflowFail
+	// OverflowDropNewest drops the value being sent.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest value in the queue to make space,
+	// it's only supported by multi-consumer queues.
+	OverflowDropOldest
+	// OverflowHandler drops the value being sent and passes it to the handler,
+	// use WithOverflowHandler to set it.
+	OverflowHandler
+)
+
+func (policy Overflow) String() string {
+	switch policy {
+	case OverflowBlock:
+		return "Block"
+	case OverflowFail:
+		return "Fail"
+	case OverflowDropNewest:
+		return "DropNewest"
+	case OverflowDropOldest:
+		return "DropOldest"
+	case OverflowHandler:
+		return "Handler"
+	default:
+		return fmt.Sprintf("Overflow(%d)", int(policy))
+	}
+}
+
+// WithOverflow sets what Send does when a bounded queue is full.
+//
+// It is supported by MPMCqGo, MPMCqpGo, MPMCqsDV, MPMCqDV and SPSCrMC,
+// other queues panic when a policy other than OverflowBlock is set.
+func WithOverflow(policy Overflow) Option {
+	return func(c *config) { c.overflow = policy }
+}
+
+// WithOverflowHandler makes Send drop values that don't fit in a full queue and
+// call fn with them. fn must match the value type of the queue.
+//
+// It is supported by the same queues as WithOverflow.
+func WithOverflowHandler[T any](fn func(v T)) Option {
+	return func(c *config) {
+		c.overflow = OverflowHandler
+		c.overflowHandler = fn
+	}
+}
+
+// overflow implements overflow policies for bounded queues.
+type overflow[T any] struct {
+	policy  Overflow
+	handler func(T)
+	dropped uint64
+}
+
+func newOverflow[T any](c config, multipleConsumers bool) overflow[T] {
+	o := overflow[T]{policy: c.overflow}
+	switch c.overflow {
+	case OverflowBlock, OverflowFail, OverflowDropNewest:
+	case OverflowDropOldest:
+		if !multipleConsumers {
+			panic("extqueue: OverflowDropOldest requires a multi-consumer queue")
+		}
+	case OverflowHandler:
+		fn, ok := c.overflowHandler.(func(T))
+		if !ok {
+			var zero T
+			panic(fmt.Sprintf("extqueue: overflow handler %T does not match values %T", c.overflowHandler, zero))
+		}
+		o.handler = fn
+	default:
+		panic("extqueue: unknown overflow policy " + c.overflow.String())
+	}
+	return o
+}
+
+// blockOnOverflow panics when c sets an overflow policy,
+// it's used by queues which only support blocking on a full queue.
+func blockOnOverflow(c config) {
+	if c.overflow != OverflowBlock {
+		panic("extqueue: overflow policy " + c.overflow.String() + " is not supported by this queue")
+	}
+}
+
+// reject handles a value that doesn't fit in the queue,
+// returns the result for Send.
+func (o *overflow[T]) reject(v T) bool {
+	switch o.policy {
+	case OverflowFail:
+		return false
+	case OverflowHandler:
+		o.handler(v)
+	}
+	atomic.AddUint64(&o.dropped, 1)
+	return true
+}
+
+// evict drops the oldest value from the queue using tryRecv.
+func (o *overflow[T]) evict(tryRecv func(*T) bool) {
+	var v T
+	if tryRecv(&v) {
+		atomic.AddUint64(&o.dropped, 1)
+	} else {
+		// the queue is temporarily neither full nor empty,
+		// let the other senders or receivers finish
+		wait()
+	}
+}
@@ -0,0 +1,38 @@
+package extqueue
+
+import "testing"
+
+func TestOverflowHandler(t *testing.T) {
+	var handled []int
+	q := NewMPMCqGo[int](2, WithOverflowHandler(func(v int) { handled = append(handled, v) }))
+	for i := 0; i < 5; i++ {
+		if !q.Send(i) {
+			t.Fatalf("failed to send %v", i)
+		}
+	}
+
+	if len(handled) != 3 || handled[0] != 2 || handled[1] != 3 || handled[2] != 4 {
+		t.Fatalf("handled %v, expected [2 3 4]", handled)
+	}
+	if got := q.Overflowed(); got != 3 {
+		t.Fatalf("overflowed %v, expected 3", got)
+	}
+}
+
+func TestOverflowInvalid(t *testing.T) {
+	mustPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%v: expected panic", name)
+			}
+		}()
+		fn()
+	}
+
+	mustPanic("DropOldest", func() { NewSPSCrMC[int](1, 8, WithOverflow(OverflowDropOldest)) })
+	mustPanic("Handler", func() { NewMPMCqGo[int](8, WithOverflow(OverflowHandler)) })
+	mustPanic("HandlerType", func() { NewMPMCqGo[int](8, WithOverflowHandler(func(string) {})) })
+	mustPanic("Unsupported", func() { NewMPSCqsDV[int](8, WithOverflow(OverflowFail)) })
+	mustPanic("UnsupportedLanes", func() { NewMPMCqsPL[int](2, 8, WithOverflow(OverflowDropNewest)) })
+}
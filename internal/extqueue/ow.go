@@ -0,0 +1,99 @@
+package extqueue
+
+import "sync/atomic"
+
+// owRing is a ring buffer, where producers overwrite the oldest value instead
+// of waiting for the consumer, which is used by the OW queues.
+//
+// Every slot has a sequence number 4*pos+phase, where pos is the position of the
+// value in the stream. The consumer detects overwritten values by finding a slot
+// with a later position and then skips to the oldest value that might be still available.
+type owRing[T any] struct {
+	mask  uint64
+	slots []owSlot[T]
+	_     [5]uint64
+	// producers
+	sendx uint64
+	_     [7]uint64
+	// consumer
+	recvx   uint64
+	skipped uint64
+	dropped uint64
+	_       [5]uint64
+}
+
+type owSlot[T any] struct {
+	seq   uint64
+	value T
+}
+
+// slot phases
+const (
+	owEmpty = iota
+	owWriting
+	owFull
+	owReading
+)
+
+func (r *owRing[T]) init(size int) {
+	r.mask = uint64(size) - 1
+	r.slots = make([]owSlot[T], size)
+	for i := range r.slots {
+		r.slots[i].seq = uint64(i) * 4
+	}
+}
+
+// write writes a value to a claimed position.
+func (r *owRing[T]) write(pos uint64, v T) {
+	slot := &r.slots[pos&r.mask]
+	prev := pos - (r.mask + 1)
+	for try := 0; ; spin(&try) {
+		// the slot is either empty or contains an unread value from the previous lap,
+		// otherwise the consumer or the previous writer is still using it
+		seq := atomic.LoadUint64(&slot.seq)
+		if seq == pos*4+owEmpty || seq == prev*4+owFull {
+			if atomic.CompareAndSwapUint64(&slot.seq, seq, pos*4+owWriting) {
+				break
+			}
+		}
+	}
+	slot.value = v
+	atomic.StoreUint64(&slot.seq, pos*4+owFull)
+}
+
+// tryRecv receives the next value, must only be called by the consumer.
+func (r *owRing[T]) tryRecv(v *T) bool {
+	for {
+		pos := r.recvx
+		slot := &r.slots[pos&r.mask]
+		seq := atomic.LoadUint64(&slot.seq)
+		switch {
+		case seq == pos*4+owFull:
+			if !atomic.CompareAndSwapUint64(&slot.seq, seq, pos*4+owReading) {
+				// a producer started overwriting the value
+				continue
+			}
+			var zero T
+			*v, slot.value = slot.value, zero
+			atomic.StoreUint64(&slot.seq, (pos+r.mask+1)*4+owEmpty)
+
+			r.recvx = pos + 1
+			r.dropped, r.skipped = r.skipped, 0
+			return true
+		case seq/4 > pos:
+			// the value has been overwritten
+			oldest := atomic.LoadUint64(&r.sendx) - (r.mask + 1)
+			r.skipped += oldest - pos
+			r.recvx = oldest
+		default:
+			return false
+		}
+	}
+}
+
+// ready returns whether tryRecv can make progress.
+func (r *owRing[T]) ready() bool {
+	pos := r.recvx
+	seq := atomic.LoadUint64(&r.slots[pos&r.mask].seq)
+	return seq == pos*4+owFull || seq/4 > pos
+}
@@ -0,0 +1,113 @@
+package extqueue
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// MPSCqsOW is a bounded MPSC queue, which overwrites the oldest value when it is full,
+// hence producers never wait for the consumer.
+//
+// Dropped reports how many values were overwritten before the last received value.
+type MPSCqsOW[T any] struct {
+	ring    owRing[T]
+	closed  uint32
+	sending int64
+	// waiting
+	recvw     Waiter
+	recvReady func() bool
+}
+
+// NewMPSCqsOW creates a MPSCqsOW queue
+func NewMPSCqsOW[T any](size int, opts ...Option) *MPSCqsOW[T] {
+	if size <= 1 {
+		size = 2
+	}
+	size = int(nextPowerOfTwo(uint32(size - 1)))
+
+	q := &MPSCqsOW[T]{}
+	q.ring.init(size)
+
+	cfg := newConfig(opts)
+	blockOnOverflow(cfg)
+	q.recvw = cfg.wait.NewWaiter()
+	q.recvReady = q.canRecv
+
+	return q
+}
+
+// Cap returns number of elements this queue can hold before overwriting
+func (q *MPSCqsOW[T]) Cap() int { return len(q.ring.slots) }
+
+// MultipleProducers makes this a MP queue
+func (q *MPSCqsOW[T]) MultipleProducers() {}
+
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPSCqsOW[T]) Close() {
+	atomic.StoreUint32(&q.closed, 1)
+	q.recvw.Notify()
+}
+
+// Send sends a value to the queue and overwrites the oldest value when it is full,
+// returns false when the queue has been closed
+func (q *MPSCqsOW[T]) Send(v T) bool {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+
+	pos := atomic.AddUint64(&q.ring.sendx, 1) - 1
+	q.ring.write(pos, v)
+	q.recvw.Notify()
+	return true
+}
+
+// TrySend sends a value to the queue, always succeeds unless the queue has been closed
+func (q *MPSCqsOW[T]) TrySend(v T) bool { return q.Send(v) }
+
+// SendContext sends a value to the queue, the queue never blocks so it never waits for ctx
+func (q *MPSCqsOW[T]) SendContext(ctx context.Context, v T) bool { return q.Send(v) }
+
+// SendTimeout sends a value to the queue, the queue never blocks so it never waits for timeout
+func (q *MPSCqsOW[T]) SendTimeout(v T, timeout time.Duration) bool { return q.Send(v) }
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPSCqsOW[T]) Recv(v *T) bool { return q.recv(v, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPSCqsOW[T]) RecvContext(ctx context.Context, v *T) bool { return q.recv(v, ctx.Done()) }
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPSCqsOW[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
+
+func (q *MPSCqsOW[T]) recv(v *T, done <-chan struct{}) bool {
+	for wait := 0; ; wait++ {
+		drained := atomic.LoadUint32(&q.closed) != 0 && atomic.LoadInt64(&q.sending) == 0
+		if q.TryRecv(v) {
+			return true
+		}
+		if drained || canceled(done) {
+			return false
+		}
+		q.recvw.Wait(wait, q.recvReady, done)
+	}
+}
+
+// TryRecv receives a value from the queue and returns when it is empty
+func (q *MPSCqsOW[T]) TryRecv(v *T) bool { return q.ring.tryRecv(v) }
+
+// Dropped returns number of values that were overwritten between
+// the previous and the last received value
+func (q *MPSCqsOW[T]) Dropped() uint64 { return q.ring.dropped }
+
+func (q *MPSCqsOW[T]) canRecv() bool {
+	return atomic.LoadUint32(&q.closed) != 0 || q.ring.ready()
+}
@@ -0,0 +1,111 @@
+package extqueue
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// SPSCqsOW is a bounded SPSC queue, which overwrites the oldest value when it is full,
+// hence the producer never waits for the consumer.
+//
+// Dropped reports how many values were overwritten before the last received value.
+type SPSCqsOW[T any] struct {
+	ring    owRing[T]
+	closed  uint32
+	sending int64
+	// waiting
+	recvw     Waiter
+	recvReady func() bool
+}
+
+// NewSPSCqsOW creates a SPSCqsOW queue
+func NewSPSCqsOW[T any](size int, opts ...Option) *SPSCqsOW[T] {
+	if size <= 1 {
+		size = 2
+	}
+	size = int(nextPowerOfTwo(uint32(size - 1)))
+
+	q := &SPSCqsOW[T]{}
+	q.ring.init(size)
+
+	cfg := newConfig(opts)
+	blockOnOverflow(cfg)
+	q.recvw = cfg.wait.NewWaiter()
+	q.recvReady = q.canRecv
+
+	return q
+}
+
+// Cap returns number of elements this queue can hold before overwriting
+func (q *SPSCqsOW[T]) Cap() int { return len(q.ring.slots) }
+
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *SPSCqsOW[T]) Close() {
+	atomic.StoreUint32(&q.closed, 1)
+	q.recvw.Notify()
+}
+
+// Send sends a value to the queue and overwrites the oldest value when it is full,
+// returns false when the queue has been closed
+func (q *SPSCqsOW[T]) Send(v T) bool {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+
+	pos := q.ring.sendx
+	atomic.StoreUint64(&q.ring.sendx, pos+1)
+	q.ring.write(pos, v)
+	q.recvw.Notify()
+	return true
+}
+
+// TrySend sends a value to the queue, always succeeds unless the queue has been closed
+func (q *SPSCqsOW[T]) TrySend(v T) bool { return q.Send(v) }
+
+// SendContext sends a value to the queue, the queue never blocks so it never waits for ctx
+func (q *SPSCqsOW[T]) SendContext(ctx context.Context, v T) bool { return q.Send(v) }
+
+// SendTimeout sends a value to the queue, the queue never blocks so it never waits for timeout
+func (q *SPSCqsOW[T]) SendTimeout(v T, timeout time.Duration) bool { return q.Send(v) }
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *SPSCqsOW[T]) Recv(v *T) bool { return q.recv(v, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *SPSCqsOW[T]) RecvContext(ctx context.Context, v *T) bool { return q.recv(v, ctx.Done()) }
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *SPSCqsOW[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
+
+func (q *SPSCqsOW[T]) recv(v *T, done <-chan struct{}) bool {
+	for wait := 0; ; wait++ {
+		drained := atomic.LoadUint32(&q.closed) != 0 && atomic.LoadInt64(&q.sending) == 0
+		if q.TryRecv(v) {
+			return true
+		}
+		if drained || canceled(done) {
+			return false
+		}
+		q.recvw.Wait(wait, q.recvReady, done)
+	}
+}
+
+// TryRecv receives a value from the queue and returns when it is empty
+func (q *SPSCqsOW[T]) TryRecv(v *T) bool { return q.ring.tryRecv(v) }
+
+// Dropped returns number of values that were overwritten between
+// the previous and the last received value
+func (q *SPSCqsOW[T]) Dropped() uint64 { return q.ring.dropped }
+
+func (q *SPSCqsOW[T]) canRecv() bool {
+	return atomic.LoadUint32(&q.closed) != 0 || q.ring.ready()
+}
@@ -0,0 +1,233 @@
+package extqueue
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// MPMCqsPL is a MPMC priority queue with a fixed number of lanes,
+// where each lane is a MPMCqsDV queue and lane 0 has the highest priority.
+//
+// By default receiving always takes a value from the highest priority lane.
+// WithLaneWeights makes receiving take values from the lanes in proportion to
+// the weights using smooth weighted round-robin, so that the lower priority
+// lanes are not starved. Values within a lane are received in FIFO order.
+type MPMCqsPL[T any] struct {
+	lanes    []*MPMCqsDV[T]
+	schedule []int
+	weights  []int
+	closed   uint32
+	// waiting
+	recvw     Waiter
+	recvReady func() bool
+	_         [4]uint64
+
+	tick uint64
+	_    [7]uint64
+}
+
+// WithLaneWeights sets weights for the lanes of a priority queue,
+// there must be a positive weight for every lane.
+func WithLaneWeights(weights ...int) Option {
+	return func(c *config) { c.laneWeights = weights }
+}
+
+// NewMPMCqsPL creates a MPMCqsPL queue with the specified number of lanes,
+// where each lane holds size values
+func NewMPMCqsPL[T any](lanes, size int, opts ...Option) *MPMCqsPL[T] {
+	if lanes < 1 {
+		lanes = 1
+	}
+
+	cfg := newConfig(opts)
+	blockOnOverflow(cfg)
+
+	q := &MPMCqsPL[T]{}
+	// the lanes notify the receivers of the queue after sending
+	q.recvw = cfg.wait.NewWaiter()
+	q.lanes = make([]*MPMCqsDV[T], lanes)
+	for i := range q.lanes {
+		q.lanes[i] = newMPMCqsDV[T](size, cfg, q.recvw)
+	}
+
+	if cfg.laneWeights != nil {
+		if len(cfg.laneWeights) != lanes {
+			panic("extqueue: lane weights don't match the number of lanes")
+		}
+		q.weights = append([]int{}, cfg.laneWeights...)
+		q.schedule = plSchedule(q.weights)
+	}
+	q.recvReady = q.canRecv
+
+	return q
+}
+
+// plSchedule creates a smooth weighted round-robin order of lanes.
+func plSchedule(weights []int) []int {
+	total := 0
+	for _, w := range weights {
+		if w <= 0 {
+			panic("extqueue: lane weights must be positive")
+		}
+		total += w
+	}
+
+	schedule := make([]int, total)
+	current := make([]int, len(weights))
+	for k := range schedule {
+		best := 0
+		for i, w := range weights {
+			current[i] += w
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		schedule[k] = best
+	}
+	return schedule
+}
+
+// Cap returns number of elements a single lane can hold before blocking
+func (q *MPMCqsPL[T]) Cap() int { return q.lanes[0].Cap() }
+
+// Priorities returns the number of lanes
+func (q *MPMCqsPL[T]) Priorities() int { return len(q.lanes) }
+
+// Weights returns the lane weights, nil when the highest priority lane is always preferred
+func (q *MPMCqsPL[T]) Weights() []int { return q.weights }
+
+// MultipleConsumers makes this a MC queue
+func (q *MPMCqsPL[T]) MultipleConsumers() {}
+
+// MultipleProducers makes this a MP queue
+func (q *MPMCqsPL[T]) MultipleProducers() {}
+
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPMCqsPL[T]) Close() {
+	atomic.StoreUint32(&q.closed, 1)
+	for _, lane := range q.lanes {
+		lane.Close()
+	}
+}
+
+// lane returns the lane for priority, out of range priorities are clamped.
+func (q *MPMCqsPL[T]) lane(priority int) *MPMCqsDV[T] {
+	if priority < 0 {
+		priority = 0
+	} else if priority >= len(q.lanes) {
+		priority = len(q.lanes) - 1
+	}
+	return q.lanes[priority]
+}
+
+// Send sends a value to the lowest priority lane and blocks when it is full,
+// returns false when the queue has been closed
+func (q *MPMCqsPL[T]) Send(v T) bool { return q.send(v, len(q.lanes)-1, nil) }
+
+// SendContext sends a value to the lowest priority lane and blocks when it is full,
+// returns false when the queue has been closed or the context is done
+func (q *MPMCqsPL[T]) SendContext(ctx context.Context, v T) bool {
+	return q.send(v, len(q.lanes)-1, ctx.Done())
+}
+
+// SendTimeout sends a value to the lowest priority lane and blocks when it is full,
+// returns false when the queue has been closed or the timeout elapsed
+func (q *MPMCqsPL[T]) SendTimeout(v T, timeout time.Duration) bool {
+	return sendTimeout(q.SendContext, v, timeout)
+}
+
+// TrySend tries to send a value to the lowest priority lane and returns immediately when it is full or closed
+func (q *MPMCqsPL[T]) TrySend(v T) bool { return q.TrySendPriority(v, len(q.lanes)-1) }
+
+// SendPriority sends a value to the lane for priority and blocks when it is full,
+// returns false when the queue has been closed
+func (q *MPMCqsPL[T]) SendPriority(v T, priority int) bool { return q.send(v, priority, nil) }
+
+// SendPriorityContext sends a value to the lane for priority and blocks when it is full,
+// returns false when the queue has been closed or the context is done
+func (q *MPMCqsPL[T]) SendPriorityContext(ctx context.Context, v T, priority int) bool {
+	return q.send(v, priority, ctx.Done())
+}
+
+// TrySendPriority tries to send a value to the lane for priority and returns immediately when it is full or closed
+func (q *MPMCqsPL[T]) TrySendPriority(v T, priority int) bool {
+	return q.lane(priority).TrySend(v)
+}
+
+func (q *MPMCqsPL[T]) send(v T, priority int, done <-chan struct{}) bool {
+	return q.lane(priority).send(v, done)
+}
+
+// Recv receives a value from the queue and blocks when all lanes are empty,
+// returns false when the queue has been closed and drained
+func (q *MPMCqsPL[T]) Recv(v *T) bool { return q.recv(v, nil) }
+
+// RecvContext receives a value from the queue and blocks when all lanes are empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPMCqsPL[T]) RecvContext(ctx context.Context, v *T) bool { return q.recv(v, ctx.Done()) }
+
+// RecvTimeout receives a value from the queue and blocks when all lanes are empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPMCqsPL[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
+
+func (q *MPMCqsPL[T]) recv(v *T, done <-chan struct{}) bool {
+	for wait := 0; ; wait++ {
+		if q.TryRecv(v) {
+			return true
+		}
+		if q.drained() || canceled(done) {
+			return false
+		}
+		q.recvw.Wait(wait, q.recvReady, done)
+	}
+}
+
+// TryRecv receives a value from the queue and returns when all lanes are empty
+func (q *MPMCqsPL[T]) TryRecv(v *T) bool {
+	if q.schedule == nil {
+		for _, lane := range q.lanes {
+			if lane.TryRecv(v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the schedule advances only after receiving, so that polling
+	// an empty queue doesn't write to the shared tick
+	tick := atomic.LoadUint64(&q.tick)
+	first := q.schedule[tick%uint64(len(q.schedule))]
+	received := q.lanes[first].TryRecv(v)
+	// the scheduled lane is empty, fall back to the highest priority
+	for i := 0; !received && i < len(q.lanes); i++ {
+		received = i != first && q.lanes[i].TryRecv(v)
+	}
+	if received {
+		// when the CAS fails another receiver has already advanced the schedule
+		atomic.CompareAndSwapUint64(&q.tick, tick, tick+1)
+	}
+	return received
+}
+
+// drained returns whether all lanes have been closed and all their values have been received
+func (q *MPMCqsPL[T]) drained() bool {
+	for _, lane := range q.lanes {
+		if !lane.drained() {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *MPMCqsPL[T]) canRecv() bool {
+	for _, lane := range q.lanes {
+		if lane.canRecv() {
+			return true
+		}
+	}
+	return atomic.LoadUint32(&q.closed) != 0
+}
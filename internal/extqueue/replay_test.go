@@ -0,0 +1,124 @@
+//go:build replay
+// +build replay
+
+package extqueue
+
+import (
+	"flag"
+	"fmt"
+	"testing"
+
+	"loov.dev/queue/internal/replay"
+)
+
+// Run with:
+//
+//	go test -tags replay -run Replay ./internal/extqueue
+//
+// A failure can be reproduced with -replay.seed or -replay.schedule.
+
+var (
+	replaySeed     = flag.Int64("replay.seed", 0, "seed of the first PCT run, 0 picks a new seed")
+	replayRuns     = flag.Int("replay.runs", 1000, "number of runs for every exploration")
+	replaySchedule = flag.String("replay.schedule", "", "replay only the schedule")
+)
+
+func explore(t *testing.T, program replay.Program) {
+	t.Helper()
+
+	opts := replay.Options{Runs: *replayRuns, Seed: *replaySeed}
+	if *replaySchedule != "" {
+		schedule, err := replay.ParseSchedule(*replaySchedule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := replay.Replay(program, schedule, opts); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	if failure := replay.PCT(program, opts); failure != nil {
+		t.Fatalf("PCT: %v\nrerun with -replay.schedule=%q", failure, failure.Schedule.String())
+	}
+	if failure := replay.Exhaustive(program, opts); failure != nil {
+		t.Fatalf("Exhaustive: %v\nrerun with -replay.schedule=%q", failure, failure.Schedule.String())
+	}
+}
+
+// checkReceived checks that every producer's values arrived exactly once and in order,
+// values are encoded as producer*100 + index.
+func checkReceived(received []int, producers, count int) error {
+	next := make([]int, producers)
+	for _, v := range received {
+		p, i := v/100, v%100
+		if p >= producers || i != next[p] {
+			return fmt.Errorf("received %v out of order: %v", v, received)
+		}
+		next[p]++
+	}
+	for p, n := range next {
+		if n != count {
+			return fmt.Errorf("received %d values from producer %d, expected %d: %v", n, p, count, received)
+		}
+	}
+	return nil
+}
+
+func TestReplayMPSCnsiDV(t *testing.T) {
+	const producers, count = 2, 2
+	explore(t, func(s *replay.Scheduler) func() error {
+		q := NewMPSCnsiDV[int]()
+		var received []int
+		for p := 0; p < producers; p++ {
+			p := p
+			s.Go(func() {
+				for i := 0; i < count; i++ {
+					q.SendNode(&Node[int]{Value: p*100 + i})
+				}
+			})
+		}
+		s.Go(func() {
+			for len(received) < producers*count {
+				node, ok := q.TryRecvNode()
+				if !ok {
+					replay.Gosched()
+					continue
+				}
+				received = append(received, node.Value)
+			}
+		})
+		return func() error { return checkReceived(received, producers, count) }
+	})
+}
+
+func TestReplayMPSCrMC(t *testing.T) {
+	const producers, count = 2, 3
+	for _, size := range []struct{ batch, size int }{{1, 2}, {2, 2}, {2, 4}} {
+		size := size
+		t.Run(fmt.Sprintf("b%d-s%d", size.batch, size.size), func(t *testing.T) {
+			explore(t, func(s *replay.Scheduler) func() error {
+				q := NewMPSCrMC[int](size.batch, size.size)
+				var received []int
+				for p := 0; p < producers; p++ {
+					p := p
+					s.Go(func() {
+						for i := 0; i < count; i++ {
+							q.Send(p*100 + i)
+						}
+					})
+				}
+				s.Go(func() {
+					for len(received) < producers*count {
+						var v int
+						if !q.Recv(&v) {
+							panic("receive failed")
+						}
+						received = append(received, v)
+					}
+				})
+				return func() error { return checkReceived(received, producers, count) }
+			})
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package extqueue
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// scqRing is a ring of indices based on "A Scalable, Portable, and Memory-Efficient
+// Lock-Free FIFO Queue" by Ruslan Nikolaev, https://arxiv.org/abs/1908.04511.
+//
+// Senders and receivers claim positions with fetch-and-add, which scales
+// considerably better under contention than CAS loops on a shared index.
+//
+// The ring holds up to half of its entries. Each entry contains the cycle of
+// the position, a safe bit and an index. An index of all ones means that
+// the entry is empty.
+type scqRing struct {
+	_         [8]uint64
+	head      uint64
+	_         [7]uint64
+	tail      uint64
+	_         [7]uint64
+	threshold int64
+	_         [7]uint64
+
+	order   uint // log2(len(entries))
+	entries []uint64
+}
+
+const (
+	// scqEmpty is returned when the ring is empty
+	scqEmpty = ^uint64(0)
+	// scqFinalized is set on tail when no more values can be added
+	scqFinalized = 1 << 63
+	// scqSpins is how many times a receiver retries an empty entry
+	// claimed by a lagging sender before invalidating it
+	scqSpins = 256
+)
+
+// init initializes the ring to hold up to half indices,
+// when full is true the ring contains indices [0, half).
+func (r *scqRing) init(half int, full bool) {
+	r.order = uint(bits.Len(uint(half)))
+	n := uint64(1) << r.order
+	r.entries = make([]uint64, n)
+	for i := range r.entries {
+		r.entries[i] = scqEmpty
+	}
+	r.head = 0
+	r.tail = 0
+	r.threshold = -1
+
+	if full {
+		for i := uint64(0); i < uint64(half); i++ {
+			// cycle 0, safe and index i
+			r.entries[r.remap(i)] = n + i
+		}
+		r.tail = uint64(half)
+		r.threshold = r.threshold3()
+	}
+}
+
+// threshold3 is the number of failed receives after which the ring is empty.
+func (r *scqRing) threshold3() int64 { return 3*int64(len(r.entries))/2 - 1 }
+
+// remap spreads adjacent positions over different cachelines.
+func (r *scqRing) remap(pos uint64) uint64 {
+	const shift = 3 // 8 entries per cacheline
+	n := uint64(len(r.entries))
+	if r.order < shift {
+		return pos & (n - 1)
+	}
+	return (pos&(n-1))>>(r.order-shift) | (pos<<shift)&(n-1)
+}
+
+// scqLess compares positions and cycles, taking wraparound into account.
+func scqLess(a, b uint64) bool { return int64(a-b) < 0 }
+
+// enqueue adds index to the ring,
+// returns false only when the ring has been finalized.
+func (r *scqRing) enqueue(index uint64) bool {
+	n := uint64(len(r.entries))
+	index ^= n - 1
+
+	for {
+		tail := atomic.AddUint64(&r.tail, 1) - 1
+		if tail&scqFinalized != 0 {
+			return false
+		}
+
+		tcycle := tail<<1 | (2*n - 1)
+		entry := &r.entries[r.remap(tail)]
+		e := atomic.LoadUint64(entry)
+		for {
+			ecycle := e | (2*n - 1)
+			if !scqLess(ecycle, tcycle) {
+				break
+			}
+			// the entry must be empty and either safe or unused by receivers
+			if e != ecycle && (e != ecycle^n || scqLess(tail, atomic.LoadUint64(&r.head))) {
+				break
+			}
+			if !atomic.CompareAndSwapUint64(entry, e, tcycle^index) {
+				e = atomic.LoadUint64(entry)
+				continue
+			}
+
+			if atomic.LoadInt64(&r.threshold) != r.threshold3() {
+				atomic.StoreInt64(&r.threshold, r.threshold3())
+			}
+			return true
+		}
+	}
+}
+
+// dequeue removes an index from the ring,
+// returns scqEmpty when the ring is empty.
+func (r *scqRing) dequeue() uint64 {
+	if atomic.LoadInt64(&r.threshold) < 0 {
+		return scqEmpty
+	}
+	// avoid invalidating entries when no sender has claimed them
+	if !scqLess(atomic.LoadUint64(&r.head), atomic.LoadUint64(&r.tail)&^scqFinalized) {
+		return scqEmpty
+	}
+
+	n := uint64(len(r.entries))
+	for {
+		head := atomic.AddUint64(&r.head, 1) - 1
+		hcycle := head<<1 | (2*n - 1)
+		entry := &r.entries[r.remap(head)]
+
+		e := atomic.LoadUint64(entry)
+		for attempt := 0; ; {
+			ecycle := e | (2*n - 1)
+			if ecycle == hcycle {
+				// mark the entry as empty
+				for !atomic.CompareAndSwapUint64(entry, e, e|(n-1)) {
+					e = atomic.LoadUint64(entry)
+				}
+				return e & (n - 1)
+			}
+
+			var next uint64
+			if e|n != ecycle {
+				// occupied by an older cycle, mark it unsafe
+				next = e &^ n
+				if e == next {
+					break
+				}
+			} else if attempt < scqSpins && scqLess(head, atomic.LoadUint64(&r.tail)&^scqFinalized) {
+				// a sender has claimed the entry, give it a chance to fill it
+				attempt++
+				e = atomic.LoadUint64(entry)
+				continue
+			} else {
+				// move the empty entry to the current cycle
+				next = hcycle ^ (^e & n)
+			}
+
+			if !scqLess(ecycle, hcycle) || atomic.CompareAndSwapUint64(entry, e, next) {
+				break
+			}
+			e = atomic.LoadUint64(entry)
+		}
+
+		tail := atomic.LoadUint64(&r.tail) &^ scqFinalized
+		if !scqLess(head+1, tail) {
+			r.catchup(tail, head+1)
+			atomic.AddInt64(&r.threshold, -1)
+			return scqEmpty
+		}
+		if atomic.AddInt64(&r.threshold, -1) < 0 {
+			return scqEmpty
+		}
+	}
+}
+
+// catchup moves tail to head, when receivers have overtaken senders.
+func (r *scqRing) catchup(tail, head uint64) {
+	for !atomic.CompareAndSwapUint64(&r.tail, tail, head) {
+		head = atomic.LoadUint64(&r.head)
+		tail = atomic.LoadUint64(&r.tail)
+		if tail&scqFinalized != 0 || !scqLess(tail, head) {
+			return
+		}
+	}
+}
+
+// finalize prevents adding any more indices to the ring.
+func (r *scqRing) finalize() {
+	for {
+		tail := atomic.LoadUint64(&r.tail)
+		if tail&scqFinalized != 0 || atomic.CompareAndSwapUint64(&r.tail, tail, tail|scqFinalized) {
+			return
+		}
+	}
+}
+
+// finalized returns whether finalize has been called.
+func (r *scqRing) finalized() bool {
+	return atomic.LoadUint64(&r.tail)&scqFinalized != 0
+}
+
+// mightDequeue returns whether dequeue might succeed.
+func (r *scqRing) mightDequeue() bool {
+	return atomic.LoadInt64(&r.threshold) >= 0 &&
+		scqLess(atomic.LoadUint64(&r.head), atomic.LoadUint64(&r.tail)&^scqFinalized)
+}
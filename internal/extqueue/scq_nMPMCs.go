@@ -0,0 +1,226 @@
+package extqueue
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// lscqSegmentSize is the number of values in a single MPMCnsLSCQ segment.
+const lscqSegmentSize = 1024
+
+// lscqClosed is linked after the last segment by Close.
+var lscqClosed = unsafe.Pointer(new(byte))
+
+// MPMCnsLSCQ is an unbounded MPMC queue based on "A Scalable, Portable, and Memory-Efficient
+// Lock-Free FIFO Queue" by Ruslan Nikolaev, https://arxiv.org/abs/1908.04511.
+//
+// The queue is a linked list of bounded SCQ segments, when a segment becomes full
+// it is finalized and the values are sent to a new segment.
+type MPMCnsLSCQ[T any] struct {
+	closed uint32
+	_      [7]uint64
+	head   unsafe.Pointer // *lscqSegment[T]
+	_      [7]uint64
+	tail   unsafe.Pointer // *lscqSegment[T]
+	_      [7]uint64
+}
+
+type lscqSegment[T any] struct {
+	next   unsafe.Pointer // *lscqSegment[T]
+	aq, fq scqRing
+	buffer []T
+}
+
+func newLSCQSegment[T any]() *lscqSegment[T] {
+	seg := &lscqSegment[T]{}
+	seg.buffer = make([]T, lscqSegmentSize)
+	seg.aq.init(lscqSegmentSize, false)
+	seg.fq.init(lscqSegmentSize, true)
+	return seg
+}
+
+// NewMPMCnsLSCQ creates a MPMCnsLSCQ queue
+func NewMPMCnsLSCQ[T any]() *MPMCnsLSCQ[T] {
+	q := &MPMCnsLSCQ[T]{}
+	seg := unsafe.Pointer(newLSCQSegment[T]())
+	q.head = seg
+	q.tail = seg
+	return q
+}
+
+// MultipleProducers makes this a MP queue
+func (q *MPMCnsLSCQ[T]) MultipleProducers() {}
+
+// MultipleConsumers makes this a MC queue
+func (q *MPMCnsLSCQ[T]) MultipleConsumers() {}
+
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPMCnsLSCQ[T]) Close() {
+	atomic.StoreUint32(&q.closed, 1)
+	for {
+		tail := atomic.LoadPointer(&q.tail)
+		seg := (*lscqSegment[T])(tail)
+		next := atomic.LoadPointer(&seg.next)
+		if next == lscqClosed {
+			return
+		}
+		if next != nil {
+			atomic.CompareAndSwapPointer(&q.tail, tail, next)
+			continue
+		}
+
+		// finalizing fails the senders racing with closing,
+		// which then find the marker instead of appending a segment
+		seg.aq.finalize()
+		if atomic.CompareAndSwapPointer(&seg.next, nil, lscqClosed) {
+			return
+		}
+	}
+}
+
+// Send sends a value to the queue, always succeeds unless the queue has been closed
+func (q *MPMCnsLSCQ[T]) Send(value T) bool {
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+
+	// last is allocated only once and reused when appending fails
+	var last *lscqSegment[T]
+	for {
+		tail := atomic.LoadPointer(&q.tail)
+		seg := (*lscqSegment[T])(tail)
+		next := atomic.LoadPointer(&seg.next)
+		if next == lscqClosed {
+			return false
+		}
+		if next != nil {
+			// tail is lagging behind, help to move it forward
+			atomic.CompareAndSwapPointer(&q.tail, tail, next)
+			continue
+		}
+
+		if seg.trySend(value) {
+			return true
+		}
+
+		// the segment is full, try to append a new one with the value,
+		// unless another sender or Close has already linked the next one
+		if atomic.LoadPointer(&seg.next) != nil {
+			continue
+		}
+		if last == nil {
+			last = newLSCQSegment[T]()
+			last.trySend(value)
+		}
+		if atomic.CompareAndSwapPointer(&seg.next, nil, unsafe.Pointer(last)) {
+			atomic.CompareAndSwapPointer(&q.tail, tail, unsafe.Pointer(last))
+			return true
+		}
+	}
+}
+
+// TrySend sends a value to the queue, always succeeds unless the queue has been closed
+func (q *MPMCnsLSCQ[T]) TrySend(value T) bool { return q.Send(value) }
+
+// SendContext sends a value to the queue, the queue is unbounded so it never waits for ctx
+func (q *MPMCnsLSCQ[T]) SendContext(ctx context.Context, value T) bool { return q.Send(value) }
+
+// SendTimeout sends a value to the queue, the queue is unbounded so it never waits for timeout
+func (q *MPMCnsLSCQ[T]) SendTimeout(value T, timeout time.Duration) bool { return q.Send(value) }
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPMCnsLSCQ[T]) Recv(value *T) bool { return q.recv(value, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPMCnsLSCQ[T]) RecvContext(ctx context.Context, value *T) bool {
+	return q.recv(value, ctx.Done())
+}
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPMCnsLSCQ[T]) RecvTimeout(value *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, value, timeout)
+}
+
+func (q *MPMCnsLSCQ[T]) recv(value *T, done <-chan struct{}) bool {
+	for wait := 0; ; spin(&wait) {
+		drained := q.drained()
+		if q.TryRecv(value) {
+			return true
+		}
+		if drained || canceled(done) {
+			return false
+		}
+	}
+}
+
+// TryRecv receives a value from the queue and returns when it is empty
+func (q *MPMCnsLSCQ[T]) TryRecv(value *T) bool {
+	for {
+		head := atomic.LoadPointer(&q.head)
+		seg := (*lscqSegment[T])(head)
+		if seg.tryRecv(value) {
+			return true
+		}
+
+		next := atomic.LoadPointer(&seg.next)
+		if next == nil {
+			return false
+		}
+
+		// the segment has been finalized, however a sender may still
+		// be finishing, so reset the threshold and check once more
+		atomic.StoreInt64(&seg.aq.threshold, seg.aq.threshold3())
+		if seg.tryRecv(value) {
+			return true
+		}
+
+		if next == lscqClosed {
+			return false
+		}
+		atomic.CompareAndSwapPointer(&q.head, head, next)
+	}
+}
+
+// drained returns whether the queue has been closed and the head is the last segment,
+// after which a failed receive means that the queue stays empty
+func (q *MPMCnsLSCQ[T]) drained() bool {
+	seg := (*lscqSegment[T])(atomic.LoadPointer(&q.head))
+	return atomic.LoadPointer(&seg.next) == lscqClosed
+}
+
+// trySend tries to send value to the segment,
+// returns false and finalizes the segment when it is full.
+func (seg *lscqSegment[T]) trySend(value T) bool {
+	index := seg.fq.dequeue()
+	if index == scqEmpty {
+		seg.aq.finalize()
+		return false
+	}
+
+	seg.buffer[index] = value
+	if !seg.aq.enqueue(index) {
+		// finalized concurrently, the index won't be used anymore
+		var zero T
+		seg.buffer[index] = zero
+		return false
+	}
+	return true
+}
+
+// tryRecv tries to receive a value from the segment.
+func (seg *lscqSegment[T]) tryRecv(value *T) bool {
+	index := seg.aq.dequeue()
+	if index == scqEmpty {
+		return false
+	}
+
+	var zero T
+	*value, seg.buffer[index] = seg.buffer[index], zero
+	seg.fq.enqueue(index)
+	return true
+}
@@ -0,0 +1,164 @@
+package extqueue
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// MPMCqsSCQ is a MPMC queue based on "A Scalable, Portable, and Memory-Efficient
+// Lock-Free FIFO Queue" by Ruslan Nikolaev, https://arxiv.org/abs/1908.04511.
+//
+// Values are stored in an array, free array indices are kept in one ring
+// and allocated array indices in another.
+type MPMCqsSCQ[T any] struct {
+	aq, fq scqRing
+	buffer []T
+	closed uint32
+	// waiting
+	sendw, recvw         Waiter
+	sendReady, recvReady func() bool
+}
+
+// NewMPMCqsSCQ creates a MPMCqsSCQ queue
+func NewMPMCqsSCQ[T any](size int, opts ...Option) *MPMCqsSCQ[T] {
+	if size <= 1 {
+		size = 2
+	}
+	size = int(nextPowerOfTwo(uint32(size)))
+
+	q := &MPMCqsSCQ[T]{}
+	q.buffer = make([]T, size)
+	q.aq.init(size, false)
+	q.fq.init(size, true)
+
+	cfg := newConfig(opts)
+	blockOnOverflow(cfg)
+	q.sendw = cfg.wait.NewWaiter()
+	q.recvw = cfg.wait.NewWaiter()
+	q.sendReady = q.canSend
+	q.recvReady = q.canRecv
+
+	return q
+}
+
+// Cap returns number of elements this queue can hold before blocking
+func (q *MPMCqsSCQ[T]) Cap() int { return len(q.buffer) }
+
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPMCqsSCQ[T]) Close() {
+	atomic.StoreUint32(&q.closed, 1)
+	q.aq.finalize()
+	q.sendw.Notify()
+	q.recvw.Notify()
+}
+
+// MultipleConsumers makes this a MC queue
+func (q *MPMCqsSCQ[T]) MultipleConsumers() {}
+
+// MultipleProducers makes this a MP queue
+func (q *MPMCqsSCQ[T]) MultipleProducers() {}
+
+// Send sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed
+func (q *MPMCqsSCQ[T]) Send(v T) bool { return q.send(v, nil) }
+
+// SendContext sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the context is done
+func (q *MPMCqsSCQ[T]) SendContext(ctx context.Context, v T) bool { return q.send(v, ctx.Done()) }
+
+// SendTimeout sends a value to the queue and blocks when it is full,
+// returns false when the queue has been closed or the timeout elapsed
+func (q *MPMCqsSCQ[T]) SendTimeout(v T, timeout time.Duration) bool {
+	return sendTimeout(q.SendContext, v, timeout)
+}
+
+func (q *MPMCqsSCQ[T]) send(v T, done <-chan struct{}) bool {
+	for wait := 0; ; wait++ {
+		if q.TrySend(v) {
+			return true
+		}
+		if atomic.LoadUint32(&q.closed) != 0 || canceled(done) {
+			return false
+		}
+		q.sendw.Wait(wait, q.sendReady, done)
+	}
+}
+
+// TrySend tries to send a value to the queue and returns immediately when it is full or closed
+func (q *MPMCqsSCQ[T]) TrySend(v T) bool {
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+
+	index := q.fq.dequeue()
+	if index == scqEmpty {
+		return false
+	}
+	q.buffer[index] = v
+	if !q.aq.enqueue(index) {
+		// closed concurrently
+		var zero T
+		q.buffer[index] = zero
+		q.fq.enqueue(index)
+		return false
+	}
+	q.recvw.Notify()
+	return true
+}
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPMCqsSCQ[T]) Recv(v *T) bool { return q.recv(v, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPMCqsSCQ[T]) RecvContext(ctx context.Context, v *T) bool { return q.recv(v, ctx.Done()) }
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPMCqsSCQ[T]) RecvTimeout(v *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, v, timeout)
+}
+
+func (q *MPMCqsSCQ[T]) recv(v *T, done <-chan struct{}) bool {
+	for wait := 0; ; wait++ {
+		if q.TryRecv(v) {
+			return true
+		}
+		if q.aq.finalized() {
+			// the queue has been closed, however a sender may still
+			// be finishing, so reset the threshold and check once more
+			atomic.StoreInt64(&q.aq.threshold, q.aq.threshold3())
+			return q.TryRecv(v)
+		}
+		if canceled(done) {
+			return false
+		}
+		q.recvw.Wait(wait, q.recvReady, done)
+	}
+}
+
+// TryRecv receives a value from the queue and returns when it is empty
+func (q *MPMCqsSCQ[T]) TryRecv(v *T) bool {
+	index := q.aq.dequeue()
+	if index == scqEmpty {
+		return false
+	}
+
+	var zero T
+	*v, q.buffer[index] = q.buffer[index], zero
+	q.fq.enqueue(index)
+	q.sendw.Notify()
+	return true
+}
+
+// canSend returns whether sending might succeed without waiting
+func (q *MPMCqsSCQ[T]) canSend() bool {
+	return q.fq.mightDequeue() || atomic.LoadUint32(&q.closed) != 0
+}
+
+// canRecv returns whether receiving might succeed without waiting
+func (q *MPMCqsSCQ[T]) canRecv() bool {
+	return q.aq.mightDequeue() || atomic.LoadUint32(&q.closed) != 0
+}
@@ -0,0 +1,174 @@
+package extqueue
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// sgLap is the number of positions per segment in MPSCnsSG,
+// the last position marks that the next segment is being linked.
+const sgLap = sgSegmentSize + 1
+
+// MPSCnsSG is an unbounded MPSC queue, which stores values in linked fixed-size segments.
+//
+// Producers claim positions with a CAS, the producer claiming the last position
+// in a segment links the next one. Drained segments are reused in the same manner
+// as SPSCnsDV reuses nodes.
+type MPSCnsSG[T any] struct {
+	closed  uint32
+	sending int64
+	_       [6]uint64
+	// producers
+	sendx    uint64
+	sendseg  unsafe.Pointer // *sgSlotSegment[T]
+	_        [6]uint64
+	first    unsafe.Pointer // *sgSlotSegment[T]
+	tailCopy unsafe.Pointer // *sgSlotSegment[T]
+	_        [6]uint64
+	// consumer
+	recvseg unsafe.Pointer // *sgSlotSegment[T]
+	recvx   int
+	_       [6]uint64
+}
+
+type sgSlotSegment[T any] struct {
+	next  unsafe.Pointer // *sgSlotSegment[T]
+	slots [sgSegmentSize]sgSlot[T]
+}
+
+type sgSlot[T any] struct {
+	ready uint32
+	value T
+}
+
+// NewMPSCnsSG creates a MPSCnsSG queue
+func NewMPSCnsSG[T any]() *MPSCnsSG[T] {
+	q := &MPSCnsSG[T]{}
+	seg := unsafe.Pointer(&sgSlotSegment[T]{})
+	q.sendseg = seg
+	q.first = seg
+	q.tailCopy = seg
+	q.recvseg = seg
+	return q
+}
+
+// MultipleProducers makes this a MP queue
+func (q *MPSCnsSG[T]) MultipleProducers() {}
+
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *MPSCnsSG[T]) Close() { atomic.StoreUint32(&q.closed, 1) }
+
+// Send sends a value to the queue, always succeeds unless the queue has been closed
+func (q *MPSCnsSG[T]) Send(value T) bool {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+
+	for try := 0; ; spin(&try) {
+		pos := atomic.LoadUint64(&q.sendx)
+		seg := (*sgSlotSegment[T])(atomic.LoadPointer(&q.sendseg))
+
+		offset := pos % sgLap
+		if offset == sgSegmentSize {
+			// another producer is linking the next segment
+			continue
+		}
+
+		if !atomic.CompareAndSwapUint64(&q.sendx, pos, pos+1) {
+			continue
+		}
+
+		if offset == sgSegmentSize-1 {
+			next := q.alloc()
+			atomic.StorePointer(&seg.next, unsafe.Pointer(next))
+			atomic.StorePointer(&q.sendseg, unsafe.Pointer(next))
+			atomic.StoreUint64(&q.sendx, pos+2)
+		}
+
+		slot := &seg.slots[offset]
+		slot.value = value
+		atomic.StoreUint32(&slot.ready, 1)
+		return true
+	}
+}
+
+// TrySend sends a value to the queue, always succeeds unless the queue has been closed
+func (q *MPSCnsSG[T]) TrySend(value T) bool { return q.Send(value) }
+
+// SendContext sends a value to the queue, the queue is unbounded so it never waits for ctx
+func (q *MPSCnsSG[T]) SendContext(ctx context.Context, value T) bool { return q.Send(value) }
+
+// SendTimeout sends a value to the queue, the queue is unbounded so it never waits for timeout
+func (q *MPSCnsSG[T]) SendTimeout(value T, timeout time.Duration) bool { return q.Send(value) }
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *MPSCnsSG[T]) Recv(value *T) bool { return q.recv(value, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *MPSCnsSG[T]) RecvContext(ctx context.Context, value *T) bool {
+	return q.recv(value, ctx.Done())
+}
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *MPSCnsSG[T]) RecvTimeout(value *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, value, timeout)
+}
+
+func (q *MPSCnsSG[T]) recv(value *T, done <-chan struct{}) bool {
+	for wait := 0; ; spin(&wait) {
+		drained := atomic.LoadUint32(&q.closed) != 0 && atomic.LoadInt64(&q.sending) == 0
+		if q.TryRecv(value) {
+			return true
+		}
+		if drained || canceled(done) {
+			return false
+		}
+	}
+}
+
+// TryRecv receives a value from the queue and returns when it is empty
+func (q *MPSCnsSG[T]) TryRecv(value *T) bool {
+	seg := (*sgSlotSegment[T])(q.recvseg)
+	slot := &seg.slots[q.recvx]
+	if atomic.LoadUint32(&slot.ready) == 0 {
+		return false
+	}
+
+	var zero T
+	*value, slot.value = slot.value, zero
+	atomic.StoreUint32(&slot.ready, 0)
+
+	q.recvx++
+	if q.recvx == sgSegmentSize {
+		// the last slot is filled only after the next segment has been linked
+		atomic.StorePointer(&q.recvseg, atomic.LoadPointer(&seg.next))
+		q.recvx = 0
+	}
+	return true
+}
+
+// alloc is only called by the producer linking the next segment.
+func (q *MPSCnsSG[T]) alloc() *sgSlotSegment[T] {
+	// first tries to reuse a segment that the consumer has drained,
+	// if attempt fails, allocates a new segment
+
+	if q.first == q.tailCopy {
+		q.tailCopy = atomic.LoadPointer(&q.recvseg)
+		if q.first == q.tailCopy {
+			return &sgSlotSegment[T]{}
+		}
+	}
+
+	seg := (*sgSlotSegment[T])(q.first)
+	q.first = seg.next
+	seg.next = nil
+	return seg
+}
@@ -0,0 +1,157 @@
+package extqueue
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// sgSegmentSize is the number of values in a single segment.
+//
+// It is one less than a power of two, so that MPSCnsSG can use
+// the remaining offset to mark that the next segment is being linked.
+const sgSegmentSize = 255
+
+// SPSCnsSG is an unbounded SPSC queue, which stores values in linked fixed-size segments.
+//
+// Drained segments are reused by the producer in the same manner as SPSCnsDV reuses nodes,
+// http://www.1024cores.net/home/lock-free-algorithms/queues/unbounded-spsc-queue
+type SPSCnsSG[T any] struct {
+	closed  uint32
+	sending int64
+	_       [6]uint64
+	// producer
+	sendseg  *sgSegment[T]
+	sendx    int
+	first    unsafe.Pointer // *sgSegment[T]
+	tailCopy unsafe.Pointer // *sgSegment[T]
+	_        [4]uint64
+	sent     uint64
+	_        [7]uint64
+	// consumer
+	recvseg  unsafe.Pointer // *sgSegment[T]
+	recvx    int
+	received uint64
+	sentCopy uint64
+	_        [4]uint64
+}
+
+type sgSegment[T any] struct {
+	next   unsafe.Pointer // *sgSegment[T]
+	values [sgSegmentSize]T
+}
+
+// NewSPSCnsSG creates a new SPSCnsSG queue
+func NewSPSCnsSG[T any]() *SPSCnsSG[T] {
+	q := &SPSCnsSG[T]{}
+	seg := &sgSegment[T]{}
+	q.sendseg = seg
+	q.first = unsafe.Pointer(seg)
+	q.tailCopy = unsafe.Pointer(seg)
+	q.recvseg = unsafe.Pointer(seg)
+	return q
+}
+
+// Close closes the queue for sending, values that are already in the queue can still be received
+func (q *SPSCnsSG[T]) Close() { atomic.StoreUint32(&q.closed, 1) }
+
+// Send sends a value to the queue, always succeeds unless the queue has been closed
+func (q *SPSCnsSG[T]) Send(value T) bool {
+	atomic.AddInt64(&q.sending, 1)
+	defer atomic.AddInt64(&q.sending, -1)
+
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return false
+	}
+
+	if q.sendx == sgSegmentSize {
+		seg := q.alloc()
+		atomic.StorePointer(&q.sendseg.next, unsafe.Pointer(seg))
+		q.sendseg = seg
+		q.sendx = 0
+	}
+
+	q.sendseg.values[q.sendx] = value
+	q.sendx++
+	atomic.StoreUint64(&q.sent, atomic.LoadUint64(&q.sent)+1)
+	return true
+}
+
+// TrySend tries to send a value to the queue, always succeeds unless the queue has been closed
+func (q *SPSCnsSG[T]) TrySend(value T) bool { return q.Send(value) }
+
+// SendContext sends a value to the queue, the queue is unbounded so it never waits for ctx
+func (q *SPSCnsSG[T]) SendContext(ctx context.Context, value T) bool { return q.Send(value) }
+
+// SendTimeout sends a value to the queue, the queue is unbounded so it never waits for timeout
+func (q *SPSCnsSG[T]) SendTimeout(value T, timeout time.Duration) bool { return q.Send(value) }
+
+// Recv receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained
+func (q *SPSCnsSG[T]) Recv(value *T) bool { return q.recv(value, nil) }
+
+// RecvContext receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the context is done
+func (q *SPSCnsSG[T]) RecvContext(ctx context.Context, value *T) bool {
+	return q.recv(value, ctx.Done())
+}
+
+// RecvTimeout receives a value from the queue and blocks when it is empty,
+// returns false when the queue has been closed and drained or the timeout elapsed
+func (q *SPSCnsSG[T]) RecvTimeout(value *T, timeout time.Duration) bool {
+	return recvTimeout(q.RecvContext, value, timeout)
+}
+
+func (q *SPSCnsSG[T]) recv(value *T, done <-chan struct{}) bool {
+	for wait := 0; ; spin(&wait) {
+		drained := atomic.LoadUint32(&q.closed) != 0 && atomic.LoadInt64(&q.sending) == 0
+		if q.TryRecv(value) {
+			return true
+		}
+		if drained || canceled(done) {
+			return false
+		}
+	}
+}
+
+// TryRecv receives a value from the queue and returns when it is empty
+func (q *SPSCnsSG[T]) TryRecv(value *T) bool {
+	if q.received == q.sentCopy {
+		q.sentCopy = atomic.LoadUint64(&q.sent)
+		if q.received == q.sentCopy {
+			return false
+		}
+	}
+
+	seg := (*sgSegment[T])(q.recvseg)
+	if q.recvx == sgSegmentSize {
+		// the producer links the next segment before sending to it
+		seg = (*sgSegment[T])(atomic.LoadPointer(&seg.next))
+		atomic.StorePointer(&q.recvseg, unsafe.Pointer(seg))
+		q.recvx = 0
+	}
+
+	var zero T
+	*value, seg.values[q.recvx] = seg.values[q.recvx], zero
+	q.recvx++
+	q.received++
+	return true
+}
+
+func (q *SPSCnsSG[T]) alloc() *sgSegment[T] {
+	// first tries to reuse a segment that the consumer has drained,
+	// if attempt fails, allocates a new segment
+
+	if q.first == q.tailCopy {
+		q.tailCopy = atomic.LoadPointer(&q.recvseg)
+		if q.first == q.tailCopy {
+			return &sgSegment[T]{}
+		}
+	}
+
+	seg := (*sgSegment[T])(q.first)
+	q.first = seg.next
+	seg.next = nil
+	return seg
+}
@@ -0,0 +1,35 @@
+//go:build !replay
+// +build !replay
+
+package extqueue
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// The algorithms checked by the replay scheduler use these instead of
+// sync/atomic and sync, building with -tags replay replaces them with
+// scheduling points, see sync_replay.go.
+
+type mutex = sync.Mutex
+type cond = sync.Cond
+
+func loadUint32(addr *uint32) uint32            { return atomic.LoadUint32(addr) }
+func storeUint32(addr *uint32, v uint32)        { atomic.StoreUint32(addr, v) }
+func loadInt64(addr *int64) int64               { return atomic.LoadInt64(addr) }
+func storeInt64(addr *int64, v int64)           { atomic.StoreInt64(addr, v) }
+func addInt64(addr *int64, delta int64) int64   { return atomic.AddInt64(addr, delta) }
+func casInt64(addr *int64, old, new int64) bool { return atomic.CompareAndSwapInt64(addr, old, new) }
+
+func loadPointer(addr *unsafe.Pointer) unsafe.Pointer { return atomic.LoadPointer(addr) }
+func storePointer(addr *unsafe.Pointer, v unsafe.Pointer) {
+	atomic.StorePointer(addr, v)
+}
+func swapPointer(addr *unsafe.Pointer, new unsafe.Pointer) unsafe.Pointer {
+	return atomic.SwapPointer(addr, new)
+}
+
+// waitOn waits on c until it's signaled or done is closed.
+func waitOn(c *cond, done <-chan struct{}) { waitCond(c, done) }
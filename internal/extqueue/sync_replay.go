@@ -0,0 +1,37 @@
+//go:build replay
+// +build replay
+
+package extqueue
+
+import (
+	"unsafe"
+
+	"loov.dev/queue/internal/replay"
+)
+
+type mutex = replay.Mutex
+type cond = replay.Cond
+
+func loadUint32(addr *uint32) uint32            { return replay.LoadUint32(addr) }
+func storeUint32(addr *uint32, v uint32)        { replay.StoreUint32(addr, v) }
+func loadInt64(addr *int64) int64               { return replay.LoadInt64(addr) }
+func storeInt64(addr *int64, v int64)           { replay.StoreInt64(addr, v) }
+func addInt64(addr *int64, delta int64) int64   { return replay.AddInt64(addr, delta) }
+func casInt64(addr *int64, old, new int64) bool { return replay.CompareAndSwapInt64(addr, old, new) }
+
+func loadPointer(addr *unsafe.Pointer) unsafe.Pointer { return replay.LoadPointer(addr) }
+func storePointer(addr *unsafe.Pointer, v unsafe.Pointer) {
+	replay.StorePointer(addr, v)
+}
+func swapPointer(addr *unsafe.Pointer, new unsafe.Pointer) unsafe.Pointer {
+	return replay.SwapPointer(addr, new)
+}
+
+// waitOn waits on c until it's signaled, the replay scheduler
+// cannot observe channels, hence done must be nil.
+func waitOn(c *cond, done <-chan struct{}) {
+	if done != nil {
+		panic("extqueue: waiting with a context is not supported with replay")
+	}
+	c.Wait()
+}
@@ -0,0 +1,182 @@
+package extqueue
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WaitStrategy decides how a blocking operation waits for the queue
+// to become non-full or non-empty.
+//
+// Spinning strategies have the lowest latency, but burn CPU while waiting,
+// parking strategies are better citizens, but waking up takes longer.
+type WaitStrategy interface {
+	// NewWaiter creates a waiter for a single queue condition.
+	NewWaiter() Waiter
+}
+
+// Waiter waits for a single queue condition, e.g. "not full" or "not empty".
+type Waiter interface {
+	// Wait is called repeatedly while the condition is false,
+	// iteration counts the calls during a single blocking operation.
+	//
+	// Wait may return before the condition is true, however it must
+	// return when ready returns true or done is closed.
+	Wait(iteration int, ready func() bool, done <-chan struct{})
+	// Notify is called after the condition might have become true.
+	Notify()
+}
+
+// WaitFunc is a spinning wait strategy that calls the func on every iteration.
+type WaitFunc func(iteration int)
+
+// NewWaiter implements WaitStrategy.
+func (fn WaitFunc) NewWaiter() Waiter { return fn }
+
+// Wait implements Waiter.
+func (fn WaitFunc) Wait(iteration int, ready func() bool, done <-chan struct{}) { fn(iteration) }
+
+// Notify implements Waiter.
+func (fn WaitFunc) Notify() {}
+
+var (
+	// BusySpin spins without ever yielding the processor.
+	BusySpin WaitStrategy = WaitFunc(func(int) {})
+
+	// Spin spins and yields the processor every 256 iterations.
+	Spin WaitStrategy = WaitFunc(func(iteration int) {
+		if iteration%256 == 255 {
+			runtime.Gosched()
+		}
+	})
+
+	// Yield yields the processor on every iteration.
+	Yield WaitStrategy = WaitFunc(func(int) { runtime.Gosched() })
+
+	// Backoff spins, then yields and finally sleeps with exponentially
+	// increasing durations up to 128µs.
+	Backoff WaitStrategy = WaitFunc(func(iteration int) {
+		switch {
+		case iteration < 4:
+		case iteration < 16:
+			runtime.Gosched()
+		default:
+			shift := iteration - 16
+			if shift > 7 {
+				shift = 7
+			}
+			time.Sleep(time.Microsecond << uint(shift))
+		}
+	})
+
+	// Park parks the goroutine until the condition is notified.
+	Park WaitStrategy = Hybrid(0)
+
+	// spinThenPark is used by the waiting variants of spinning queues.
+	spinThenPark WaitStrategy = Hybrid(128)
+)
+
+// Hybrid spins the specified number of iterations and then parks the goroutine
+// until the condition is notified.
+func Hybrid(spins int) WaitStrategy { return hybrid(spins) }
+
+type hybrid int
+
+func (spins hybrid) NewWaiter() Waiter { return &parker{spins: int(spins)} }
+
+// parker parks goroutines on a channel, which is closed by Notify.
+type parker struct {
+	spins    int
+	sleepers int32
+
+	mu   sync.Mutex
+	wake chan struct{}
+}
+
+// Wait implements Waiter.
+func (p *parker) Wait(iteration int, ready func() bool, done <-chan struct{}) {
+	if iteration < p.spins {
+		if iteration%256 == 255 {
+			runtime.Gosched()
+		}
+		return
+	}
+
+	p.mu.Lock()
+	if p.wake == nil {
+		p.wake = make(chan struct{})
+	}
+	wake := p.wake
+	atomic.AddInt32(&p.sleepers, 1)
+	p.mu.Unlock()
+
+	// the condition must be checked after announcing sleeping,
+	// otherwise Notify may miss the sleeper
+	if !ready() {
+		select {
+		case <-wake:
+		case <-done:
+		}
+	}
+
+	atomic.AddInt32(&p.sleepers, -1)
+}
+
+// Notify implements Waiter.
+func (p *parker) Notify() {
+	if atomic.LoadInt32(&p.sleepers) == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	if p.wake != nil {
+		close(p.wake)
+		p.wake = nil
+	}
+	p.mu.Unlock()
+}
+
+// Option configures a queue.
+type Option func(*config)
+
+type config struct {
+	wait WaitStrategy
+
+	overflow        Overflow
+	overflowHandler any
+
+	laneWeights []int
+
+	clock Clock
+}
+
+// WithWaitStrategy sets how a bounded queue waits when it is full or empty.
+//
+// MPMCqGo and MPMCqpGo always sleep until the opposite side wakes them up,
+// hence they panic when a wait strategy is set.
+func WithWaitStrategy(strategy WaitStrategy) Option {
+	return func(c *config) { c.wait = strategy }
+}
+
+// newConfig applies opts to the default configuration.
+func newConfig(opts []Option) config {
+	c := config{wait: Spin, clock: systemClock{}}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// sleepOnWait panics when opts set a wait strategy,
+// it's used by queues which always sleep on a sync.Cond.
+func sleepOnWait(opts []Option) {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.wait != nil {
+		panic("extqueue: wait strategies are not supported by this queue")
+	}
+}
@@ -0,0 +1,82 @@
+package extqueue
+
+import (
+	"flag"
+	"math"
+	"testing"
+
+	"loov.dev/queue/internal/testsuite"
+)
+
+var wrapLaps = flag.Int("wrap-laps", 1, "number of laps around the buffer before sequence counters wrap in TestWrapAround")
+
+// seqStart32 returns a starting sequence number for queues with uint32 sequences,
+// the sequence number increases by 2 for every lap
+func seqStart32() uint32 { return math.MaxUint32 - 2*uint32(*wrapLaps) + 1 }
+
+// seqStart64 returns a starting position for queues with int64 sequences
+func seqStart64(cap int) int64 { return math.MaxInt64 - int64(*wrapLaps)*int64(cap) + 1 }
+
+// TestWrapAround runs the conformance suite with sequence numbered queues
+// starting near the point where their sequence counters wrap around.
+func TestWrapAround(t *testing.T) {
+	descs := testsuite.Descs[int64]{
+		{
+			Name:  "MPMCqGo",
+			Param: testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue {
+				q := NewMPMCqGo[int64](s)
+				q.startAt(seqStart32())
+				return q
+			}},
+		{
+			Name:  "MPMCqpGo",
+			Param: testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue {
+				q := NewMPMCqpGo[int64](s)
+				q.startAt(seqStart32())
+				return q
+			}},
+	}
+
+	type startAt interface {
+		testsuite.Queue
+		Cap() int
+		startAt(pos int64)
+	}
+	dv := []struct {
+		Name   string
+		Create func(size int) startAt
+	}{
+		{"MPMCqsDV", func(s int) startAt { return NewMPMCqsDV[int64](s) }},
+		{"MPMCqspDV", func(s int) startAt { return NewMPMCqspDV[int64](s) }},
+		{"SPMCqsDV", func(s int) startAt { return NewSPMCqsDV[int64](s) }},
+		{"SPMCqspDV", func(s int) startAt { return NewSPMCqspDV[int64](s) }},
+		{"MPSCqsDV", func(s int) startAt { return NewMPSCqsDV[int64](s) }},
+		{"MPSCqspDV", func(s int) startAt { return NewMPSCqspDV[int64](s) }},
+		{"SPSCqsDV", func(s int) startAt { return NewSPSCqsDV[int64](s) }},
+		{"SPSCqspDV", func(s int) startAt { return NewSPSCqspDV[int64](s) }},
+
+		{"MPMCqDV", func(s int) startAt { return NewMPMCqDV[int64](s) }},
+		{"MPMCqpDV", func(s int) startAt { return NewMPMCqpDV[int64](s) }},
+		{"SPMCqDV", func(s int) startAt { return NewSPMCqDV[int64](s) }},
+		{"SPMCqpDV", func(s int) startAt { return NewSPMCqpDV[int64](s) }},
+		{"MPSCqDV", func(s int) startAt { return NewMPSCqDV[int64](s) }},
+		{"MPSCqpDV", func(s int) startAt { return NewMPSCqpDV[int64](s) }},
+		{"SPSCqDV", func(s int) startAt { return NewSPSCqDV[int64](s) }},
+		{"SPSCqpDV", func(s int) startAt { return NewSPSCqpDV[int64](s) }},
+	}
+	for _, impl := range dv {
+		create := impl.Create
+		descs.Append(&testsuite.Desc[int64]{
+			Name:  impl.Name,
+			Param: testsuite.ParamSize,
+			Create: func(bs, s int) testsuite.Queue {
+				q := create(s)
+				q.startAt(seqStart64(q.Cap()))
+				return q
+			}})
+	}
+
+	descs.TestDefault(t, testsuite.Int64)
+}
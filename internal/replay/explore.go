@@ -0,0 +1,224 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Switch makes Thread run at Step instead of the default choice.
+type Switch struct {
+	Step   int
+	Thread int
+}
+
+// Schedule lists the deviations from the default choice, which runs the current
+// thread until it finishes, blocks or calls Gosched and then switches to the
+// next thread in round-robin order.
+type Schedule []Switch
+
+// String formats the schedule as "step:thread" pairs, e.g. "3:1 17:0".
+func (schedule Schedule) String() string {
+	xs := make([]string, len(schedule))
+	for i, sw := range schedule {
+		xs[i] = strconv.Itoa(sw.Step) + ":" + strconv.Itoa(sw.Thread)
+	}
+	return strings.Join(xs, " ")
+}
+
+// ParseSchedule parses a schedule formatted by Schedule.String.
+func ParseSchedule(s string) (Schedule, error) {
+	var schedule Schedule
+	for _, field := range strings.Fields(s) {
+		step, thread, ok := strings.Cut(field, ":")
+		if !ok {
+			return nil, fmt.Errorf("replay: invalid switch %q", field)
+		}
+		var sw Switch
+		var err error
+		if sw.Step, err = strconv.Atoi(step); err != nil {
+			return nil, fmt.Errorf("replay: invalid step in %q: %w", field, err)
+		}
+		if sw.Thread, err = strconv.Atoi(thread); err != nil {
+			return nil, fmt.Errorf("replay: invalid thread in %q: %w", field, err)
+		}
+		schedule = append(schedule, sw)
+	}
+	return schedule, nil
+}
+
+// scheduleOf converts decisions to a schedule.
+func scheduleOf(trace []choice) Schedule {
+	var schedule Schedule
+	for step, c := range trace {
+		if c.chosen != defaultChoice(c.current, c.runnable, c.gosched) {
+			schedule = append(schedule, Switch{Step: step, Thread: c.chosen})
+		}
+	}
+	return schedule
+}
+
+// Options configures exploring the schedules.
+type Options struct {
+	// Runs is the maximum number of runs, 0 means 1000.
+	Runs int
+	// MaxSteps is the maximum number of scheduling points in a single run, 0 means 100000.
+	MaxSteps int
+	// Seed is the seed of the first PCT run, 0 picks a new seed.
+	// Every run uses the next seed, so a failing seed reproduces the failure with Runs = 1.
+	Seed int64
+	// Depth is the depth of bugs PCT looks for, 0 means 3.
+	Depth int
+	// Preemptions bounds the number of preemptions in exhaustive search, 0 means 2.
+	Preemptions int
+}
+
+func (opts Options) withDefaults() Options {
+	if opts.Runs <= 0 {
+		opts.Runs = 1000
+	}
+	if opts.MaxSteps <= 0 {
+		opts.MaxSteps = 100000
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	if opts.Depth <= 0 {
+		opts.Depth = 3
+	}
+	if opts.Preemptions <= 0 {
+		opts.Preemptions = 2
+	}
+	return opts
+}
+
+// Failure describes a failing run.
+type Failure struct {
+	// Err is the error returned by the check, a panic, a deadlock or ErrStepLimit.
+	Err error
+	// Seed is the seed of the failing PCT run, 0 for exhaustive search.
+	Seed int64
+	// Run is the index of the failing run, -1 when PCT failed with the default schedule.
+	Run int
+	// Schedule is a minimal schedule, which reproduces the failure with Replay.
+	Schedule Schedule
+}
+
+func (f *Failure) Error() string {
+	return fmt.Sprintf("%v\nrun %d, seed %d, schedule %q", f.Err, f.Run, f.Seed, f.Schedule.String())
+}
+
+// PCT runs the program with random PCT schedules until a run fails
+// and returns the failure, nil when all the runs succeeded.
+func PCT(p Program, opts Options) *Failure {
+	opts = opts.withDefaults()
+
+	// the number of steps is needed to spread the priority changes over the run,
+	// it's estimated by the default schedule, so that every seed is independent of
+	// the previous runs
+	trace, err := run(p, func(int) strategy { return &replayer{} }, opts.MaxSteps)
+	if err != nil {
+		return &Failure{Err: err, Run: -1, Schedule: Minimize(p, scheduleOf(trace), opts)}
+	}
+	steps := len(trace)
+
+	for i := 0; i < opts.Runs; i++ {
+		seed := opts.Seed + int64(i)
+		trace, err := run(p, func(threads int) strategy {
+			return newPCT(rand.New(rand.NewSource(seed)), threads, opts.Depth, steps)
+		}, opts.MaxSteps)
+		if err != nil {
+			return &Failure{Err: err, Seed: seed, Run: i, Schedule: Minimize(p, scheduleOf(trace), opts)}
+		}
+	}
+	return nil
+}
+
+// Exhaustive runs the program with all the schedules, which have at most
+// opts.Preemptions deviations from the default choice, until a run fails
+// and returns the failure, nil when all the runs succeeded.
+//
+// The search stops after opts.Runs runs, even when it hasn't finished.
+func Exhaustive(p Program, opts Options) *Failure {
+	opts = opts.withDefaults()
+
+	var prefix Schedule
+	for i := 0; i < opts.Runs; i++ {
+		trace, err := run(p, func(int) strategy {
+			return &replayer{schedule: prefix}
+		}, opts.MaxSteps)
+		if err != nil {
+			return &Failure{Err: err, Run: i, Schedule: Minimize(p, scheduleOf(trace), opts)}
+		}
+
+		var ok bool
+		prefix, ok = nextPrefix(trace, opts.Preemptions)
+		if !ok {
+			return nil
+		}
+	}
+	return nil
+}
+
+// nextPrefix finds the next schedule in depth-first order, where at every step
+// the default choice is tried first and then the other runnable threads by id.
+func nextPrefix(trace []choice, preemptions int) (Schedule, bool) {
+	schedule := scheduleOf(trace)
+	for step := len(trace) - 1; step >= 0; step-- {
+		for len(schedule) > 0 && schedule[len(schedule)-1].Step >= step {
+			schedule = schedule[:len(schedule)-1]
+		}
+		if len(schedule)+1 > preemptions {
+			continue
+		}
+
+		c := trace[step]
+		def := defaultChoice(c.current, c.runnable, c.gosched)
+		// the default choice has been tried before the others
+		next := -1
+		for _, id := range c.runnable {
+			if id != def && (c.chosen == def || id > c.chosen) {
+				next = id
+				break
+			}
+		}
+		if next >= 0 {
+			return append(schedule, Switch{Step: step, Thread: next}), true
+		}
+	}
+	return nil, false
+}
+
+// Replay runs the program with the schedule and returns the failure.
+func Replay(p Program, schedule Schedule, opts Options) error {
+	opts = opts.withDefaults()
+	_, err := run(p, func(int) strategy {
+		return &replayer{schedule: schedule}
+	}, opts.MaxSteps)
+	return err
+}
+
+// Minimize removes switches from a failing schedule while it still fails.
+//
+// Hitting the step limit doesn't count as the same failure,
+// unless the schedule failed with it in the first place.
+func Minimize(p Program, schedule Schedule, opts Options) Schedule {
+	opts = opts.withDefaults()
+	stepLimit := errors.Is(Replay(p, schedule, opts), ErrStepLimit)
+	for i := 0; i < len(schedule); {
+		candidate := append(append(Schedule{}, schedule[:i]...), schedule[i+1:]...)
+		trace, err := run(p, func(int) strategy {
+			return &replayer{schedule: candidate}
+		}, opts.MaxSteps)
+		failed := err != nil && errors.Is(err, ErrStepLimit) == stepLimit
+		if reduced := scheduleOf(trace); failed && len(reduced) < len(schedule) {
+			schedule, i = reduced, 0
+			continue
+		}
+		i++
+	}
+	return schedule
+}
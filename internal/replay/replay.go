@@ -0,0 +1,238 @@
+// Package replay runs concurrent algorithms under a controlled scheduler,
+// which explores the interleavings of threads deterministically.
+//
+// Only one thread runs at a time and threads switch only at scheduling points:
+// the atomic operations, Mutex and Cond in this package, Yield and Gosched.
+// The algorithm under test must use them instead of sync/atomic and sync,
+// and the program must be deterministic apart from the scheduling,
+// so that a schedule or a seed reproduces the same run.
+//
+// PCT explores random schedules with a probabilistic guarantee for finding bugs of
+// a given depth, Exhaustive enumerates the schedules with a bounded number of preemptions.
+// Both report the failure with a minimal schedule, which can be rerun with Replay.
+//
+// The scheduler assumes sequential consistency, hence it doesn't find
+// bugs that depend on a weaker memory model.
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// Program sets up a concurrent program by starting threads with s.Go.
+// check is called after all threads have finished, nil check means no check.
+type Program func(s *Scheduler) (check func() error)
+
+// Scheduler controls a single run of a program.
+type Scheduler struct {
+	threads []*thread
+	current *thread
+	started bool
+
+	strategy strategy
+	maxSteps int
+	trace    []choice
+
+	err      error
+	aborted  bool
+	finished chan struct{}
+}
+
+type thread struct {
+	id   int
+	fn   func()
+	wake chan struct{}
+	done bool
+	// waitFor is non-nil while the thread is blocked
+	waitFor  func() bool
+	signaled bool
+}
+
+func (th *thread) runnable() bool {
+	return !th.done && (th.waitFor == nil || th.waitFor())
+}
+
+// choice records a single scheduling decision.
+type choice struct {
+	current  int
+	runnable []int
+	chosen   int
+	gosched  bool
+}
+
+// ErrStepLimit is reported when a run exceeds the maximum number of steps,
+// which usually means a livelock.
+var ErrStepLimit = errors.New("replay: step limit exceeded")
+
+// errAbort unwinds threads of an aborted run.
+var errAbort = errors.New("replay: aborted")
+
+// active is the scheduler of the current run, nil outside of runs.
+var active *Scheduler
+
+// Go starts a thread, it must be called before the program returns.
+func (s *Scheduler) Go(fn func()) {
+	if s.started {
+		panic("replay: threads must be started during program setup")
+	}
+	s.threads = append(s.threads, &thread{
+		id:   len(s.threads),
+		fn:   fn,
+		wake: make(chan struct{}),
+	})
+}
+
+// run runs the program once and returns the decisions it made.
+func run(p Program, newStrategy func(threads int) strategy, maxSteps int) ([]choice, error) {
+	if active != nil {
+		panic("replay: runs cannot be nested")
+	}
+
+	s := &Scheduler{maxSteps: maxSteps, finished: make(chan struct{})}
+	check := p(s)
+	if len(s.threads) == 0 {
+		return nil, errors.New("replay: program didn't start any threads")
+	}
+	s.strategy = newStrategy(len(s.threads))
+	s.started = true
+
+	active = s
+	for _, th := range s.threads {
+		go s.start(th)
+	}
+	s.switchTo(s.choose(nil, false))
+	<-s.finished
+	active = nil
+
+	if s.err == nil && check != nil {
+		s.err = check()
+	}
+	return s.trace, s.err
+}
+
+func (s *Scheduler) start(th *thread) {
+	<-th.wake
+	defer func() {
+		if r := recover(); r != nil && r != errAbort {
+			s.fail(fmt.Errorf("thread %d panicked: %v\n%s", th.id, r, debug.Stack()))
+		}
+		th.done = true
+		s.switchTo(s.choose(th, false))
+	}()
+	if s.aborted {
+		return
+	}
+	th.fn()
+}
+
+// fail aborts the run with err.
+func (s *Scheduler) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+	s.aborted = true
+}
+
+// choose picks the next thread to run after from, nil means the run is finished.
+func (s *Scheduler) choose(from *thread, gosched bool) *thread {
+	if s.aborted {
+		// wake up the remaining threads, so that they can unwind
+		for _, th := range s.threads {
+			if !th.done && th != from {
+				return th
+			}
+		}
+		return nil
+	}
+
+	var runnable []int
+	for _, th := range s.threads {
+		if th.runnable() {
+			runnable = append(runnable, th.id)
+		}
+	}
+	if len(runnable) == 0 {
+		var blocked []string
+		for _, th := range s.threads {
+			if !th.done {
+				blocked = append(blocked, fmt.Sprint(th.id))
+			}
+		}
+		if len(blocked) > 0 {
+			s.fail(fmt.Errorf("replay: deadlock, threads %v are blocked", strings.Join(blocked, ", ")))
+			return s.choose(from, false)
+		}
+		return nil
+	}
+
+	if s.maxSteps > 0 && len(s.trace) >= s.maxSteps {
+		s.fail(ErrStepLimit)
+		return s.choose(from, false)
+	}
+
+	current := -1
+	if from != nil {
+		current = from.id
+	}
+	chosen := s.strategy.pick(len(s.trace), current, runnable, gosched)
+	s.trace = append(s.trace, choice{
+		current:  current,
+		runnable: runnable,
+		chosen:   chosen,
+		gosched:  gosched,
+	})
+	return s.threads[chosen]
+}
+
+// switchTo passes control to next, nil next finishes the run.
+func (s *Scheduler) switchTo(next *thread) {
+	s.current = next
+	if next == nil {
+		close(s.finished)
+		return
+	}
+	next.wake <- struct{}{}
+}
+
+// schedule is a scheduling point of the current thread.
+func (s *Scheduler) schedule(gosched bool) {
+	th := s.current
+	next := s.choose(th, gosched)
+	if s.aborted {
+		panic(errAbort)
+	}
+	if next == th {
+		return
+	}
+	s.switchTo(next)
+	<-th.wake
+	if s.aborted {
+		panic(errAbort)
+	}
+}
+
+// block blocks the current thread until cond returns true.
+func (s *Scheduler) block(cond func() bool) {
+	th := s.current
+	th.waitFor = cond
+	s.schedule(false)
+	th.waitFor = nil
+}
+
+// defaultChoice is the choice without any preemptions: the current thread
+// continues, unless it has finished, blocked or yielded with Gosched,
+// then the next runnable thread in round-robin order runs.
+func defaultChoice(current int, runnable []int, gosched bool) int {
+	i := sort.SearchInts(runnable, current)
+	if i < len(runnable) && runnable[i] == current {
+		if !gosched {
+			return current
+		}
+		i++
+	}
+	return runnable[i%len(runnable)]
+}
@@ -0,0 +1,210 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// racyCounter increments a counter without a read-modify-write operation.
+func racyCounter(s *Scheduler) func() error {
+	var counter int64
+	for i := 0; i < 2; i++ {
+		s.Go(func() {
+			v := LoadInt64(&counter)
+			StoreInt64(&counter, v+1)
+		})
+	}
+	return func() error {
+		if counter != 2 {
+			return fmt.Errorf("counter %v, expected 2", counter)
+		}
+		return nil
+	}
+}
+
+func atomicCounter(s *Scheduler) func() error {
+	var counter int64
+	for i := 0; i < 3; i++ {
+		s.Go(func() {
+			AddInt64(&counter, 1)
+			AddInt64(&counter, 1)
+		})
+	}
+	return func() error {
+		if counter != 6 {
+			return fmt.Errorf("counter %v, expected 6", counter)
+		}
+		return nil
+	}
+}
+
+func TestFindsRace(t *testing.T) {
+	for _, explore := range []struct {
+		Name string
+		Run  func(Program, Options) *Failure
+	}{
+		{"PCT", PCT},
+		{"Exhaustive", Exhaustive},
+	} {
+		t.Run(explore.Name, func(t *testing.T) {
+			failure := explore.Run(racyCounter, Options{Seed: 1})
+			if failure == nil {
+				t.Fatal("did not find the race")
+			}
+			if len(failure.Schedule) != 1 {
+				t.Fatalf("schedule %q is not minimal", failure.Schedule)
+			}
+			if err := Replay(racyCounter, failure.Schedule, Options{}); err == nil {
+				t.Fatalf("schedule %q did not reproduce the failure", failure.Schedule)
+			}
+			if err := Replay(racyCounter, nil, Options{}); err != nil {
+				t.Fatalf("default schedule failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestNoFalsePositives(t *testing.T) {
+	if failure := PCT(atomicCounter, Options{Seed: 1, Runs: 200}); failure != nil {
+		t.Fatal(failure)
+	}
+	if failure := Exhaustive(atomicCounter, Options{Runs: 100000}); failure != nil {
+		t.Fatal(failure)
+	}
+}
+
+func TestSeedReproduces(t *testing.T) {
+	failure := PCT(racyCounter, Options{Seed: 42})
+	if failure == nil {
+		t.Fatal("did not find the race")
+	}
+	again := PCT(racyCounter, Options{Seed: failure.Seed, Runs: 1})
+	if again == nil {
+		t.Fatalf("seed %v did not reproduce the failure", failure.Seed)
+	}
+	if again.Schedule.String() != failure.Schedule.String() {
+		t.Fatalf("schedule %q, expected %q", again.Schedule, failure.Schedule)
+	}
+}
+
+func TestDeadlock(t *testing.T) {
+	program := func(s *Scheduler) func() error {
+		var a, b Mutex
+		s.Go(func() {
+			a.Lock()
+			b.Lock()
+			b.Unlock()
+			a.Unlock()
+		})
+		s.Go(func() {
+			b.Lock()
+			a.Lock()
+			a.Unlock()
+			b.Unlock()
+		})
+		return nil
+	}
+
+	failure := Exhaustive(program, Options{})
+	if failure == nil {
+		t.Fatal("did not find the deadlock")
+	}
+	if !strings.Contains(failure.Err.Error(), "deadlock") {
+		t.Fatalf("unexpected error: %v", failure.Err)
+	}
+}
+
+func TestCond(t *testing.T) {
+	program := func(s *Scheduler) func() error {
+		var mu Mutex
+		cond := Cond{L: &mu}
+		var queue []int
+		var received []int
+
+		s.Go(func() {
+			for i := 0; i < 3; i++ {
+				mu.Lock()
+				queue = append(queue, i)
+				cond.Signal()
+				mu.Unlock()
+			}
+		})
+		s.Go(func() {
+			for i := 0; i < 3; i++ {
+				mu.Lock()
+				for len(queue) == 0 {
+					cond.Wait()
+				}
+				received = append(received, queue[0])
+				queue = queue[1:]
+				mu.Unlock()
+			}
+		})
+		return func() error {
+			if fmt.Sprint(received) != "[0 1 2]" {
+				return fmt.Errorf("received %v", received)
+			}
+			return nil
+		}
+	}
+
+	if failure := Exhaustive(program, Options{Preemptions: 3, Runs: 100000}); failure != nil {
+		t.Fatal(failure)
+	}
+	if failure := PCT(program, Options{Seed: 1, Runs: 200}); failure != nil {
+		t.Fatal(failure)
+	}
+}
+
+func TestSpinning(t *testing.T) {
+	program := func(s *Scheduler) func() error {
+		var flag int64
+		s.Go(func() {
+			for LoadInt64(&flag) == 0 {
+				Gosched()
+			}
+		})
+		s.Go(func() { StoreInt64(&flag, 1) })
+		return nil
+	}
+
+	if failure := PCT(program, Options{Seed: 1, Runs: 200, MaxSteps: 1000}); failure != nil {
+		t.Fatal(failure)
+	}
+	if failure := Exhaustive(program, Options{MaxSteps: 1000}); failure != nil {
+		t.Fatal(failure)
+	}
+}
+
+func TestStepLimit(t *testing.T) {
+	program := func(s *Scheduler) func() error {
+		var flag int64
+		s.Go(func() {
+			for LoadInt64(&flag) == 0 {
+				Gosched()
+			}
+		})
+		return nil
+	}
+
+	err := Replay(program, nil, Options{MaxSteps: 100})
+	if !errors.Is(err, ErrStepLimit) {
+		t.Fatalf("got %v, expected step limit", err)
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	schedule := Schedule{{Step: 3, Thread: 1}, {Step: 17, Thread: 0}}
+	parsed, err := ParseSchedule(schedule.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.String() != "3:1 17:0" {
+		t.Fatalf("parsed %q", parsed)
+	}
+	if _, err := ParseSchedule("3-1"); err == nil {
+		t.Fatal("parsing invalid schedule succeeded")
+	}
+}
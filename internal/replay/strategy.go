@@ -0,0 +1,84 @@
+package replay
+
+import (
+	"math/rand"
+)
+
+// strategy picks the next thread at every scheduling point.
+type strategy interface {
+	// pick chooses a thread from runnable, which is sorted by id,
+	// current is the thread at the scheduling point or -1 at the start.
+	pick(step, current int, runnable []int, gosched bool) int
+}
+
+// replayer follows a schedule and makes the default choice otherwise.
+//
+// When a thread in the schedule isn't runnable, the switch is skipped,
+// which allows replaying slightly modified schedules.
+type replayer struct {
+	schedule Schedule
+}
+
+func (r *replayer) pick(step, current int, runnable []int, gosched bool) int {
+	for len(r.schedule) > 0 && r.schedule[0].Step < step {
+		r.schedule = r.schedule[1:]
+	}
+	if len(r.schedule) > 0 && r.schedule[0].Step == step {
+		for _, id := range runnable {
+			if id == r.schedule[0].Thread {
+				return id
+			}
+		}
+	}
+	return defaultChoice(current, runnable, gosched)
+}
+
+// pct implements "A Randomized Scheduler with Probabilistic Guarantees of Finding Bugs"
+// by Sebastian Burckhardt, Pravesh Kothari, Madanlal Musuvathi and Santosh Nagarakatte.
+//
+// Threads get random distinct priorities and the highest priority runnable thread runs.
+// At depth-1 random steps the priority of the running thread drops below all initial
+// priorities. Gosched drops the priority as well, so that spinning threads let others progress.
+type pct struct {
+	priority []int
+	changes  map[int]int
+	lowest   int
+}
+
+func newPCT(rng *rand.Rand, threads, depth, steps int) *pct {
+	p := &pct{
+		priority: make([]int, threads),
+		changes:  map[int]int{},
+		lowest:   0,
+	}
+	for i, k := range rng.Perm(threads) {
+		p.priority[i] = depth + k
+	}
+	if steps < 1 {
+		steps = 1
+	}
+	for i := 0; i < depth-1; i++ {
+		p.changes[rng.Intn(steps)] = depth - 1 - i
+	}
+	return p
+}
+
+func (p *pct) pick(step, current int, runnable []int, gosched bool) int {
+	if current >= 0 {
+		if priority, ok := p.changes[step]; ok {
+			p.priority[current] = priority
+		}
+		if gosched {
+			p.lowest--
+			p.priority[current] = p.lowest
+		}
+	}
+
+	best := runnable[0]
+	for _, id := range runnable[1:] {
+		if p.priority[id] > p.priority[best] {
+			best = id
+		}
+	}
+	return best
+}
@@ -0,0 +1,172 @@
+package replay
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Yield is a scheduling point, outside of a run it does nothing.
+func Yield() {
+	if s := active; s != nil {
+		s.schedule(false)
+	}
+}
+
+// Gosched is a scheduling point, which prefers running other threads,
+// spinning loops must call it to avoid livelocks.
+func Gosched() {
+	if s := active; s != nil {
+		s.schedule(true)
+		return
+	}
+	runtime.Gosched()
+}
+
+// LoadUint32 is atomic.LoadUint32 with a scheduling point.
+func LoadUint32(addr *uint32) uint32 {
+	Yield()
+	return atomic.LoadUint32(addr)
+}
+
+// StoreUint32 is atomic.StoreUint32 with a scheduling point.
+func StoreUint32(addr *uint32, v uint32) {
+	Yield()
+	atomic.StoreUint32(addr, v)
+}
+
+// LoadInt64 is atomic.LoadInt64 with a scheduling point.
+func LoadInt64(addr *int64) int64 {
+	Yield()
+	return atomic.LoadInt64(addr)
+}
+
+// StoreInt64 is atomic.StoreInt64 with a scheduling point.
+func StoreInt64(addr *int64, v int64) {
+	Yield()
+	atomic.StoreInt64(addr, v)
+}
+
+// AddInt64 is atomic.AddInt64 with a scheduling point.
+func AddInt64(addr *int64, delta int64) int64 {
+	Yield()
+	return atomic.AddInt64(addr, delta)
+}
+
+// CompareAndSwapInt64 is atomic.CompareAndSwapInt64 with a scheduling point.
+func CompareAndSwapInt64(addr *int64, old, new int64) bool {
+	Yield()
+	return atomic.CompareAndSwapInt64(addr, old, new)
+}
+
+// LoadPointer is atomic.LoadPointer with a scheduling point.
+func LoadPointer(addr *unsafe.Pointer) unsafe.Pointer {
+	Yield()
+	return atomic.LoadPointer(addr)
+}
+
+// StorePointer is atomic.StorePointer with a scheduling point.
+func StorePointer(addr *unsafe.Pointer, v unsafe.Pointer) {
+	Yield()
+	atomic.StorePointer(addr, v)
+}
+
+// SwapPointer is atomic.SwapPointer with a scheduling point.
+func SwapPointer(addr *unsafe.Pointer, new unsafe.Pointer) unsafe.Pointer {
+	Yield()
+	return atomic.SwapPointer(addr, new)
+}
+
+// Mutex is a mutex, where locking and unlocking are scheduling points.
+// Outside of a run it behaves as sync.Mutex.
+type Mutex struct {
+	mu     sync.Mutex
+	locked bool
+}
+
+// Lock locks the mutex.
+func (m *Mutex) Lock() {
+	s := active
+	if s == nil {
+		m.mu.Lock()
+		return
+	}
+	s.schedule(false)
+	if m.locked {
+		s.block(func() bool { return !m.locked })
+	}
+	m.locked = true
+}
+
+// Unlock unlocks the mutex.
+func (m *Mutex) Unlock() {
+	s := active
+	if s == nil {
+		m.mu.Unlock()
+		return
+	}
+	if !m.locked {
+		panic("replay: unlock of unlocked mutex")
+	}
+	m.locked = false
+	s.schedule(false)
+}
+
+// Cond is a condition variable, where waiting and signaling are scheduling points.
+// Outside of a run it behaves as sync.Cond.
+type Cond struct {
+	L sync.Locker
+
+	once    sync.Once
+	cond    sync.Cond
+	waiters []*thread
+}
+
+func (c *Cond) outside() *sync.Cond {
+	c.once.Do(func() { c.cond.L = c.L })
+	return &c.cond
+}
+
+// Wait unlocks c.L, waits for a signal and locks c.L again.
+func (c *Cond) Wait() {
+	s := active
+	if s == nil {
+		c.outside().Wait()
+		return
+	}
+	th := s.current
+	th.signaled = false
+	c.waiters = append(c.waiters, th)
+	c.L.Unlock()
+	s.block(func() bool { return th.signaled })
+	c.L.Lock()
+}
+
+// Signal wakes up the longest waiting thread.
+func (c *Cond) Signal() {
+	s := active
+	if s == nil {
+		c.outside().Signal()
+		return
+	}
+	s.schedule(false)
+	if len(c.waiters) > 0 {
+		c.waiters[0].signaled = true
+		c.waiters = c.waiters[1:]
+	}
+}
+
+// Broadcast wakes up all the waiting threads.
+func (c *Cond) Broadcast() {
+	s := active
+	if s == nil {
+		c.outside().Broadcast()
+		return
+	}
+	s.schedule(false)
+	for _, th := range c.waiters {
+		th.signaled = true
+	}
+	c.waiters = nil
+}
@@ -29,6 +29,18 @@ var TestCount = []int{
 	1023, 1024, 1025,
 }
 
+// LinearizeProcs is the number of producers or consumers in linearizability tests
+var LinearizeProcs = 4
+
+// LinearizeCount is the number of values per producer and consumer in linearizability tests
+var LinearizeCount = 64
+
+// ParallelProcs is the minimum GOMAXPROCS in tests that need goroutines running in parallel
+var ParallelProcs = 4
+
+// BroadcastProcs is the number of subscribers in broadcast tests
+var BroadcastProcs = 4
+
 var shake = flag.Int("shake", 1, "run tests multiple times")
 
 func skipRedundant(q Queue, testsize int) bool {
@@ -41,97 +53,183 @@ func skipRedundant(q Queue, testsize int) bool {
 	return false
 }
 
-// Tests runs queue tests for queues
-func Tests(t *testing.T, ctor func() Queue) {
+// Tests runs queue tests for queues with values of type T
+func Tests[T any](t *testing.T, codec Codec[T], ctor func() Queue) {
 	q := ctor()
-	caps := Detect(q)
-	if !caps.Any(CapQueue) {
+	caps := Detect[T](q)
+	if !caps.Any(CapQueue | CapDeque | CapBroadcast | CapLossy | CapPriority | CapDelay) {
 		t.Fatal("does not implement any of queue interfaces")
 	}
 	t.Helper()
 
-	// TODO: add system noise when shaking
+	defer startNoise(t)()
 
 	if caps.Has(CapBlockSPSC) {
 		for i := 0; i < *shake; i++ {
-			t.Run("b/SPSC", func(t *testing.T) { t.Helper(); testSPSC(t, caps, ctor) })
+			t.Run("b/SPSC", func(t *testing.T) { t.Helper(); testSPSC(t, caps, codec, ctor) })
 		}
 	}
 	if caps.Has(CapBlockMPSC) {
 		for i := 0; i < *shake; i++ {
-			t.Run("b/MPSC", func(t *testing.T) { t.Helper(); testMPSC(t, caps, ctor) })
+			t.Run("b/MPSC", func(t *testing.T) { t.Helper(); testMPSC(t, caps, codec, ctor) })
 		}
 	}
 	if caps.Has(CapBlockSPMC) {
 		for i := 0; i < *shake; i++ {
-			t.Run("b/SPMC", func(t *testing.T) { t.Helper(); testSPMC(t, caps, ctor) })
+			t.Run("b/SPMC", func(t *testing.T) { t.Helper(); testSPMC(t, caps, codec, ctor) })
 		}
 	}
 	if caps.Has(CapBlockMPMC) {
 		for i := 0; i < *shake; i++ {
-			t.Run("b/MPMC", func(t *testing.T) { t.Helper(); testMPMC(t, caps, ctor) })
+			t.Run("b/MPMC", func(t *testing.T) { t.Helper(); testMPMC(t, caps, codec, ctor) })
+		}
+	}
+	if caps.Has(CapBlockSPSC) {
+		for i := 0; i < *shake; i++ {
+			t.Run("b/Linearizable", func(t *testing.T) { t.Helper(); testLinearizable(t, caps, codec, ctor) })
 		}
 	}
 
 	if caps.Has(CapNonblockSPSC) {
 		for i := 0; i < *shake; i++ {
-			t.Run("n/SPSC", func(t *testing.T) { t.Helper(); testNonblockSPSC(t, caps, ctor) })
+			t.Run("n/SPSC", func(t *testing.T) { t.Helper(); testNonblockSPSC(t, caps, codec, ctor) })
 		}
 	}
 	if caps.Has(CapNonblockMPSC) {
 		for i := 0; i < *shake; i++ {
-			t.Run("n/MPSC", func(t *testing.T) { t.Helper(); testNonblockMPSC(t, caps, ctor) })
+			t.Run("n/MPSC", func(t *testing.T) { t.Helper(); testNonblockMPSC(t, caps, codec, ctor) })
 		}
 	}
 	if caps.Has(CapNonblockSPMC) {
 		for i := 0; i < *shake; i++ {
-			t.Run("n/SPMC", func(t *testing.T) { t.Helper(); testNonblockSPMC(t, caps, ctor) })
+			t.Run("n/SPMC", func(t *testing.T) { t.Helper(); testNonblockSPMC(t, caps, codec, ctor) })
 		}
 	}
 	if caps.Has(CapNonblockMPMC) {
 		for i := 0; i < *shake; i++ {
-			t.Run("n/MPMC", func(t *testing.T) { t.Helper(); testNonblockMPMC(t, caps, ctor) })
+			t.Run("n/MPMC", func(t *testing.T) { t.Helper(); testNonblockMPMC(t, caps, codec, ctor) })
+		}
+	}
+	if caps.Has(CapNonblockSPSC) {
+		for i := 0; i < *shake; i++ {
+			t.Run("n/Linearizable", func(t *testing.T) { t.Helper(); testNonblockLinearizable(t, caps, codec, ctor) })
+		}
+	}
+
+	if caps.Has(CapBlockSPSC) && caps.Any(CapBatchSend|CapBatchRecv) {
+		for i := 0; i < *shake; i++ {
+			t.Run("b/Batch", func(t *testing.T) { t.Helper(); testBatch(t, caps, codec, ctor) })
+		}
+	}
+	if caps.Has(CapBlockSPSC) {
+		t.Run("b/Context", func(t *testing.T) { t.Helper(); testContext(t, caps, codec, ctor) })
+	}
+	if caps.Has(CapBlockSPSC | CapClose) {
+		t.Run("b/Close", func(t *testing.T) { t.Helper(); testClose(t, caps, codec, ctor) })
+	}
+	if caps.Has(CapNonblockSPSC | CapClose) {
+		t.Run("n/Close", func(t *testing.T) { t.Helper(); testNonblockClose(t, caps, codec, ctor) })
+	}
+
+	if caps.Has(CapDeque) {
+		for i := 0; i < *shake; i++ {
+			t.Run("d/Deque", func(t *testing.T) { t.Helper(); testDeque(t, caps, codec, ctor) })
+		}
+	}
+	if caps.Has(CapBroadcast) {
+		for i := 0; i < *shake; i++ {
+			t.Run("c/Broadcast", func(t *testing.T) { t.Helper(); testBroadcast(t, caps, codec, ctor) })
+		}
+	}
+	if caps.Has(CapLossy) {
+		for i := 0; i < *shake; i++ {
+			t.Run("l/Lossy", func(t *testing.T) { t.Helper(); testLossy(t, caps, codec, ctor) })
+		}
+	}
+	if caps.Any(CapOverflow) {
+		for i := 0; i < *shake; i++ {
+			t.Run("o/Overflow", func(t *testing.T) { t.Helper(); testOverflow(t, caps, codec, ctor) })
+		}
+	}
+	if caps.Has(CapPriority) {
+		for i := 0; i < *shake; i++ {
+			t.Run("p/Priority", func(t *testing.T) { t.Helper(); testPriority(t, caps, codec, ctor) })
+		}
+	}
+	if caps.Has(CapDelay) {
+		for i := 0; i < *shake; i++ {
+			t.Run("t/Delay", func(t *testing.T) { t.Helper(); testDelay(t, caps, codec, ctor) })
 		}
 	}
 }
 
-// Benchmarks runs queue benchmarks for queues
-func Benchmarks(b *testing.B, ctor func() Queue) {
-	caps := Detect(ctor())
-	if !caps.Any(CapQueue) {
+// Benchmarks runs queue benchmarks for queues with values of type T
+func Benchmarks[T any](b *testing.B, ctor func() Queue) {
+	caps := Detect[T](ctor())
+	if !caps.Any(CapQueue | CapDeque | CapBroadcast | CapLossy | CapPriority | CapDelay) {
 		b.Fatal("does not implement any of queue interfaces")
 	}
 	b.Helper()
 
-	benchCommon(b, caps, ctor)
+	benchCommon[T](b, caps, ctor)
 
 	// blocking implementations
 
 	if caps.Has(CapBlockSPSC) {
-		b.Run("b/SPSC", func(b *testing.B) { b.Helper(); benchSPSC(b, caps, ctor) })
+		b.Run("b/SPSC", func(b *testing.B) { b.Helper(); benchSPSC[T](b, caps, ctor) })
 	}
 	if caps.Has(CapBlockMPSC) {
-		b.Run("b/MPSC", func(b *testing.B) { b.Helper(); benchMPSC(b, caps, ctor) })
+		b.Run("b/MPSC", func(b *testing.B) { b.Helper(); benchMPSC[T](b, caps, ctor) })
 	}
 	if caps.Has(CapBlockSPMC) {
-		b.Run("b/SPMC", func(b *testing.B) { b.Helper(); benchSPMC(b, caps, ctor) })
+		b.Run("b/SPMC", func(b *testing.B) { b.Helper(); benchSPMC[T](b, caps, ctor) })
 	}
 	if caps.Has(CapBlockMPMC) {
-		b.Run("b/MPMC", func(b *testing.B) { b.Helper(); benchMPMC(b, caps, ctor) })
+		b.Run("b/MPMC", func(b *testing.B) { b.Helper(); benchMPMC[T](b, caps, ctor) })
 	}
 
 	// non-blocking implementations
 
 	if caps.Has(CapNonblockSPSC) {
-		b.Run("n/SPSC", func(b *testing.B) { b.Helper(); benchNonblockSPSC(b, caps, ctor) })
+		b.Run("n/SPSC", func(b *testing.B) { b.Helper(); benchNonblockSPSC[T](b, caps, ctor) })
 	}
 	if caps.Has(CapNonblockMPSC) {
-		b.Run("n/MPSC", func(b *testing.B) { b.Helper(); benchNonblockMPSC(b, caps, ctor) })
+		b.Run("n/MPSC", func(b *testing.B) { b.Helper(); benchNonblockMPSC[T](b, caps, ctor) })
 	}
 	if caps.Has(CapNonblockSPMC) {
-		b.Run("n/SPMC", func(b *testing.B) { b.Helper(); benchNonblockSPMC(b, caps, ctor) })
+		b.Run("n/SPMC", func(b *testing.B) { b.Helper(); benchNonblockSPMC[T](b, caps, ctor) })
 	}
 	if caps.Has(CapNonblockMPMC) {
-		b.Run("n/MPMC", func(b *testing.B) { b.Helper(); benchNonblockMPMC(b, caps, ctor) })
+		b.Run("n/MPMC", func(b *testing.B) { b.Helper(); benchNonblockMPMC[T](b, caps, ctor) })
+	}
+
+	// work-stealing deques
+
+	if caps.Has(CapDeque) {
+		b.Run("d/Deque", func(b *testing.B) { b.Helper(); benchDeque[T](b, caps, ctor) })
+	}
+
+	// broadcast queues
+
+	if caps.Has(CapBroadcast) {
+		b.Run("c/Broadcast", func(b *testing.B) { b.Helper(); benchBroadcast[T](b, caps, ctor) })
+	}
+
+	// lossy queues
+
+	if caps.Has(CapLossy) {
+		b.Run("l/Lossy", func(b *testing.B) { b.Helper(); benchLossy[T](b, caps, ctor) })
+	}
+
+	// priority queues
+
+	if caps.Has(CapPriority) {
+		b.Run("p/Priority", func(b *testing.B) { b.Helper(); benchPriority[T](b, caps, ctor) })
+	}
+
+	// delay queues
+
+	if caps.Has(CapDelay) {
+		b.Run("t/Delay", func(b *testing.B) { b.Helper(); benchDelay[T](b, caps, ctor) })
 	}
 }
@@ -6,7 +6,7 @@ import (
 	"testing"
 )
 
-func benchCommon(b *testing.B, caps Capability, ctor func() Queue) {
+func benchCommon[T any](b *testing.B, caps Capability, ctor func() Queue) {
 	b.Run("Create/x1", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
 			_ = ctor()
@@ -14,13 +14,13 @@ func benchCommon(b *testing.B, caps Capability, ctor func() Queue) {
 	})
 }
 
-func benchSPSC(b *testing.B, caps Capability, ctor func() Queue) {
+func benchSPSC[T any](b *testing.B, caps Capability, ctor func() Queue) {
 	b.Run("Single/x1", func(b *testing.B) {
-		q := ctor().(SPSC)
+		q := ctor().(SPSC[T])
 		b.ResetTimer()
 		if flusher, ok := q.(Flusher); ok {
 			for i := 0; i < b.N; i++ {
-				var v Value
+				var v T
 				q.Send(v)
 				flusher.FlushSend()
 				q.Recv(&v)
@@ -28,7 +28,7 @@ func benchSPSC(b *testing.B, caps Capability, ctor func() Queue) {
 			}
 		} else {
 			for i := 0; i < b.N; i++ {
-				var v Value
+				var v T
 				q.Send(v)
 				q.Recv(&v)
 			}
@@ -37,10 +37,10 @@ func benchSPSC(b *testing.B, caps Capability, ctor func() Queue) {
 
 	b.Run("Uncontended/x100", func(b *testing.B) {
 		b.RunParallel(func(pb *testing.PB) {
-			q := ctor().(SPSC)
+			q := ctor().(SPSC[T])
 			if flusher, ok := q.(Flusher); ok {
 				for pb.Next() {
-					var v Value
+					var v T
 					for i := 0; i < 100; i++ {
 						q.Send(v)
 						flusher.FlushSend()
@@ -50,7 +50,7 @@ func benchSPSC(b *testing.B, caps Capability, ctor func() Queue) {
 				}
 			} else {
 				for pb.Next() {
-					var v Value
+					var v T
 					for i := 0; i < 100; i++ {
 						q.Send(v)
 						q.Recv(&v)
@@ -62,9 +62,9 @@ func benchSPSC(b *testing.B, caps Capability, ctor func() Queue) {
 
 	b.Run("Multiple/x100", func(b *testing.B) {
 		const P = 1000
-		qs := [P]SPSC{}
+		qs := [P]SPSC[T]{}
 		for i := range qs {
-			qs[i] = ctor().(SPSC)
+			qs[i] = ctor().(SPSC[T])
 		}
 
 		b.ResetTimer()
@@ -72,17 +72,17 @@ func benchSPSC(b *testing.B, caps Capability, ctor func() Queue) {
 		var wg sync.WaitGroup
 		wg.Add(P * 2)
 		for i := 0; i < P; i++ {
-			go func(q SPSC) {
+			go func(q SPSC[T]) {
 				for i := 0; i < b.N; i++ {
-					var v Value
+					var v T
 					q.Send(v)
 				}
 				FlushSend(q)
 				wg.Done()
 			}(qs[i])
-			go func(q SPSC) {
+			go func(q SPSC[T]) {
 				for i := 0; i < b.N; i++ {
-					var v Value
+					var v T
 					q.Recv(&v)
 				}
 				FlushRecv(q)
@@ -98,13 +98,13 @@ func benchSPSC(b *testing.B, caps Capability, ctor func() Queue) {
 			suffix = "Work" + strconv.Itoa(work)
 		}
 		b.Run("ProducerConsumer"+suffix+"/x1", func(b *testing.B) {
-			q := ctor().(SPSC)
+			q := ctor().(SPSC[T])
 			b.ResetTimer()
 			var wg sync.WaitGroup
 			wg.Add(2)
 			go func() {
 				for i := 0; i < b.N; i++ {
-					var v Value
+					var v T
 					q.Send(v)
 					LocalWork(work)
 				}
@@ -113,7 +113,7 @@ func benchSPSC(b *testing.B, caps Capability, ctor func() Queue) {
 			}()
 			go func() {
 				for i := 0; i < b.N; i++ {
-					var v Value
+					var v T
 					q.Recv(&v)
 					LocalWork(work)
 				}
@@ -124,13 +124,13 @@ func benchSPSC(b *testing.B, caps Capability, ctor func() Queue) {
 		})
 
 		b.Run("PingPong"+suffix+"/x1", func(b *testing.B) {
-			q1, q2 := ctor().(SPSC), ctor().(SPSC)
+			q1, q2 := ctor().(SPSC[T]), ctor().(SPSC[T])
 			b.ResetTimer()
 			var wg sync.WaitGroup
 			wg.Add(2)
 			go func() {
 				for i := 0; i < b.N; i++ {
-					var v Value
+					var v T
 					q1.Send(v)
 					FlushSend(q1)
 					LocalWork(work)
@@ -141,7 +141,7 @@ func benchSPSC(b *testing.B, caps Capability, ctor func() Queue) {
 			}()
 			go func() {
 				for i := 0; i < b.N; i++ {
-					var v Value
+					var v T
 					q1.Recv(&v)
 					FlushRecv(q1)
 					LocalWork(work)
@@ -155,23 +155,24 @@ func benchSPSC(b *testing.B, caps Capability, ctor func() Queue) {
 	}
 }
 
-func benchMPSC(b *testing.B, caps Capability, ctor func() Queue) {
+func benchMPSC[T any](b *testing.B, caps Capability, ctor func() Queue) {
 	for _, work := range BenchWork {
 		suffix := ""
 		if work > 0 {
 			suffix = "Work" + strconv.Itoa(work)
 		}
 		b.Run("ProducerConsumer"+suffix+"/x100", func(b *testing.B) {
-			q := ctor().(MPSC)
+			q := ctor().(MPSC[T])
 			b.ResetTimer()
 			var wg sync.WaitGroup
 			wg.Add(2)
 
 			go func() {
 				b.RunParallel(func(pb *testing.PB) {
+					var zero T
 					for pb.Next() {
 						for i := 0; i < 100; i++ {
-							q.Send(0)
+							q.Send(zero)
 							LocalWork(work)
 						}
 					}
@@ -183,7 +184,7 @@ func benchMPSC(b *testing.B, caps Capability, ctor func() Queue) {
 			go func() {
 				for i := 0; i < b.N; i++ {
 					for i := 0; i < 100; i++ {
-						var v Value
+						var v T
 						q.Recv(&v)
 						LocalWork(work)
 					}
@@ -195,22 +196,23 @@ func benchMPSC(b *testing.B, caps Capability, ctor func() Queue) {
 	}
 }
 
-func benchSPMC(b *testing.B, caps Capability, ctor func() Queue) {
+func benchSPMC[T any](b *testing.B, caps Capability, ctor func() Queue) {
 	for _, work := range BenchWork {
 		suffix := ""
 		if work > 0 {
 			suffix = "Work" + strconv.Itoa(work)
 		}
 		b.Run("ProducerConsumer"+suffix+"/x100", func(b *testing.B) {
-			q := ctor().(SPMC)
+			q := ctor().(SPMC[T])
 			b.ResetTimer()
 			var wg sync.WaitGroup
 			wg.Add(2)
 
 			go func() {
+				var zero T
 				for i := 0; i < b.N; i++ {
 					for i := 0; i < 100; i++ {
-						q.Send(0)
+						q.Send(zero)
 						LocalWork(work)
 					}
 				}
@@ -222,7 +224,7 @@ func benchSPMC(b *testing.B, caps Capability, ctor func() Queue) {
 				b.RunParallel(func(pb *testing.PB) {
 					for pb.Next() {
 						for i := 0; i < 100; i++ {
-							var v Value
+							var v T
 							q.Recv(&v)
 						}
 					}
@@ -235,14 +237,14 @@ func benchSPMC(b *testing.B, caps Capability, ctor func() Queue) {
 	}
 }
 
-func benchMPMC(b *testing.B, caps Capability, ctor func() Queue) {
+func benchMPMC[T any](b *testing.B, caps Capability, ctor func() Queue) {
 	b.Run("Contended/x100", func(b *testing.B) {
-		q := ctor().(MPMC)
+		q := ctor().(MPMC[T])
 		b.RunParallel(func(pb *testing.PB) {
 			if flusher, ok := q.(Flusher); ok {
 				for pb.Next() {
 					for i := 0; i < 100; i++ {
-						var v Value
+						var v T
 						q.Send(v)
 						flusher.FlushSend()
 						q.Recv(&v)
@@ -252,7 +254,7 @@ func benchMPMC(b *testing.B, caps Capability, ctor func() Queue) {
 			} else {
 				for pb.Next() {
 					for i := 0; i < 100; i++ {
-						var v Value
+						var v T
 						q.Send(v)
 						q.Recv(&v)
 					}
@@ -261,22 +263,40 @@ func benchMPMC(b *testing.B, caps Capability, ctor func() Queue) {
 		})
 	})
 
+	// Uncontended measures the path where nobody is ever waiting,
+	// which should not be slowed down by waking up sleepers.
+	b.Run("Uncontended/x100", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			q := ctor().(MPMC[T])
+			for pb.Next() {
+				var v T
+				for i := 0; i < 100; i++ {
+					q.Send(v)
+					FlushSend(q)
+					q.Recv(&v)
+					FlushRecv(q)
+				}
+			}
+		})
+	})
+
 	for _, work := range BenchWork {
 		suffix := ""
 		if work > 0 {
 			suffix = "Work" + strconv.Itoa(work)
 		}
 		b.Run("ProducerConsumer"+suffix+"/x100", func(b *testing.B) {
-			q := ctor().(MPMC)
+			q := ctor().(MPMC[T])
 			b.ResetTimer()
 
 			var wg sync.WaitGroup
 			wg.Add(2)
 			go func() {
 				b.RunParallel(func(pb *testing.PB) {
+					var zero T
 					for pb.Next() {
 						for i := 0; i < 100; i++ {
-							q.Send(0)
+							q.Send(zero)
 							LocalWork(work)
 						}
 					}
@@ -289,7 +309,7 @@ func benchMPMC(b *testing.B, caps Capability, ctor func() Queue) {
 				b.RunParallel(func(pb *testing.PB) {
 					for pb.Next() {
 						for i := 0; i < 100; i++ {
-							var v Value
+							var v T
 							q.Recv(&v)
 							LocalWork(work)
 						}
@@ -302,13 +322,13 @@ func benchMPMC(b *testing.B, caps Capability, ctor func() Queue) {
 	}
 }
 
-func benchNonblockSPSC(b *testing.B, caps Capability, ctor func() Queue) {
+func benchNonblockSPSC[T any](b *testing.B, caps Capability, ctor func() Queue) {
 	b.Run("Single/x1", func(b *testing.B) {
-		q := ctor().(NonblockingSPSC)
+		q := ctor().(NonblockingSPSC[T])
 		b.ResetTimer()
 		if flusher, ok := q.(Flusher); ok {
 			for i := 0; i < b.N; i++ {
-				var v Value
+				var v T
 				q.TrySend(v)
 				flusher.FlushSend()
 				q.TryRecv(&v)
@@ -316,7 +336,7 @@ func benchNonblockSPSC(b *testing.B, caps Capability, ctor func() Queue) {
 			}
 		} else {
 			for i := 0; i < b.N; i++ {
-				var v Value
+				var v T
 				q.TrySend(v)
 				q.TryRecv(&v)
 			}
@@ -325,9 +345,9 @@ func benchNonblockSPSC(b *testing.B, caps Capability, ctor func() Queue) {
 
 	b.Run("Uncontended/x100", func(b *testing.B) {
 		b.RunParallel(func(pb *testing.PB) {
-			q := ctor().(NonblockingSPSC)
+			q := ctor().(NonblockingSPSC[T])
 			for pb.Next() {
-				var v Value
+				var v T
 				for i := 0; i < 100; i++ {
 					q.TrySend(v)
 					FlushSend(q)
@@ -339,15 +359,15 @@ func benchNonblockSPSC(b *testing.B, caps Capability, ctor func() Queue) {
 	})
 }
 
-func benchNonblockMPSC(b *testing.B, caps Capability, ctor func() Queue) { b.Skip("todo") }
-func benchNonblockSPMC(b *testing.B, caps Capability, ctor func() Queue) { b.Skip("todo") }
+func benchNonblockMPSC[T any](b *testing.B, caps Capability, ctor func() Queue) { b.Skip("todo") }
+func benchNonblockSPMC[T any](b *testing.B, caps Capability, ctor func() Queue) { b.Skip("todo") }
 
-func benchNonblockMPMC(b *testing.B, caps Capability, ctor func() Queue) {
+func benchNonblockMPMC[T any](b *testing.B, caps Capability, ctor func() Queue) {
 	b.Run("Contended/x100", func(b *testing.B) {
-		q := ctor().(NonblockingMPMC)
+		q := ctor().(NonblockingMPMC[T])
 		b.RunParallel(func(pb *testing.PB) {
 			for pb.Next() {
-				var v Value
+				var v T
 				for i := 0; i < 100; i++ {
 					q.TrySend(v)
 					FlushSend(q)
@@ -0,0 +1,189 @@
+package testsuite
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func testBroadcast[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
+	if caps.Has(CapBounded) {
+		t.Run("Gate", func(t *testing.T) {
+			q := ctor().(Broadcast[T])
+			capacity := q.(Bounded).Cap()
+
+			next := int64(0)
+			for i := 0; i < 2*capacity+1; i++ {
+				if !q.TrySend(codec.Encode(next)) {
+					t.Fatalf("send without subscribers failed at %v", i)
+				}
+				next++
+			}
+
+			r := q.Subscribe()
+			first := next
+			for i := 0; i < capacity; i++ {
+				if !q.TrySend(codec.Encode(next)) {
+					t.Fatalf("send to subscriber failed at %v, expected to succeed %v times", i, capacity)
+				}
+				next++
+			}
+			if q.TrySend(codec.Encode(next)) {
+				t.Fatalf("send to full subscriber succeeded")
+			}
+
+			var v T
+			if !r.TryRecv(&v) {
+				t.Fatal("failed to recv")
+			}
+			if got := codec.Decode(v); got != first {
+				t.Fatalf("recv got %v, expected %v", got, first)
+			}
+			if !q.TrySend(codec.Encode(next)) {
+				t.Fatal("send after recv failed")
+			}
+			next++
+
+			r.Close()
+			if r.TryRecv(&v) {
+				t.Fatal("recv after unsubscribe succeeded")
+			}
+			for i := 0; i < 2*capacity+1; i++ {
+				if !q.TrySend(codec.Encode(next)) {
+					t.Fatalf("send after unsubscribe failed at %v", i)
+				}
+				next++
+			}
+		})
+	}
+
+	t.Run("Stream", func(t *testing.T) {
+		for _, count := range TestCount {
+			q := ctor().(Broadcast[T])
+
+			subs := make([]Subscriber[T], BroadcastProcs)
+			for i := range subs {
+				subs[i] = q.Subscribe()
+			}
+
+			ProducerConsumer(t, 1, len(subs), func(int) error {
+				for i := 0; i < count; i++ {
+					if !q.Send(codec.Encode(int64(i))) {
+						return fmt.Errorf("failed to send %v", i)
+					}
+				}
+				q.Close()
+				return nil
+			}, func(id int) error {
+				r := subs[id-1]
+				for exp := int64(0); exp < int64(count); exp++ {
+					var v T
+					if !r.Recv(&v) {
+						return fmt.Errorf("failed to recv %v", exp)
+					}
+					if got := codec.Decode(v); got != exp {
+						return fmt.Errorf("invalid order got %v, expected %v", got, exp)
+					}
+				}
+				var v T
+				if r.Recv(&v) {
+					return fmt.Errorf("recv from closed and drained succeeded")
+				}
+				return nil
+			})
+		}
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		for _, count := range TestCount {
+			q := ctor().(Broadcast[T])
+
+			var sent int64
+			ProducerConsumer(t, 1, BroadcastProcs, func(int) error {
+				for i := 0; i < count; i++ {
+					if !q.Send(codec.Encode(int64(i))) {
+						return fmt.Errorf("failed to send %v", i)
+					}
+					atomic.StoreInt64(&sent, int64(i+1))
+				}
+				q.Close()
+				return nil
+			}, func(id int) error {
+				// subscribe at different points of the stream
+				start := int64(count * (id - 1) / BroadcastProcs)
+				for atomic.LoadInt64(&sent) < start {
+					runtime.Gosched()
+				}
+				r := q.Subscribe()
+
+				// every other subscriber leaves halfway
+				leave := -1
+				if id%2 == 0 {
+					leave = (count + 1) / 2
+				}
+
+				last := int64(-1)
+				for n := 0; n != leave; n++ {
+					var v T
+					if !r.Recv(&v) {
+						if last >= 0 && last != int64(count-1) {
+							return fmt.Errorf("stream ended at %v, expected %v", last, count-1)
+						}
+						return nil
+					}
+
+					got := codec.Decode(v)
+					if last < 0 && got < start {
+						return fmt.Errorf("received %v sent before subscribing at %v", got, start)
+					}
+					if last >= 0 && got != last+1 {
+						return fmt.Errorf("invalid order got %v, expected %v", got, last+1)
+					}
+					last = got
+				}
+
+				r.Close()
+				var v T
+				if r.Recv(&v) {
+					return fmt.Errorf("recv after unsubscribe succeeded")
+				}
+				return nil
+			})
+		}
+	})
+}
+
+func benchBroadcast[T any](b *testing.B, caps Capability, ctor func() Queue) {
+	for _, subscribers := range []int{1, BroadcastProcs} {
+		b.Run(fmt.Sprintf("Subscribers%v/x100", subscribers), func(b *testing.B) {
+			q := ctor().(Broadcast[T])
+			subs := make([]Subscriber[T], subscribers)
+			for i := range subs {
+				subs[i] = q.Subscribe()
+			}
+			b.ResetTimer()
+
+			var wg sync.WaitGroup
+			wg.Add(len(subs))
+			for _, r := range subs {
+				go func(r Subscriber[T]) {
+					defer wg.Done()
+					var v T
+					for r.Recv(&v) {
+					}
+				}(r)
+			}
+
+			var zero T
+			for i := 0; i < b.N; i++ {
+				for i := 0; i < 100; i++ {
+					q.Send(zero)
+				}
+			}
+			q.Close()
+			wg.Wait()
+		})
+	}
+}
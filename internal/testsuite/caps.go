@@ -39,6 +39,45 @@ func (caps Capability) String() string {
 	if caps.Has(CapBounded) {
 		xs = append(xs, "Bounded")
 	}
+	if caps.Has(CapClose) {
+		xs = append(xs, "Close")
+	}
+	if caps.Has(CapBatchSend) {
+		xs = append(xs, "BatchSend")
+	}
+	if caps.Has(CapBatchRecv) {
+		xs = append(xs, "BatchRecv")
+	}
+	if caps.Has(CapDeque) {
+		xs = append(xs, "Deque")
+	}
+	if caps.Has(CapBroadcast) {
+		xs = append(xs, "Broadcast")
+	}
+	if caps.Has(CapLossy) {
+		xs = append(xs, "Lossy")
+	}
+	if caps.Has(CapOverflowFail) {
+		xs = append(xs, "OverflowFail")
+	}
+	if caps.Has(CapOverflowDropNewest) {
+		xs = append(xs, "OverflowDropNewest")
+	}
+	if caps.Has(CapOverflowDropOldest) {
+		xs = append(xs, "OverflowDropOldest")
+	}
+	if caps.Has(CapOverflowHandler) {
+		xs = append(xs, "OverflowHandler")
+	}
+	if caps.Has(CapPriority) {
+		xs = append(xs, "Priority")
+	}
+	if caps.Has(CapPriorityWeighted) {
+		xs = append(xs, "PriorityWeighted")
+	}
+	if caps.Has(CapDelay) {
+		xs = append(xs, "Delay")
+	}
 	return "[" + strings.Join(xs, ", ") + "]"
 }
 
@@ -50,36 +89,102 @@ const (
 	CapNonblockMPSC = CapNonblockSPSC | Capability(1<<iota)
 	CapNonblockSPMC
 	CapBounded = Capability(1 << iota)
-	// BatchReceiver
+	CapClose   = Capability(1 << iota)
+
+	CapBatchSend = Capability(1 << iota)
+	CapBatchRecv = Capability(1 << iota)
+
+	CapDeque     = Capability(1 << iota)
+	CapBroadcast = Capability(1 << iota)
+	CapLossy     = Capability(1 << iota)
+
+	CapOverflowFail       = Capability(1 << iota)
+	CapOverflowDropNewest = Capability(1 << iota)
+	CapOverflowDropOldest = Capability(1 << iota)
+	CapOverflowHandler    = Capability(1 << iota)
+
+	CapPriority         = Capability(1 << iota)
+	CapPriorityWeighted = Capability(1 << iota)
+
+	CapDelay = Capability(1 << iota)
 
 	CapBlockMPMC    = CapBlockMPSC | CapBlockSPMC
 	CapNonblockMPMC = CapNonblockMPSC | CapNonblockSPMC
 
 	CapQueue = CapBlockMPMC | CapNonblockMPMC
+
+	CapOverflow = CapOverflowFail | CapOverflowDropNewest | CapOverflowDropOldest | CapOverflowHandler
 )
 
-func Detect(q Queue) Capability {
+// Detect detects capabilities of a queue with values of type T
+func Detect[T any](q Queue) Capability {
 	var caps Capability
-	if _, ok := q.(SPSC); ok {
+	if _, ok := q.(SPSC[T]); ok {
 		caps.Add(CapBlockSPSC)
 	}
-	if _, ok := q.(MPSC); ok {
+	if _, ok := q.(MPSC[T]); ok {
 		caps.Add(CapBlockMPSC)
 	}
-	if _, ok := q.(SPMC); ok {
+	if _, ok := q.(SPMC[T]); ok {
 		caps.Add(CapBlockSPMC)
 	}
-	if _, ok := q.(NonblockingSPSC); ok {
+	if _, ok := q.(NonblockingSPSC[T]); ok {
 		caps.Add(CapNonblockSPSC)
 	}
-	if _, ok := q.(NonblockingMPSC); ok {
+	if _, ok := q.(NonblockingMPSC[T]); ok {
 		caps.Add(CapNonblockMPSC)
 	}
-	if _, ok := q.(NonblockingSPMC); ok {
+	if _, ok := q.(NonblockingSPMC[T]); ok {
 		caps.Add(CapNonblockSPMC)
 	}
 	if _, ok := q.(Bounded); ok {
 		caps.Add(CapBounded)
 	}
+	if _, ok := q.(Closer); ok {
+		caps.Add(CapClose)
+	}
+	if _, ok := q.(BatchSender[T]); ok {
+		caps.Add(CapBatchSend)
+	}
+	if _, ok := q.(BatchReceiver[T]); ok {
+		caps.Add(CapBatchRecv)
+	}
+	if _, ok := q.(Deque[T]); ok {
+		caps.Add(CapDeque)
+	}
+	if _, ok := q.(Broadcast[T]); ok {
+		caps.Add(CapBroadcast)
+	}
+	if _, ok := q.(Lossy[T]); ok {
+		// lossy queues don't deliver every value,
+		// hence they don't conform to the queue tests
+		caps &^= CapQueue
+		caps.Add(CapLossy)
+	}
+	if q, ok := q.(Overflow); ok {
+		switch q.OverflowPolicy() {
+		case "Fail":
+			caps.Add(CapOverflowFail)
+		case "DropNewest":
+			caps.Add(CapOverflowDropNewest)
+		case "DropOldest":
+			caps.Add(CapOverflowDropOldest)
+		case "Handler":
+			caps.Add(CapOverflowHandler)
+		}
+		if caps.Any(CapOverflow) {
+			// Send doesn't block on a full queue
+			caps &^= CapBlockMPMC
+		}
+	}
+	if _, ok := q.(Priority[T]); ok {
+		caps.Add(CapPriority)
+		if q, ok := q.(WeightedPriority); ok && len(q.Weights()) > 0 {
+			caps.Add(CapPriorityWeighted)
+		}
+	}
+	if _, ok := q.(Delay[T]); ok {
+		caps.Add(CapDelay)
+	}
 	return caps
 }
@@ -11,4 +11,22 @@ func TestCapability(t *testing.T) {
 	if !CapBlockSPSC.Any(CapQueue) {
 		t.Fatal("!CapBlockSPSC.Any(CapQueue)")
 	}
+	if CapDeque.Any(CapQueue) {
+		t.Fatal("CapDeque.Any(CapQueue)")
+	}
+	if CapBroadcast.Any(CapQueue | CapDeque) {
+		t.Fatal("CapBroadcast.Any(CapQueue | CapDeque)")
+	}
+	if CapLossy.Any(CapQueue) {
+		t.Fatal("CapLossy.Any(CapQueue)")
+	}
+	if CapOverflow.Any(CapQueue | CapBounded) {
+		t.Fatal("CapOverflow.Any(CapQueue | CapBounded)")
+	}
+	if (CapPriority | CapPriorityWeighted).Any(CapQueue | CapOverflow) {
+		t.Fatal("(CapPriority | CapPriorityWeighted).Any(CapQueue | CapOverflow)")
+	}
+	if CapDelay.Any(CapQueue | CapPriority | CapPriorityWeighted) {
+		t.Fatal("CapDelay.Any(CapQueue | CapPriority | CapPriorityWeighted)")
+	}
 }
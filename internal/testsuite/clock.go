@@ -0,0 +1,93 @@
+package testsuite
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// FakeClock is a clock for delay queues, which only moves with Advance
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    uint64
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at  time.Time
+	seq uint64
+	f   func()
+}
+
+// NewFakeClock creates a fake clock starting at now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the current fake time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AtFunc calls f once the clock has been advanced to at
+func (c *FakeClock) AtFunc(at time.Time, f func()) (stop func() bool) {
+	c.mu.Lock()
+	if !at.After(c.now) {
+		c.mu.Unlock()
+		f()
+		return func() bool { return false }
+	}
+	defer c.mu.Unlock()
+
+	c.seq++
+	t := &fakeTimer{at: at, seq: c.seq, f: f}
+	c.timers = append(c.timers, t)
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, x := range c.timers {
+			if x == t {
+				c.timers = append(c.timers[:i], c.timers[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Timers returns number of pending timers
+func (c *FakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// Advance moves the clock forward and calls the timers that expired
+// in the order of their deadlines
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	var expired, pending []*fakeTimer
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			expired = append(expired, t)
+		}
+	}
+	c.timers = pending
+	c.mu.Unlock()
+
+	sort.Slice(expired, func(i, k int) bool {
+		if !expired[i].at.Equal(expired[k].at) {
+			return expired[i].at.Before(expired[k].at)
+		}
+		return expired[i].seq < expired[k].seq
+	})
+	for _, t := range expired {
+		t.f()
+	}
+}
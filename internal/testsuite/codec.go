@@ -0,0 +1,71 @@
+package testsuite
+
+import "strconv"
+
+// Codec converts between test values and queue values of type T.
+//
+// Tests generate int64 values, which encode producer id and sequence
+// number, and use Codec to send them through a queue of type T.
+type Codec[T any] struct {
+	// Encode converts a test value to a queue value.
+	Encode func(v int64) T
+	// Decode converts a queue value back to the test value.
+	Decode func(v T) int64
+}
+
+// Int64 sends test values as is.
+var Int64 = Codec[int64]{
+	Encode: func(v int64) int64 { return v },
+	Decode: func(v int64) int64 { return v },
+}
+
+// String sends test values as decimal strings.
+var String = Codec[string]{
+	Encode: func(v int64) string { return strconv.FormatInt(v, 10) },
+	Decode: func(v string) int64 {
+		x, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return -1
+		}
+		return x
+	},
+}
+
+// Pointer sends test values as pointers to a newly allocated value.
+var Pointer = Codec[*int64]{
+	Encode: func(v int64) *int64 { return &v },
+	Decode: func(v *int64) int64 {
+		if v == nil {
+			return -1
+		}
+		return *v
+	},
+}
+
+// Large is a value that spans multiple cachelines.
+//
+// All elements of the payload are equal, which allows to
+// detect partially copied values.
+type Large struct {
+	Payload [16]int64
+}
+
+// LargeStruct sends test values as Large values.
+var LargeStruct = Codec[Large]{
+	Encode: func(v int64) Large {
+		var x Large
+		for i := range x.Payload {
+			x.Payload[i] = v
+		}
+		return x
+	},
+	Decode: func(x Large) int64 {
+		v := x.Payload[0]
+		for _, p := range x.Payload[1:] {
+			if p != v {
+				return -1
+			}
+		}
+		return v
+	},
+}
@@ -0,0 +1,205 @@
+package testsuite
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForTimer waits until a receiver has started waiting on the clock.
+func waitForTimer(t *testing.T, clock *FakeClock) {
+	t.Helper()
+	start := time.Now()
+	for clock.Timers() == 0 {
+		if time.Since(start) > time.Second {
+			t.Fatal("receiver did not start waiting")
+		}
+		runtime.Gosched()
+	}
+}
+
+func testDelay[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
+	t.Run("Order", func(t *testing.T) {
+		q := ctor().(Delay[T])
+		clock := q.FakeClock()
+
+		// values with the same delay must be received in the order they were sent
+		delays := []int{5, 1, 4, 2, 3, 1, 5, 3}
+		for i, delay := range delays {
+			if !q.SendAfter(codec.Encode(int64(i)), time.Duration(delay)*time.Second) {
+				t.Fatalf("failed to send %v", i)
+			}
+		}
+		if !q.SendAt(codec.Encode(100), clock.Now().Add(-time.Second)) {
+			t.Fatal("failed to send to the past")
+		}
+
+		recv := func(exp int64) {
+			t.Helper()
+			var v T
+			if !q.TryRecv(&v) {
+				t.Fatalf("failed to recv %v", exp)
+			}
+			if got := codec.Decode(v); got != exp {
+				t.Fatalf("recv got %v, expected %v", got, exp)
+			}
+		}
+		empty := func() {
+			t.Helper()
+			var v T
+			if q.TryRecv(&v) {
+				t.Fatalf("recv before deadline succeeded, got %v", codec.Decode(v))
+			}
+		}
+
+		recv(100)
+		empty()
+		for step := 1; step <= 5; step++ {
+			clock.Advance(time.Second)
+			for i, delay := range delays {
+				if delay == step {
+					recv(int64(i))
+				}
+			}
+			empty()
+		}
+	})
+
+	t.Run("Blocking", func(t *testing.T) {
+		q := ctor().(Delay[T])
+		clock := q.FakeClock()
+
+		result := make(chan int64, 1)
+		recv := func() {
+			var v T
+			if !q.Recv(&v) {
+				result <- -1
+				return
+			}
+			result <- codec.Decode(v)
+		}
+		check := func(exp int64) {
+			t.Helper()
+			select {
+			case got := <-result:
+				if got != exp {
+					t.Fatalf("recv got %v, expected %v", got, exp)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("recv did not unblock, expected %v", exp)
+			}
+		}
+
+		q.SendAfter(codec.Encode(1), 10*time.Second)
+		go recv()
+		waitForTimer(t, clock)
+
+		// an earlier value must wake up the receiver
+		q.SendAfter(codec.Encode(2), 5*time.Second)
+		clock.Advance(5 * time.Second)
+		check(2)
+
+		go recv()
+		waitForTimer(t, clock)
+		clock.Advance(4 * time.Second)
+		select {
+		case got := <-result:
+			t.Fatalf("recv before deadline got %v", got)
+		case <-time.After(time.Millisecond):
+		}
+		clock.Advance(time.Second)
+		check(1)
+
+		// closing must not drop values that are waiting for their deadline
+		q.SendAfter(codec.Encode(3), time.Second)
+		q.Close()
+		if q.SendAfter(codec.Encode(4), 0) {
+			t.Fatal("send to closed succeeded")
+		}
+		go recv()
+		waitForTimer(t, clock)
+		clock.Advance(time.Second)
+		check(3)
+
+		go recv()
+		check(-1)
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		for _, count := range TestCount {
+			q := ctor().(Delay[T])
+			clock := q.FakeClock()
+			start := clock.Now()
+			deadline := func(i int64) time.Time { return start.Add(time.Duration(i%7) * time.Second) }
+
+			received := NewDelivery(2 * TestProcs)
+
+			var done uint32
+			var advancer sync.WaitGroup
+			advancer.Add(1)
+			go func() {
+				defer advancer.Done()
+				for atomic.LoadUint32(&done) == 0 {
+					clock.Advance(time.Second)
+					time.Sleep(time.Millisecond)
+				}
+			}()
+
+			running := int32(TestProcs)
+			ProducerConsumer(t, TestProcs, TestProcs, func(id int) error {
+				defer func() {
+					if atomic.AddInt32(&running, -1) == 0 {
+						q.Close()
+					}
+				}()
+				for i := 0; i < count; i++ {
+					if !q.SendAt(codec.Encode(int64(id)<<32|int64(i+1)), deadline(int64(i+1))) {
+						return fmt.Errorf("failed to send %v", i)
+					}
+				}
+				return nil
+			}, func(id int) error {
+				for {
+					var v T
+					if !q.Recv(&v) {
+						return nil
+					}
+					now := clock.Now()
+
+					val := codec.Decode(v)
+					received.Add(id, val)
+					if i := val & 0xFFFFFFFF; now.Before(deadline(i)) {
+						return fmt.Errorf("value %v:%v received %v before deadline", val>>32, i, deadline(i).Sub(now))
+					}
+				}
+			})
+
+			atomic.StoreUint32(&done, 1)
+			advancer.Wait()
+
+			if err := received.Check(producerSequences(TestProcs, count)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+func benchDelay[T any](b *testing.B, caps Capability, ctor func() Queue) {
+	b.Run("SendAfter/x100", func(b *testing.B) {
+		q := ctor().(Delay[T])
+		b.ResetTimer()
+		var v T
+		for i := 0; i < b.N; i++ {
+			for i := 0; i < 100; i++ {
+				q.SendAfter(v, time.Duration(i%10))
+			}
+			q.FakeClock().Advance(10)
+			for i := 0; i < 100; i++ {
+				q.TryRecv(&v)
+			}
+		}
+	})
+}
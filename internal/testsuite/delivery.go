@@ -0,0 +1,100 @@
+package testsuite
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Delivery collects values received by concurrent goroutines and
+// verifies that every sent value was received exactly once.
+//
+// Each goroutine must use a separate id, which allows collecting
+// without additional synchronization between goroutines.
+type Delivery struct {
+	received [][]int64
+}
+
+// NewDelivery creates a delivery for goroutines with ids less than procs.
+func NewDelivery(procs int) *Delivery {
+	return &Delivery{received: make([][]int64, procs)}
+}
+
+// Add adds a value received by goroutine id.
+func (d *Delivery) Add(id int, v int64) { d.received[id] = append(d.received[id], v) }
+
+// Check verifies that the received values are the same multiset as sent,
+// otherwise it reports which values were lost, duplicated or unexpected.
+func (d *Delivery) Check(sent []int64) error {
+	expected := make(map[int64]int, len(sent))
+	for _, v := range sent {
+		expected[v]++
+	}
+	counts := make(map[int64]int, len(sent))
+	for _, received := range d.received {
+		for _, v := range received {
+			counts[v]++
+		}
+	}
+
+	var lost, duplicated, unexpected []int64
+	for v, exp := range expected {
+		if counts[v] < exp {
+			lost = append(lost, v)
+		}
+	}
+	for v, count := range counts {
+		exp, ok := expected[v]
+		switch {
+		case !ok:
+			unexpected = append(unexpected, v)
+		case count > exp:
+			duplicated = append(duplicated, v)
+		}
+	}
+	if len(lost) == 0 && len(duplicated) == 0 && len(unexpected) == 0 {
+		return nil
+	}
+
+	var problems []string
+	if len(lost) > 0 {
+		problems = append(problems, "lost "+formatValues(lost))
+	}
+	if len(duplicated) > 0 {
+		problems = append(problems, "duplicated "+formatValues(duplicated))
+	}
+	if len(unexpected) > 0 {
+		problems = append(problems, "unexpected "+formatValues(unexpected))
+	}
+	return fmt.Errorf("delivery of %d values: %s", len(sent), strings.Join(problems, ", "))
+}
+
+// formatValues formats the first few values in ascending order.
+func formatValues(values []int64) string {
+	const limit = 8
+	sort.Slice(values, func(i, k int) bool { return values[i] < values[k] })
+	if len(values) > limit {
+		return fmt.Sprintf("%d values %v...", len(values), values[:limit])
+	}
+	return fmt.Sprintf("%d values %v", len(values), values)
+}
+
+// sequence returns values 1..n sent by a single producer.
+func sequence(n int) []int64 {
+	values := make([]int64, n)
+	for i := range values {
+		values[i] = int64(i + 1)
+	}
+	return values
+}
+
+// producerSequences returns values id<<32 | 1..n sent by each producer.
+func producerSequences(producers, n int) []int64 {
+	values := make([]int64, 0, producers*n)
+	for id := 0; id < producers; id++ {
+		for i := 0; i < n; i++ {
+			values = append(values, int64(id)<<32|int64(i+1))
+		}
+	}
+	return values
+}
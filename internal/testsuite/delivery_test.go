@@ -0,0 +1,30 @@
+package testsuite
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDelivery(t *testing.T) {
+	d := NewDelivery(2)
+	d.Add(0, 1)
+	d.Add(1, 2)
+	d.Add(0, 3)
+	if err := d.Check([]int64{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	d = NewDelivery(2)
+	d.Add(0, 1)
+	d.Add(1, 1)
+	d.Add(1, 4)
+	err := d.Check([]int64{1, 2, 3})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, expected := range []string{"lost 2 values [2 3]", "duplicated 1 values [1]", "unexpected 1 values [4]"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q does not contain %q", err, expected)
+		}
+	}
+}
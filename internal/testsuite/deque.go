@@ -0,0 +1,183 @@
+package testsuite
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func testDeque[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
+	t.Run("Owner", func(t *testing.T) {
+		for _, count := range TestCount {
+			q := ctor().(Deque[T])
+
+			var v T
+			if q.PopBottom(&v) {
+				t.Fatal("pop from empty succeeded")
+			}
+			if q.Steal(&v) {
+				t.Fatal("steal from empty succeeded")
+			}
+
+			for i := 0; i < count; i++ {
+				q.PushBottom(codec.Encode(int64(i)))
+			}
+			for i := count - 1; i >= 0; i-- {
+				if !q.PopBottom(&v) {
+					t.Fatalf("failed to pop %v", i)
+				}
+				if got := codec.Decode(v); got != int64(i) {
+					t.Fatalf("pop got %v, expected %v", got, i)
+				}
+			}
+			if q.PopBottom(&v) {
+				t.Fatal("pop from drained succeeded")
+			}
+
+			for i := 0; i < count; i++ {
+				q.PushBottom(codec.Encode(int64(i)))
+			}
+			for i := 0; i < count; i++ {
+				if !q.Steal(&v) {
+					t.Fatalf("failed to steal %v", i)
+				}
+				if got := codec.Decode(v); got != int64(i) {
+					t.Fatalf("steal got %v, expected %v", got, i)
+				}
+			}
+			if q.Steal(&v) {
+				t.Fatal("steal from drained succeeded")
+			}
+		}
+	})
+
+	t.Run("Steal", func(t *testing.T) {
+		for _, count := range TestCount {
+			q := ctor().(Deque[T])
+
+			taken := make([]int32, count)
+			take := func(v T) error {
+				got := codec.Decode(v)
+				if got < 0 || got >= int64(count) {
+					return fmt.Errorf("invalid value %v", got)
+				}
+				if atomic.AddInt32(&taken[got], 1) != 1 {
+					return fmt.Errorf("value %v taken multiple times", got)
+				}
+				return nil
+			}
+
+			var done uint32
+			ProducerConsumer(t, 1, TestProcs, func(int) error {
+				defer atomic.StoreUint32(&done, 1)
+				for i := 0; i < count; i++ {
+					q.PushBottom(codec.Encode(int64(i)))
+					if i%3 == 2 {
+						var v T
+						if q.PopBottom(&v) {
+							if err := take(v); err != nil {
+								return err
+							}
+						}
+					}
+				}
+				for {
+					var v T
+					if !q.PopBottom(&v) {
+						return nil
+					}
+					if err := take(v); err != nil {
+						return err
+					}
+				}
+			}, func(int) error {
+				// values are pushed in increasing order,
+				// hence the top of the deque only increases
+				last := int64(-1)
+				for {
+					var v T
+					if !q.Steal(&v) {
+						if atomic.LoadUint32(&done) != 0 {
+							return nil
+						}
+						runtime.Gosched()
+						continue
+					}
+					if err := take(v); err != nil {
+						return err
+					}
+					got := codec.Decode(v)
+					if got <= last {
+						return fmt.Errorf("invalid order got %v, expected at least %v", got, last+1)
+					}
+					last = got
+				}
+			})
+
+			for i, n := range taken {
+				if n != 1 {
+					t.Fatalf("value %v taken %v times", i, n)
+				}
+			}
+		}
+	})
+}
+
+func benchDeque[T any](b *testing.B, caps Capability, ctor func() Queue) {
+	b.Run("Owner/x100", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			q := ctor().(Deque[T])
+			for pb.Next() {
+				var v T
+				for i := 0; i < 100; i++ {
+					q.PushBottom(v)
+				}
+				for i := 0; i < 100; i++ {
+					q.PopBottom(&v)
+				}
+			}
+		})
+	})
+
+	b.Run("Stealing/x100", func(b *testing.B) {
+		q := ctor().(Deque[T])
+		b.ResetTimer()
+
+		var done uint32
+		var wg sync.WaitGroup
+		thieves := runtime.GOMAXPROCS(0)
+		wg.Add(thieves)
+		for p := 0; p < thieves; p++ {
+			go func() {
+				defer wg.Done()
+				for atomic.LoadUint32(&done) == 0 {
+					var v T
+					if !q.Steal(&v) {
+						runtime.Gosched()
+					}
+				}
+			}()
+		}
+
+		for i := 0; i < b.N; i++ {
+			var v T
+			for i := 0; i < 100; i++ {
+				q.PushBottom(v)
+			}
+			for i := 0; i < 50; i++ {
+				q.PopBottom(&v)
+			}
+		}
+		for {
+			var v T
+			if !q.PopBottom(&v) {
+				break
+			}
+		}
+
+		atomic.StoreUint32(&done, 1)
+		wg.Wait()
+	})
+}
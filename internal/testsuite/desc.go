@@ -6,19 +6,19 @@ import (
 )
 
 // Descs is a list of Queue implementation descriptions
-type Descs []*Desc
+type Descs[T any] []*Desc[T]
 
-// Desc describes a Queue implementation
-type Desc struct {
+// Desc describes a Queue implementation with values of type T
+type Desc[T any] struct {
 	Name   string
 	Param  CreateParam
 	Create func(batchSize int, size int) Queue
 }
 
-func (desc *Desc) HasSizeParam() bool {
+func (desc *Desc[T]) HasSizeParam() bool {
 	return desc.Param == ParamSize || desc.Param == ParamBatchSizeAndSize
 }
-func (desc *Desc) HasBatchSizeParam() bool {
+func (desc *Desc[T]) HasBatchSizeParam() bool {
 	return desc.Param == ParamBatchSize || desc.Param == ParamBatchSizeAndSize
 }
 
@@ -31,21 +31,38 @@ const (
 	ParamBatchSizeAndSize
 )
 
-func (descs *Descs) Append(list ...*Desc) {
+func (descs *Descs[T]) Append(list ...*Desc[T]) {
 	*descs = append(*descs, list...)
 }
 
-func (descs Descs) TestDefault(t *testing.T) {
+// Select returns the descriptions with the specified names.
+func (descs Descs[T]) Select(names ...string) Descs[T] {
+	var selected Descs[T]
+	for _, desc := range descs {
+		for _, name := range names {
+			if desc.Name == name {
+				selected = append(selected, desc)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+func (descs Descs[T]) TestDefault(t *testing.T, codec Codec[T]) {
 	t.Helper()
-	descs.Test(t, Tests)
+	descs.Test(t, func(t *testing.T, create func() Queue) {
+		t.Helper()
+		Tests(t, codec, create)
+	})
 }
 
-func (descs Descs) BenchmarkDefault(b *testing.B) {
+func (descs Descs[T]) BenchmarkDefault(b *testing.B) {
 	b.Helper()
-	descs.Benchmark(b, Benchmarks)
+	descs.Benchmark(b, Benchmarks[T])
 }
 
-func (descs Descs) Test(t *testing.T, test func(t *testing.T, create func() Queue)) {
+func (descs Descs[T]) Test(t *testing.T, test func(t *testing.T, create func() Queue)) {
 	t.Helper()
 	for _, desc := range descs {
 		batchSizes := BatchSizes
@@ -79,7 +96,7 @@ func (descs Descs) Test(t *testing.T, test func(t *testing.T, create func() Queu
 	}
 }
 
-func (descs Descs) Benchmark(b *testing.B, bench func(b *testing.B, create func() Queue)) {
+func (descs Descs[T]) Benchmark(b *testing.B, bench func(b *testing.B, create func() Queue)) {
 	b.Helper()
 	for _, desc := range descs {
 		batchSizes := BenchBatchSizes
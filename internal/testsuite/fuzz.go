@@ -0,0 +1,390 @@
+package testsuite
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// FuzzProcs is the maximum number of goroutines in fuzz tests
+var FuzzProcs = 4
+
+// FuzzOps is the maximum number of operations in fuzz tests
+var FuzzOps = 256
+
+// FuzzTimeout is how long a blocking operation waits in fuzz tests,
+// when it could otherwise wait forever
+var FuzzTimeout = time.Millisecond
+
+// fuzzKind is an operation in a fuzz program.
+type fuzzKind byte
+
+const (
+	fuzzSend = fuzzKind(iota)
+	fuzzTrySend
+	fuzzRecv
+	fuzzTryRecv
+	fuzzFlushSend
+	fuzzFlushRecv
+	fuzzClose
+
+	fuzzKinds = iota
+)
+
+func (kind fuzzKind) String() string {
+	switch kind {
+	case fuzzSend:
+		return "Send"
+	case fuzzTrySend:
+		return "TrySend"
+	case fuzzRecv:
+		return "Recv"
+	case fuzzTryRecv:
+		return "TryRecv"
+	case fuzzFlushSend:
+		return "FlushSend"
+	case fuzzFlushRecv:
+		return "FlushRecv"
+	case fuzzClose:
+		return "Close"
+	default:
+		return "fuzzKind(" + fmt.Sprint(int(kind)) + ")"
+	}
+}
+
+// fuzzOp is a single operation of proc.
+type fuzzOp struct {
+	kind fuzzKind
+	proc int
+}
+
+// encodeFuzzOp encodes an operation the way decodeFuzzProgram expects.
+func encodeFuzzOp(kind fuzzKind, proc int) byte { return byte(proc*fuzzKinds + int(kind)) }
+
+// fuzzProgram is a sequence of operations decoded from the fuzzer input.
+type fuzzProgram struct {
+	batchSize int
+	size      int
+	procs     int
+	ops       []fuzzOp
+}
+
+func (p *fuzzProgram) String() string {
+	xs := make([]string, len(p.ops))
+	for i, op := range p.ops {
+		xs[i] = fmt.Sprintf("p%d %v", op.proc, op.kind)
+	}
+	return fmt.Sprintf("b%ds%d procs %d: %s", p.batchSize, p.size, p.procs, strings.Join(xs, ", "))
+}
+
+// decodeFuzzProgram decodes the fuzzer input:
+//
+//	data[0]: index of the queue description
+//	data[1]: batch size and size
+//	data[2]: number of goroutines
+//	data[3:]: operations and the goroutines executing them
+//
+// Operations are assigned to goroutines respecting the supported number
+// of producers and consumers and only the first Close is kept.
+// Queues with FlushSend are closed by a producer after flushing,
+// because pending sends must be flushed before closing.
+func decodeFuzzProgram[T any](desc *Desc[T], data []byte) (*fuzzProgram, Queue) {
+	p := &fuzzProgram{}
+	if desc.HasBatchSizeParam() {
+		p.batchSize = BatchSizes[int(data[1]>>4)%len(BatchSizes)]
+	}
+	if desc.HasSizeParam() {
+		var sizes []int
+		for _, size := range TestSizes {
+			if size > p.batchSize {
+				sizes = append(sizes, size)
+			}
+		}
+		p.size = sizes[int(data[1]&0xF)%len(sizes)]
+	}
+	p.procs = 1 + int(data[2])%FuzzProcs
+
+	q := desc.Create(p.batchSize, p.size)
+
+	_, flusher := q.(Flusher)
+	_, multipleProducers := q.(interface{ MultipleProducers() })
+	_, multipleConsumers := q.(interface{ MultipleConsumers() })
+	producer, consumer := 0, p.procs-1
+
+	closed := false
+	for _, b := range data[3:] {
+		if len(p.ops) >= FuzzOps {
+			break
+		}
+		op := fuzzOp{
+			kind: fuzzKind(int(b) % fuzzKinds),
+			proc: int(b) / fuzzKinds % p.procs,
+		}
+		switch op.kind {
+		case fuzzSend, fuzzTrySend, fuzzFlushSend:
+			if !multipleProducers {
+				op.proc = producer
+			}
+		case fuzzRecv, fuzzTryRecv, fuzzFlushRecv:
+			if !multipleConsumers {
+				op.proc = consumer
+			}
+		case fuzzClose:
+			if closed {
+				continue
+			}
+			closed = true
+			if flusher {
+				if !multipleProducers {
+					op.proc = producer
+				}
+				p.ops = append(p.ops, fuzzOp{kind: fuzzFlushSend, proc: op.proc})
+			}
+		}
+		p.ops = append(p.ops, op)
+	}
+	return p, q
+}
+
+// Fuzz runs fuzz programs against the queues.
+//
+// The fuzzer input selects the queue and decodes to a program of operations
+// executed by several goroutines. The recorded operations are compared to
+// a sequential FIFO queue with CheckFIFO.
+//
+// Sends to a queue with FlushSend complete only when flushed, because
+// the value might not be visible to the consumer before that.
+//
+// Blocking operations that could wait forever use the timeout variants,
+// with a single goroutine the sequential model decides whether they could.
+func (descs Descs[T]) Fuzz(f *testing.F, codec Codec[T]) {
+	for i := range descs {
+		f.Add([]byte{byte(i), 0x00, 0,
+			encodeFuzzOp(fuzzSend, 0), encodeFuzzOp(fuzzTrySend, 0), encodeFuzzOp(fuzzFlushSend, 0),
+			encodeFuzzOp(fuzzRecv, 0), encodeFuzzOp(fuzzTryRecv, 0), encodeFuzzOp(fuzzTryRecv, 0),
+			encodeFuzzOp(fuzzSend, 0), encodeFuzzOp(fuzzSend, 0), encodeFuzzOp(fuzzFlushSend, 0),
+			encodeFuzzOp(fuzzClose, 0), encodeFuzzOp(fuzzSend, 0),
+			encodeFuzzOp(fuzzRecv, 0), encodeFuzzOp(fuzzRecv, 0), encodeFuzzOp(fuzzRecv, 0),
+		})
+		f.Add([]byte{byte(i), 0x11, 3,
+			encodeFuzzOp(fuzzSend, 0), encodeFuzzOp(fuzzSend, 1), encodeFuzzOp(fuzzRecv, 2), encodeFuzzOp(fuzzRecv, 3),
+			encodeFuzzOp(fuzzTrySend, 0), encodeFuzzOp(fuzzTrySend, 1), encodeFuzzOp(fuzzTryRecv, 2), encodeFuzzOp(fuzzTryRecv, 3),
+			encodeFuzzOp(fuzzFlushSend, 0), encodeFuzzOp(fuzzFlushSend, 1), encodeFuzzOp(fuzzFlushRecv, 2), encodeFuzzOp(fuzzFlushRecv, 3),
+			encodeFuzzOp(fuzzSend, 0), encodeFuzzOp(fuzzRecv, 2), encodeFuzzOp(fuzzClose, 1), encodeFuzzOp(fuzzRecv, 3),
+		})
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) < 3 {
+			return
+		}
+		desc := descs[int(data[0])%len(descs)]
+		p, q := decodeFuzzProgram(desc, data)
+		caps := Detect[T](q)
+		if !caps.Any(CapQueue) {
+			t.Skip("not a FIFO queue")
+		}
+
+		if err := runFuzzProgram(q, caps, codec, p); err != nil {
+			t.Fatalf("%v: %v\n%v", desc.Name, err, p)
+		}
+	})
+}
+
+// fuzzModel tracks the state of a sequential queue,
+// which is only exact when a single goroutine runs the program.
+type fuzzModel struct {
+	capacity int
+	flusher  bool
+
+	closed  bool
+	len     int
+	pending int // sent, but not flushed
+}
+
+// canRecv returns whether Recv cannot wait forever.
+func (m *fuzzModel) canRecv() bool { return m.closed || m.len-m.pending > 0 }
+
+// canSend returns whether Send cannot wait forever.
+func (m *fuzzModel) canSend() bool { return m.closed || !m.flusher && m.len < m.capacity }
+
+// mustSend returns whether a send must succeed.
+func (m *fuzzModel) mustSend() bool { return !m.closed && !m.flusher && m.len < m.capacity }
+
+func (m *fuzzModel) sent() {
+	m.len++
+	if m.flusher {
+		m.pending++
+	}
+}
+
+func (m *fuzzModel) received() {
+	m.len--
+	if m.pending > m.len {
+		m.pending = m.len
+	}
+}
+
+// runFuzzProgram runs p against q and checks the results.
+func runFuzzProgram[T any](q Queue, caps Capability, codec Codec[T], p *fuzzProgram) error {
+	_, flusher := q.(Flusher)
+	block := caps.Has(CapBlockSPSC)
+	nonblock := caps.Has(CapNonblockSPSC)
+
+	var model *fuzzModel
+	if p.procs == 1 {
+		model = &fuzzModel{capacity: Cap(q), flusher: flusher}
+	}
+
+	h := NewHistory(p.procs)
+	// closedAt is the time after Close returned, 0 when not closed
+	var closedAt int64
+
+	procs := make([][]fuzzOp, p.procs)
+	for _, op := range p.ops {
+		procs[op.proc] = append(procs[op.proc], op)
+	}
+
+	var mu sync.Mutex
+	var errs []error
+	fail := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	recorded := make([][]Op, p.procs)
+	pending := make([][]int, p.procs)
+	for proc, ops := range procs {
+		proc, ops := proc, ops
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var history []Op
+			// unflushed are indices of sends that haven't been flushed
+			var unflushed []int
+			seq := int64(0)
+
+			send := func(kind fuzzKind) {
+				seq++
+				v := int64(proc)<<32 | seq
+				call := h.Now()
+				var ok bool
+				switch {
+				case kind == fuzzTrySend && nonblock || !block:
+					ok = q.(NonblockingSPSC[T]).TrySend(codec.Encode(v))
+				case model == nil || !model.canSend():
+					ok = q.(SPSC[T]).SendTimeout(codec.Encode(v), FuzzTimeout)
+				default:
+					ok = q.(SPSC[T]).Send(codec.Encode(v))
+				}
+				op := Op{Proc: proc, Kind: OpSend, Value: v, Ok: ok, Call: call, Return: h.Now()}
+
+				if ok {
+					if closed := atomic.LoadInt64(&closedAt); closed != 0 && closed < call {
+						fail(fmt.Errorf("%v succeeded after close", op))
+					}
+				}
+				if model != nil {
+					switch {
+					case ok:
+						model.sent()
+					case model.mustSend():
+						fail(fmt.Errorf("%v failed with %d of %d values in the queue", op, model.len, model.capacity))
+					}
+				}
+
+				if !ok {
+					op.Value = 0
+				} else if flusher {
+					unflushed = append(unflushed, len(history))
+				}
+				history = append(history, op)
+			}
+
+			recv := func(kind fuzzKind) {
+				call := h.Now()
+				var x T
+				var ok bool
+				switch {
+				case kind == fuzzTryRecv && nonblock || !block:
+					ok = q.(NonblockingSPSC[T]).TryRecv(&x)
+				case model == nil || !model.canRecv():
+					ok = q.(SPSC[T]).RecvTimeout(&x, FuzzTimeout)
+				default:
+					ok = q.(SPSC[T]).Recv(&x)
+				}
+				op := Op{Proc: proc, Kind: OpRecv, Ok: ok, Call: call, Return: h.Now()}
+				if ok {
+					op.Value = codec.Decode(x)
+					if model != nil {
+						model.received()
+					}
+				}
+				history = append(history, op)
+			}
+
+			for _, op := range ops {
+				Jitter()
+				switch op.kind {
+				case fuzzSend, fuzzTrySend:
+					send(op.kind)
+				case fuzzRecv, fuzzTryRecv:
+					recv(op.kind)
+				case fuzzFlushSend:
+					FlushSend(q)
+					now := h.Now()
+					for _, i := range unflushed {
+						history[i].Return = now
+					}
+					unflushed = unflushed[:0]
+					if model != nil {
+						model.pending = 0
+					}
+				case fuzzFlushRecv:
+					FlushRecv(q)
+				case fuzzClose:
+					if closer, ok := q.(Closer); ok {
+						closer.Close()
+						atomic.StoreInt64(&closedAt, h.Now())
+						if model != nil {
+							model.closed = true
+						}
+					}
+				}
+			}
+
+			recorded[proc], pending[proc] = history, unflushed
+		}()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(8*nonblockThreshold() + time.Duration(len(p.ops))*FuzzTimeout):
+		return fmt.Errorf("operations didn't complete")
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	// unflushed values might become visible any time later
+	end := h.Now()
+	for proc, ops := range recorded {
+		for _, i := range pending[proc] {
+			ops[i].Return = end
+		}
+		for _, op := range ops {
+			h.Add(op)
+		}
+	}
+	return CheckFIFO(h.Ops())
+}
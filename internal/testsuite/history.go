@@ -0,0 +1,145 @@
+package testsuite
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+)
+
+// OpKind is the kind of a recorded queue operation.
+type OpKind byte
+
+const (
+	// OpSend is Send or TrySend.
+	OpSend = OpKind(iota)
+	// OpRecv is Recv or TryRecv.
+	OpRecv
+)
+
+func (kind OpKind) String() string {
+	switch kind {
+	case OpSend:
+		return "send"
+	case OpRecv:
+		return "recv"
+	default:
+		return "OpKind(" + fmt.Sprint(int(kind)) + ")"
+	}
+}
+
+// Op is a single completed queue operation.
+type Op struct {
+	Proc  int
+	Kind  OpKind
+	Value int64
+	Ok    bool
+
+	// Call and Return are logical timestamps of invocation and response.
+	Call, Return int64
+}
+
+func (op Op) String() string {
+	if !op.Ok {
+		return fmt.Sprintf("p%d %v failed [%d,%d]", op.Proc, op.Kind, op.Call, op.Return)
+	}
+	return fmt.Sprintf("p%d %v %d [%d,%d]", op.Proc, op.Kind, op.Value, op.Call, op.Return)
+}
+
+// History records operations of concurrent goroutines on a single queue.
+//
+// Each goroutine must use a separate proc, which allows recording
+// without additional synchronization between goroutines.
+type History struct {
+	clock int64
+	procs [][]Op
+}
+
+// NewHistory creates a history for the specified number of goroutines.
+func NewHistory(procs int) *History {
+	return &History{procs: make([][]Op, procs)}
+}
+
+// Now returns the next logical timestamp.
+//
+// Timestamps are totally ordered and consistent with real-time order.
+func (h *History) Now() int64 { return atomic.AddInt64(&h.clock, 1) }
+
+// Add adds a completed operation to the history of op.Proc.
+func (h *History) Add(op Op) { h.procs[op.Proc] = append(h.procs[op.Proc], op) }
+
+// Ops returns all operations sorted by invocation time.
+func (h *History) Ops() []Op {
+	var ops []Op
+	for _, proc := range h.procs {
+		ops = append(ops, proc...)
+	}
+	sort.Slice(ops, func(i, k int) bool { return ops[i].Call < ops[k].Call })
+	return ops
+}
+
+// Recorder records operations of a single goroutine on a queue.
+type Recorder[T any] struct {
+	History *History
+	Proc    int
+	Queue   Queue
+	Codec   Codec[T]
+}
+
+// Record creates a recorder for proc.
+func Record[T any](h *History, proc int, q Queue, codec Codec[T]) *Recorder[T] {
+	return &Recorder[T]{History: h, Proc: proc, Queue: q, Codec: codec}
+}
+
+// Send records q.Send.
+func (r *Recorder[T]) Send(v int64) bool {
+	call := r.History.Now()
+	ok := r.Queue.(SPSC[T]).Send(r.Codec.Encode(v))
+	FlushSend(r.Queue)
+	r.add(OpSend, v, ok, call)
+	return ok
+}
+
+// TrySend records q.TrySend.
+func (r *Recorder[T]) TrySend(v int64) bool {
+	call := r.History.Now()
+	ok := r.Queue.(NonblockingSPSC[T]).TrySend(r.Codec.Encode(v))
+	FlushSend(r.Queue)
+	r.add(OpSend, v, ok, call)
+	return ok
+}
+
+// Recv records q.Recv.
+func (r *Recorder[T]) Recv() (int64, bool) {
+	call := r.History.Now()
+	var x T
+	ok := r.Queue.(SPSC[T]).Recv(&x)
+	FlushRecv(r.Queue)
+	v := r.Codec.Decode(x)
+	r.add(OpRecv, v, ok, call)
+	return v, ok
+}
+
+// TryRecv records q.TryRecv.
+func (r *Recorder[T]) TryRecv() (int64, bool) {
+	call := r.History.Now()
+	var x T
+	ok := r.Queue.(NonblockingSPSC[T]).TryRecv(&x)
+	FlushRecv(r.Queue)
+	v := r.Codec.Decode(x)
+	r.add(OpRecv, v, ok, call)
+	return v, ok
+}
+
+func (r *Recorder[T]) add(kind OpKind, v int64, ok bool, call int64) {
+	if !ok {
+		v = 0
+	}
+	r.History.Add(Op{
+		Proc:   r.Proc,
+		Kind:   kind,
+		Value:  v,
+		Ok:     ok,
+		Call:   call,
+		Return: r.History.Now(),
+	})
+}
@@ -0,0 +1,386 @@
+package testsuite
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// CheckFIFO verifies that ops are consistent with a sequential FIFO queue.
+//
+// Sent values must be unique. Failed sends are ignored, because a queue
+// may refuse a value when it is full or closed. A failed receive must
+// observe an empty queue.
+//
+// The check is weaker than linearizability. In bounded queues, such as
+// Vyukov's, a stalled send blocks receiving values from later sends until
+// it completes, so a receive may fail although the queue isn't empty.
+// Hence a failed receive is ignored when the queue wasn't empty, but
+// a send that started before the values in the queue were sent is still
+// in progress.
+//
+// The history is checked by looking for the violations described in
+// "Aspect-Oriented Linearizability Proofs" by Henzinger et al.
+// and failed receives while the queue wasn't empty, which takes
+// polynomial time. The remaining failed receives are checked with
+// a Wing & Gong search, which gives up after caching SearchLimit bytes.
+func CheckFIFO(ops []Op) error {
+	ops = filterFIFO(ops)
+
+	sends := map[int64]Op{}
+	recvs := map[int64]Op{}
+	var empty []Op
+	for _, op := range ops {
+		switch {
+		case op.Kind == OpSend:
+			if _, dup := sends[op.Value]; dup {
+				return fmt.Errorf("value %d sent multiple times", op.Value)
+			}
+			sends[op.Value] = op
+		case op.Ok:
+			if prev, dup := recvs[op.Value]; dup {
+				return fmt.Errorf("not linearizable: %v and %v received the same value", prev, op)
+			}
+			recvs[op.Value] = op
+		default:
+			empty = append(empty, op)
+		}
+	}
+
+	for v, recv := range recvs {
+		send, ok := sends[v]
+		if !ok {
+			return fmt.Errorf("not linearizable: %v received a value that was never sent", recv)
+		}
+		if recv.Return < send.Call {
+			return fmt.Errorf("not linearizable: %v completed before %v", recv, send)
+		}
+	}
+
+	for b, recvb := range recvs {
+		sendb := sends[b]
+		for a, senda := range sends {
+			if senda.Return >= sendb.Call {
+				continue
+			}
+			// a was sent before b, so it must be received before b
+			recva, ok := recvs[a]
+			if !ok {
+				return fmt.Errorf("not linearizable: %v received, but earlier %v was lost", recvb, senda)
+			}
+			if recvb.Return < recva.Call {
+				return fmt.Errorf("not linearizable: %v completed before %v", recvb, recva)
+			}
+		}
+	}
+
+	if len(empty) == 0 {
+		return nil
+	}
+
+	var sent []Op
+	for _, op := range sends {
+		sent = append(sent, op)
+	}
+	occupied := newOccupancy(sent, recvs)
+	for _, op := range empty {
+		if occupied.covers(op) {
+			return fmt.Errorf("not linearizable: %v failed, but the queue wasn't empty", op)
+		}
+	}
+	return searchFIFO(ops, SearchLimit)
+}
+
+// SearchLimit is the number of bytes CheckFIFO caches for visited states
+// before it gives up searching for a linearization.
+var SearchLimit = 64 << 20
+
+// filterFIFO removes failed sends and failed receives behind a stalled send.
+func filterFIFO(ops []Op) []Op {
+	var sends []Op
+	recvs := map[int64]Op{}
+	for _, op := range ops {
+		switch {
+		case op.Kind == OpSend && op.Ok:
+			sends = append(sends, op)
+		case op.Kind == OpRecv && op.Ok:
+			recvs[op.Value] = op
+		}
+	}
+	occupied := newOccupancy(sends, recvs)
+
+	valid := make([]Op, 0, len(ops))
+	for _, op := range ops {
+		switch {
+		case op.Kind == OpSend && !op.Ok:
+			continue
+		case op.Kind == OpRecv && !op.Ok && occupied.covers(op) && behindStalledSend(op, sends, recvs):
+			continue
+		}
+		valid = append(valid, op)
+	}
+	return valid
+}
+
+// occupancy contains the times when some value was in the queue
+// as sorted and disjoint open intervals.
+//
+// A value is in the queue after its send completed and
+// before its receive started.
+type occupancy struct {
+	starts, ends []int64
+}
+
+func newOccupancy(sends []Op, recvs map[int64]Op) occupancy {
+	type interval struct{ start, end int64 }
+	var intervals []interval
+	for _, send := range sends {
+		if end := queuedUntil(send, recvs); end > send.Return {
+			intervals = append(intervals, interval{send.Return, end})
+		}
+	}
+	sort.Slice(intervals, func(i, k int) bool { return intervals[i].start < intervals[k].start })
+
+	var o occupancy
+	for _, x := range intervals {
+		if n := len(o.ends); n > 0 && x.start < o.ends[n-1] {
+			if x.end > o.ends[n-1] {
+				o.ends[n-1] = x.end
+			}
+			continue
+		}
+		o.starts = append(o.starts, x.start)
+		o.ends = append(o.ends, x.end)
+	}
+	return o
+}
+
+// covers returns whether some value was in the queue during all of op.
+func (o occupancy) covers(op Op) bool {
+	i := sort.Search(len(o.starts), func(i int) bool { return o.starts[i] >= op.Call })
+	return i > 0 && o.ends[i-1] > op.Return
+}
+
+// queuedUntil returns when the value of send stopped being in the queue.
+func queuedUntil(send Op, recvs map[int64]Op) int64 {
+	if recv, ok := recvs[send.Value]; ok {
+		return recv.Call
+	}
+	return math.MaxInt64
+}
+
+// behindStalledSend returns whether the failed receive op may have been
+// blocked by a send in progress, although the queue wasn't empty.
+//
+// The stalled send must have started before a send of a value in
+// the queue during op completed and must be in progress when op starts.
+func behindStalledSend(op Op, sends []Op, recvs map[int64]Op) bool {
+	// the two values in the queue during op, which were sent last
+	var last, prev *Op
+	for i := range sends {
+		send := &sends[i]
+		if send.Return >= op.Return || queuedUntil(*send, recvs) <= op.Call {
+			continue
+		}
+		switch {
+		case last == nil || send.Return > last.Return:
+			last, prev = send, last
+		case prev == nil || send.Return > prev.Return:
+			prev = send
+		}
+	}
+
+	for i := range sends {
+		send := &sends[i]
+		if send.Return <= op.Call {
+			continue
+		}
+		witness := last
+		if witness == send {
+			witness = prev
+		}
+		if witness != nil && send.Call < witness.Return {
+			return true
+		}
+	}
+	return false
+}
+
+// searchFIFO verifies that ops are linearizable with respect to
+// a sequential FIFO queue, sent values must be unique and failed sends
+// are ignored.
+//
+// The search is based on the Wing & Gong algorithm with the improvements
+// by Lowe, i.e. operations are linearized only when their invocation
+// is reached and visited (linearized set, queue content) pairs are cached.
+//
+// The search gives up without an error after caching limit bytes,
+// 0 means no limit.
+func searchFIFO(ops []Op, limit int) error {
+	if len(ops) == 0 {
+		return nil
+	}
+
+	sends := 0
+	for _, op := range ops {
+		if op.Kind == OpSend {
+			sends++
+		}
+	}
+
+	head := buildEntries(ops)
+	model := newFIFOModel(sends)
+
+	linearized := make([]byte, (len(ops)+7)/8)
+	var stack []*linEntry
+	cache := map[string]struct{}{}
+	cached := 0
+
+	longest, stuck := 0, -1
+	entry := head.next
+	for head.next != nil {
+		if entry.call {
+			op := &ops[entry.op]
+			if model.step(op) {
+				linearized[entry.op/8] ^= 1 << (entry.op % 8)
+				key := model.key(linearized)
+				if _, seen := cache[key]; !seen {
+					cached += len(key)
+					if limit > 0 && cached > limit {
+						return nil
+					}
+					cache[key] = struct{}{}
+					stack = append(stack, entry)
+					entry.lift()
+					entry = head.next
+					continue
+				}
+				linearized[entry.op/8] ^= 1 << (entry.op % 8)
+				model.undo(op)
+			}
+			entry = entry.next
+			continue
+		}
+
+		// reached the response of an operation that cannot be linearized
+		if len(stack) >= longest {
+			longest, stuck = len(stack), entry.op
+		}
+		if len(stack) == 0 {
+			return fmt.Errorf("not linearizable: %v cannot be linearized after %d operations", ops[stuck], longest)
+		}
+
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		linearized[top.op/8] ^= 1 << (top.op % 8)
+		model.undo(&ops[top.op])
+		top.unlift()
+		entry = top.next
+	}
+
+	return nil
+}
+
+// linEntry is an invocation or a response in a doubly linked list.
+type linEntry struct {
+	op         int
+	call       bool
+	time       int64
+	match      *linEntry // response for an invocation
+	prev, next *linEntry
+}
+
+// buildEntries creates a list of invocations and responses ordered by time,
+// the returned entry is a sentinel.
+func buildEntries(ops []Op) *linEntry {
+	entries := make([]*linEntry, 0, 2*len(ops))
+	for i, op := range ops {
+		ret := &linEntry{op: i, time: op.Return}
+		call := &linEntry{op: i, call: true, time: op.Call, match: ret}
+		entries = append(entries, call, ret)
+	}
+	sort.Slice(entries, func(i, k int) bool { return entries[i].time < entries[k].time })
+
+	head := &linEntry{}
+	prev := head
+	for _, e := range entries {
+		e.prev = prev
+		prev.next = e
+		prev = e
+	}
+	return head
+}
+
+// lift removes the invocation and the matching response from the list.
+func (e *linEntry) lift() {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	m := e.match
+	m.prev.next = m.next
+	if m.next != nil {
+		m.next.prev = m.prev
+	}
+}
+
+// unlift reverts lift.
+func (e *linEntry) unlift() {
+	m := e.match
+	m.prev.next = m
+	if m.next != nil {
+		m.next.prev = m
+	}
+	e.prev.next = e
+	e.next.prev = e
+}
+
+// fifoModel is a sequential FIFO queue, which supports undoing operations.
+type fifoModel struct {
+	values []int64
+	head   int
+}
+
+func newFIFOModel(sends int) *fifoModel {
+	return &fifoModel{values: make([]int64, 0, sends)}
+}
+
+// step applies op when it is valid in the current state.
+func (m *fifoModel) step(op *Op) bool {
+	switch {
+	case op.Kind == OpSend && !op.Ok:
+		return true
+	case op.Kind == OpSend:
+		m.values = append(m.values, op.Value)
+		return true
+	case op.Ok:
+		if m.head < len(m.values) && m.values[m.head] == op.Value {
+			m.head++
+			return true
+		}
+		return false
+	default:
+		return m.head == len(m.values)
+	}
+}
+
+// undo reverts a successful step of op.
+func (m *fifoModel) undo(op *Op) {
+	switch {
+	case op.Kind == OpSend && !op.Ok:
+	case op.Kind == OpSend:
+		m.values = m.values[:len(m.values)-1]
+	case op.Ok:
+		m.head--
+	}
+}
+
+// key returns the linearized set and the queue content as a cache key.
+func (m *fifoModel) key(linearized []byte) string {
+	content := m.values[m.head:]
+	key := make([]byte, len(linearized)+8*len(content))
+	copy(key, linearized)
+	for i, v := range content {
+		binary.LittleEndian.PutUint64(key[len(linearized)+8*i:], uint64(v))
+	}
+	return string(key)
+}
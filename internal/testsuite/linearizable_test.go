@@ -0,0 +1,109 @@
+package testsuite
+
+import (
+	"testing"
+)
+
+func TestCheckFIFO(t *testing.T) {
+	send := func(proc int, v int64, call, ret int64) Op {
+		return Op{Proc: proc, Kind: OpSend, Value: v, Ok: true, Call: call, Return: ret}
+	}
+	recv := func(proc int, v int64, call, ret int64) Op {
+		return Op{Proc: proc, Kind: OpRecv, Value: v, Ok: true, Call: call, Return: ret}
+	}
+	empty := func(proc int, call, ret int64) Op {
+		return Op{Proc: proc, Kind: OpRecv, Call: call, Return: ret}
+	}
+
+	tests := []struct {
+		name string
+		ok   bool
+		ops  []Op
+	}{
+		{"Empty", true, nil},
+		{"Sequential", true, []Op{
+			send(0, 1, 1, 2), send(0, 2, 3, 4),
+			recv(1, 1, 5, 6), recv(1, 2, 7, 8),
+		}},
+		{"Reordered", false, []Op{
+			send(0, 1, 1, 2), send(0, 2, 3, 4),
+			recv(1, 2, 5, 6), recv(1, 1, 7, 8),
+		}},
+		{"ConcurrentSends", true, []Op{
+			send(0, 1, 1, 4), send(1, 2, 2, 3),
+			recv(2, 2, 5, 6), recv(2, 1, 7, 8),
+		}},
+		{"RecvBeforeSend", false, []Op{
+			recv(1, 1, 1, 2), send(0, 1, 3, 4),
+		}},
+		{"RecvOverlapsSend", true, []Op{
+			recv(1, 1, 1, 4), send(0, 1, 2, 3),
+		}},
+		{"Duplicated", false, []Op{
+			send(0, 1, 1, 2),
+			recv(1, 1, 3, 4), recv(2, 1, 5, 6),
+		}},
+		{"Lost", false, []Op{
+			send(0, 1, 1, 2), send(0, 2, 3, 4),
+			recv(1, 2, 5, 6),
+		}},
+		{"EmptyWhenNonEmpty", false, []Op{
+			send(0, 1, 1, 2),
+			empty(1, 3, 4),
+			recv(1, 1, 5, 6),
+		}},
+		{"EmptyOverlapsSend", true, []Op{
+			send(0, 1, 1, 4),
+			empty(1, 2, 3),
+			recv(1, 1, 5, 6),
+		}},
+		{"FailedSendIgnored", true, []Op{
+			{Proc: 0, Kind: OpSend, Value: 1, Call: 1, Return: 2},
+			empty(1, 3, 4),
+		}},
+		{"LostBeforeEmpty", false, []Op{
+			send(0, 1, 1, 2),
+			empty(1, 3, 4),
+		}},
+		{"EmptyBehindPendingSend", true, []Op{
+			send(0, 1, 1, 10), send(1, 2, 2, 3),
+			empty(2, 4, 5),
+			recv(2, 1, 11, 12), recv(2, 2, 13, 14),
+		}},
+		{"EmptyBehindCompletedSend", false, []Op{
+			send(0, 1, 1, 2), send(1, 2, 3, 10),
+			empty(2, 4, 5),
+			recv(2, 1, 11, 12), recv(2, 2, 13, 14),
+		}},
+		{"EmptyOverlapsLaterSend", false, []Op{
+			send(0, 1, 1, 2),
+			empty(1, 3, 6), send(2, 2, 4, 5),
+			recv(1, 1, 7, 8), recv(1, 2, 9, 10),
+		}},
+		{"EmptyAfterConcurrentRecv", true, []Op{
+			send(0, 1, 1, 2),
+			recv(1, 1, 3, 6), empty(2, 4, 5),
+		}},
+		{"RecvChain", true, []Op{
+			send(0, 1, 1, 2), send(1, 2, 1, 6), send(2, 3, 3, 8),
+			recv(3, 1, 4, 5), recv(4, 3, 7, 10), recv(5, 2, 7, 9),
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			check := func(name string, err error) {
+				t.Helper()
+				if test.ok && err != nil {
+					t.Fatalf("%s: expected linearizable: %v", name, err)
+				}
+				if !test.ok && err == nil {
+					t.Fatalf("%s: expected not linearizable", name)
+				}
+			}
+
+			check("CheckFIFO", CheckFIFO(test.ops))
+			check("searchFIFO", searchFIFO(filterFIFO(test.ops), 0))
+		})
+	}
+}
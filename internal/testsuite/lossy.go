@@ -0,0 +1,158 @@
+package testsuite
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func testLossy[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
+	t.Run("Drop", func(t *testing.T) {
+		q := ctor().(Lossy[T])
+		capacity := q.Cap()
+
+		recv := func(exp int64, dropped uint64) {
+			t.Helper()
+			var v T
+			if !q.Recv(&v) {
+				t.Fatalf("failed to recv %v", exp)
+			}
+			if got := codec.Decode(v); got != exp {
+				t.Fatalf("recv got %v, expected %v", got, exp)
+			}
+			if got := q.Dropped(); got != dropped {
+				t.Fatalf("recv %v dropped %v, expected %v", exp, got, dropped)
+			}
+		}
+
+		// a full queue must not drop anything
+		for i := 0; i < capacity; i++ {
+			if !q.Send(codec.Encode(int64(i))) {
+				t.Fatalf("failed to send %v", i)
+			}
+		}
+		for i := 0; i < capacity; i++ {
+			recv(int64(i), 0)
+		}
+
+		var v T
+		if q.TryRecv(&v) {
+			t.Fatal("recv from empty succeeded")
+		}
+
+		// only the last capacity values must remain
+		base, count := int64(capacity), 3*capacity+1
+		for i := 0; i < count; i++ {
+			if !q.Send(codec.Encode(base + int64(i))) {
+				t.Fatalf("failed to send %v", base+int64(i))
+			}
+		}
+		recv(base+int64(count-capacity), uint64(count-capacity))
+		for i := count - capacity + 1; i < count; i++ {
+			recv(base+int64(i), 0)
+		}
+
+		if q.TryRecv(&v) {
+			t.Fatal("recv from drained succeeded")
+		}
+		q.Close()
+		if q.Send(codec.Encode(0)) {
+			t.Fatal("send to closed succeeded")
+		}
+		if q.Recv(&v) {
+			t.Fatal("recv from closed succeeded")
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		producers := 1
+		if _, ok := ctor().(interface{ MultipleProducers() }); ok {
+			producers = TestProcs
+		}
+
+		for _, count := range TestCount {
+			q := ctor().(Lossy[T])
+
+			running := int32(producers)
+			ProducerConsumer(t, producers, 1, func(id int) error {
+				defer func() {
+					if atomic.AddInt32(&running, -1) == 0 {
+						q.Close()
+					}
+				}()
+				for i := 0; i < count; i++ {
+					if !q.Send(codec.Encode(int64(id)<<32 | int64(i))) {
+						return fmt.Errorf("failed to send %v", i)
+					}
+				}
+				return nil
+			}, func(int) error {
+				lasts := make([]int64, producers)
+				for i := range lasts {
+					lasts[i] = -1
+				}
+
+				received, dropped := 0, uint64(0)
+				for {
+					var v T
+					if !q.Recv(&v) {
+						break
+					}
+					received++
+					dropped += q.Dropped()
+
+					val := codec.Decode(v)
+					id, got := val>>32, val&0xFFFFFFFF
+					if id < 0 || id >= int64(producers) || got >= int64(count) {
+						return fmt.Errorf("invalid value %v:%v", id, got)
+					}
+					if got <= lasts[id] {
+						return fmt.Errorf("invalid order got %v, expected more than %v", got, lasts[id])
+					}
+					lasts[id] = got
+				}
+
+				if total := uint64(received) + dropped; total != uint64(producers*count) {
+					return fmt.Errorf("received %v and dropped %v, expected total %v", received, dropped, producers*count)
+				}
+				return nil
+			})
+		}
+	})
+}
+
+func benchLossy[T any](b *testing.B, caps Capability, ctor func() Queue) {
+	b.Run("Send/x100", func(b *testing.B) {
+		q := ctor().(Lossy[T])
+		b.ResetTimer()
+		var zero T
+		for i := 0; i < b.N; i++ {
+			for i := 0; i < 100; i++ {
+				q.Send(zero)
+			}
+		}
+	})
+
+	b.Run("ProducerConsumer/x100", func(b *testing.B) {
+		q := ctor().(Lossy[T])
+		b.ResetTimer()
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var v T
+			for q.Recv(&v) {
+			}
+		}()
+
+		var zero T
+		for i := 0; i < b.N; i++ {
+			for i := 0; i < 100; i++ {
+				q.Send(zero)
+			}
+		}
+		q.Close()
+		wg.Wait()
+	})
+}
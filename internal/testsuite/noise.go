@@ -0,0 +1,187 @@
+package testsuite
+
+import (
+	"flag"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	noiseSeed  = flag.Int64("shake.seed", 0, "seed for system noise when shaking, 0 picks a new seed")
+	noiseHogs  = flag.Int("shake.hogs", 2, "number of goroutines burning CPU when shaking")
+	noiseProcs = flag.Int("shake.procs", 0, "maximum GOMAXPROCS when shaking, 0 uses number of CPUs")
+)
+
+// noise disturbs scheduling while shaking, so that the repeated tests
+// explore different interleavings.
+//
+// The random decisions are derived from the seed, however the scheduler
+// isn't deterministic, so a seed only makes a failure more likely to repeat.
+type noise struct {
+	seed    uint64
+	counter uint64
+
+	stop  chan struct{}
+	wg    sync.WaitGroup
+	procs int
+}
+
+// activeNoise is used by Jitter, nil when not shaking.
+var activeNoise atomic.Value // *noise
+
+// noiseSlowdown scales the time limits while shaking,
+// the noise slows down progress, which shouldn't be reported as a failure.
+const noiseSlowdown = 4
+
+// pinned is the minimum GOMAXPROCS set by pinProcs, which varyProcs respects,
+// procsMu serializes changing them.
+var (
+	procsMu sync.Mutex
+	pinned  int
+)
+
+// startNoise starts background noise when shaking and returns a func to stop it.
+func startNoise(t *testing.T) (stop func()) {
+	if *shake <= 1 {
+		return func() {}
+	}
+	if active, _ := activeNoise.Load().(*noise); active != nil {
+		// nested Tests share the outer noise
+		return func() {}
+	}
+
+	seed := *noiseSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	t.Logf("shaking with noise, rerun with -shake.seed=%v", seed)
+
+	maxProcs := *noiseProcs
+	if maxProcs <= 0 {
+		maxProcs = runtime.NumCPU()
+	}
+
+	n := &noise{
+		seed:  uint64(seed),
+		stop:  make(chan struct{}),
+		procs: runtime.GOMAXPROCS(0),
+	}
+
+	for i := 0; i < *noiseHogs; i++ {
+		n.wg.Add(1)
+		go n.hog()
+	}
+	n.wg.Add(1)
+	go n.varyProcs(maxProcs)
+
+	activeNoise.Store(n)
+	return func() {
+		activeNoise.Store((*noise)(nil))
+		close(n.stop)
+		n.wg.Wait()
+		runtime.GOMAXPROCS(n.procs)
+	}
+}
+
+// nonblockThreshold returns how long a nonblocking operation may take,
+// it's NonblockThreshold scaled by noiseSlowdown while shaking.
+func nonblockThreshold() time.Duration {
+	if n, _ := activeNoise.Load().(*noise); n != nil {
+		return noiseSlowdown * NonblockThreshold
+	}
+	return NonblockThreshold
+}
+
+// pinProcs raises GOMAXPROCS to at least procs and keeps varyProcs from
+// going below it until unpin is called.
+func pinProcs(procs int) (unpin func()) {
+	procsMu.Lock()
+	defer procsMu.Unlock()
+
+	prevPinned, prevProcs := pinned, runtime.GOMAXPROCS(0)
+	pinned = procs
+	if prevProcs < procs {
+		runtime.GOMAXPROCS(procs)
+	}
+	return func() {
+		procsMu.Lock()
+		defer procsMu.Unlock()
+
+		pinned = prevPinned
+		if prevProcs < procs {
+			runtime.GOMAXPROCS(prevProcs)
+		}
+	}
+}
+
+// next returns the next pseudo-random number.
+func (n *noise) next() uint64 {
+	// splitmix64
+	h := n.seed + atomic.AddUint64(&n.counter, 1)*0x9E3779B97F4A7C15
+	h = (h ^ h>>30) * 0xBF58476D1CE4E5B9
+	h = (h ^ h>>27) * 0x94D049BB133111EB
+	return h ^ h>>31
+}
+
+// hog burns CPU in short bursts with random pauses.
+//
+// The bursts are short, because the scheduler preempts a goroutine only
+// after 10ms, which would make the tests wait for the hogs.
+func (n *noise) hog() {
+	defer n.wg.Done()
+	for {
+		select {
+		case <-n.stop:
+			return
+		default:
+		}
+		LocalWork(int(n.next() % 10000))
+		if r := n.next(); r%4 == 0 {
+			time.Sleep(time.Duration(r>>8%100) * time.Microsecond)
+		} else {
+			runtime.Gosched()
+		}
+	}
+}
+
+// varyProcs changes GOMAXPROCS at random intervals,
+// but not below the procs pinned by the running test.
+func (n *noise) varyProcs(maxProcs int) {
+	defer n.wg.Done()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-time.After(time.Duration(1+n.next()%10) * time.Millisecond):
+			procs := 1 + int(n.next()%uint64(maxProcs))
+			procsMu.Lock()
+			if procs < pinned {
+				procs = pinned
+			}
+			runtime.GOMAXPROCS(procs)
+			procsMu.Unlock()
+		}
+	}
+}
+
+// Jitter randomly yields or sleeps when shaking,
+// producer and consumer loops call it to vary the interleavings.
+func Jitter() {
+	n, _ := activeNoise.Load().(*noise)
+	if n == nil {
+		return
+	}
+
+	// sleeping is rare, because sleeps are much longer
+	// than requested with a coarse timer
+	r := n.next()
+	switch {
+	case r%1024 == 0:
+		time.Sleep(time.Duration(r>>10%50) * time.Microsecond)
+	case r%16 == 1:
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,173 @@
+package testsuite
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func testOverflow[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
+	t.Run("Full", func(t *testing.T) {
+		q := ctor()
+		sq, nq := q.(SPSC[T]), q.(NonblockingSPSC[T])
+		capacity := q.(Overflow).Cap()
+
+		for i := 0; i < capacity; i++ {
+			if !sq.Send(codec.Encode(int64(i))) {
+				t.Fatalf("failed to send %v", i)
+			}
+		}
+		FlushSend(q)
+
+		extra := capacity + 1
+		for i := 0; i < extra; i++ {
+			ok := sq.Send(codec.Encode(int64(capacity + i)))
+			if ok == caps.Has(CapOverflowFail) {
+				t.Fatalf("send to full returned %v", ok)
+			}
+		}
+		FlushSend(q)
+
+		overflowed := uint64(extra)
+		if caps.Has(CapOverflowFail) {
+			overflowed = 0
+		}
+		if got := q.(Overflow).Overflowed(); got != overflowed {
+			t.Fatalf("overflowed %v, expected %v", got, overflowed)
+		}
+
+		// only DropOldest keeps the newest values
+		first := 0
+		if caps.Has(CapOverflowDropOldest) {
+			first = extra
+		}
+		for i := first; i < first+capacity; i++ {
+			var v T
+			if !nq.TryRecv(&v) {
+				t.Fatalf("failed to recv %v", i)
+			}
+			if got := codec.Decode(v); got != int64(i) {
+				t.Fatalf("recv got %v, expected %v", got, i)
+			}
+		}
+		FlushRecv(q)
+
+		var v T
+		if nq.TryRecv(&v) {
+			t.Fatalf("recv from drained succeeded, got %v", codec.Decode(v))
+		}
+	})
+
+	if _, ok := ctor().(BatchSender[T]); ok {
+		t.Run("Batch", func(t *testing.T) {
+			q := ctor()
+			bq, nq := q.(BatchSender[T]), q.(NonblockingSPSC[T])
+			capacity := q.(Overflow).Cap()
+
+			extra := capacity + 1
+			vs := make([]T, capacity+extra)
+			for i := range vs {
+				vs[i] = codec.Encode(int64(i))
+			}
+
+			sent, expected := bq.SendBatch(vs), len(vs)
+			if caps.Has(CapOverflowFail) {
+				expected = capacity
+			}
+			if sent != expected {
+				t.Fatalf("send batch returned %v, expected %v", sent, expected)
+			}
+
+			overflowed := uint64(extra)
+			if caps.Has(CapOverflowFail) {
+				overflowed = 0
+			}
+			if got := q.(Overflow).Overflowed(); got != overflowed {
+				t.Fatalf("overflowed %v, expected %v", got, overflowed)
+			}
+
+			for i := 0; i < capacity; i++ {
+				var v T
+				if !nq.TryRecv(&v) {
+					t.Fatalf("failed to recv %v", i)
+				}
+				if got := codec.Decode(v); got != int64(i) {
+					t.Fatalf("recv got %v, expected %v", got, i)
+				}
+			}
+			FlushRecv(q)
+
+			var v T
+			if nq.TryRecv(&v) {
+				t.Fatalf("recv from drained succeeded, got %v", codec.Decode(v))
+			}
+		})
+	}
+
+	t.Run("Concurrent", func(t *testing.T) {
+		producers := 1
+		if _, ok := ctor().(interface{ MultipleProducers() }); ok {
+			producers = TestProcs
+		}
+
+		for _, count := range TestCount {
+			q := ctor()
+			sq := q.(SPSC[T])
+
+			var failed int64
+			running := int32(producers)
+			ProducerConsumer(t, producers, 1, func(id int) error {
+				defer func() {
+					if atomic.AddInt32(&running, -1) == 0 {
+						q.(Closer).Close()
+					}
+				}()
+				for i := 0; i < count; i++ {
+					if !sq.Send(codec.Encode(int64(id)<<32 | int64(i))) {
+						atomic.AddInt64(&failed, 1)
+					}
+				}
+				FlushSend(q)
+				return nil
+			}, func(int) error {
+				lasts := make([]int64, producers)
+				for i := range lasts {
+					lasts[i] = -1
+				}
+
+				received := 0
+				for {
+					var v T
+					if !sq.Recv(&v) {
+						break
+					}
+					received++
+
+					val := codec.Decode(v)
+					id, got := val>>32, val&0xFFFFFFFF
+					if id < 0 || id >= int64(producers) || got >= int64(count) {
+						return fmt.Errorf("invalid value %v:%v", id, got)
+					}
+					if got <= lasts[id] {
+						return fmt.Errorf("invalid order got %v, expected more than %v", got, lasts[id])
+					}
+					lasts[id] = got
+				}
+
+				failed := atomic.LoadInt64(&failed)
+				overflowed := q.(Overflow).Overflowed()
+				if caps.Has(CapOverflowFail) && overflowed != 0 {
+					return fmt.Errorf("overflowed %v, expected failed sends", overflowed)
+				}
+				if !caps.Has(CapOverflowFail) && failed != 0 {
+					return fmt.Errorf("failed %v sends, expected overflow", failed)
+				}
+				if total := int64(received) + failed + int64(overflowed); total != int64(producers*count) {
+					return fmt.Errorf("received %v, failed %v and overflowed %v, expected total %v",
+						received, failed, overflowed, producers*count)
+				}
+				return nil
+			})
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package testsuite
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// priorityLevels returns number of priorities to test and
+// how they are mapped to the priorities of the queue.
+func priorityLevels(q Queue) (levels int, priority func(level int) int) {
+	levels = q.(interface{ Priorities() int }).Priorities()
+	if levels > 0 {
+		return levels, func(level int) int { return level }
+	}
+	// any int can be used, so include negative and large priorities
+	return 4, func(level int) int { return (level - 2) * 1000003 }
+}
+
+func testPriority[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
+	t.Run("Order", func(t *testing.T) {
+		q := ctor().(Priority[T])
+		levels, priority := priorityLevels(q)
+		n := 8
+		if b, ok := q.(Bounded); ok && b.Cap() < n {
+			n = b.Cap()
+		}
+
+		for i := 0; i < n; i++ {
+			for level := levels - 1; level >= 0; level-- {
+				if !q.SendPriority(codec.Encode(int64(level)<<16|int64(i)), priority(level)) {
+					t.Fatalf("failed to send %v:%v", level, i)
+				}
+			}
+		}
+
+		lasts := make([]int64, levels)
+		for i := range lasts {
+			lasts[i] = -1
+		}
+		lastLevel := int64(0)
+		for k := 0; k < n*levels; k++ {
+			var v T
+			if !q.TryRecv(&v) {
+				t.Fatalf("failed to recv %v", k)
+			}
+			val := codec.Decode(v)
+			level, i := val>>16, val&0xFFFF
+			if level < 0 || level >= int64(levels) || i >= int64(n) {
+				t.Fatalf("invalid value %v:%v", level, i)
+			}
+			if i != lasts[level]+1 {
+				t.Fatalf("invalid order got %v:%v, expected %v:%v", level, i, level, lasts[level]+1)
+			}
+			lasts[level] = i
+			if !caps.Has(CapPriorityWeighted) {
+				if level < lastLevel {
+					t.Fatalf("invalid priority got %v, expected at least %v", level, lastLevel)
+				}
+				lastLevel = level
+			}
+		}
+
+		var v T
+		if q.TryRecv(&v) {
+			t.Fatalf("recv from drained succeeded, got %v", codec.Decode(v))
+		}
+		q.Close()
+		if q.SendPriority(codec.Encode(0), priority(0)) {
+			t.Fatal("send to closed succeeded")
+		}
+		if q.Recv(&v) {
+			t.Fatal("recv from closed succeeded")
+		}
+	})
+
+	if caps.Has(CapPriorityWeighted) {
+		t.Run("Weighted", func(t *testing.T) {
+			q := ctor().(Priority[T])
+			weights := q.(WeightedPriority).Weights()
+			_, priority := priorityLevels(q)
+
+			total := 0
+			for _, w := range weights {
+				total += w
+			}
+			if b, ok := q.(Bounded); ok && b.Cap() < total {
+				t.Skipf("capacity %v is less than total weight %v", b.Cap(), total)
+			}
+
+			for level := range weights {
+				for i := 0; i < total; i++ {
+					if !q.SendPriority(codec.Encode(int64(level)), priority(level)) {
+						t.Fatalf("failed to send %v:%v", level, i)
+					}
+				}
+			}
+
+			// while all priorities have values, a full round must match the weights
+			counts := make([]int, len(weights))
+			for k := 0; k < total; k++ {
+				var v T
+				if !q.TryRecv(&v) {
+					t.Fatalf("failed to recv %v", k)
+				}
+				level := codec.Decode(v)
+				if level < 0 || level >= int64(len(weights)) {
+					t.Fatalf("invalid value %v", level)
+				}
+				counts[level]++
+			}
+			for level, w := range weights {
+				if counts[level] != w {
+					t.Fatalf("received %v from priority %v, expected %v (weights %v)", counts[level], level, w, weights)
+				}
+			}
+		})
+	}
+
+	t.Run("Concurrent", func(t *testing.T) { testPriorityConcurrent(t, codec, ctor) })
+
+	// Parallel runs the consumers on multiple procs to exercise concurrent removals
+	t.Run("Parallel", func(t *testing.T) {
+		defer pinProcs(ParallelProcs)()
+		testPriorityConcurrent(t, codec, ctor)
+	})
+}
+
+// testPriorityConcurrent verifies that values sent by concurrent producers
+// are received exactly once by concurrent consumers.
+func testPriorityConcurrent[T any](t *testing.T, codec Codec[T], ctor func() Queue) {
+	for _, count := range TestCount {
+		q := ctor().(Priority[T])
+		levels, priority := priorityLevels(q)
+
+		received := NewDelivery(2 * TestProcs)
+		running := int32(TestProcs)
+		ProducerConsumer(t, TestProcs, TestProcs, func(id int) error {
+			defer func() {
+				if atomic.AddInt32(&running, -1) == 0 {
+					q.Close()
+				}
+			}()
+			for i := 0; i < count; i++ {
+				if !q.SendPriority(codec.Encode(int64(id)<<32|int64(i+1)), priority(i%levels)) {
+					return fmt.Errorf("failed to send %v", i)
+				}
+			}
+			return nil
+		}, func(id int) error {
+			for {
+				var v T
+				if !q.Recv(&v) {
+					return nil
+				}
+				received.Add(id, codec.Decode(v))
+			}
+		})
+		if err := received.Check(producerSequences(TestProcs, count)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func benchPriority[T any](b *testing.B, caps Capability, ctor func() Queue) {
+	b.Run("ProducerConsumer/x100", func(b *testing.B) {
+		q := ctor().(Priority[T])
+		levels, priority := priorityLevels(q)
+		b.ResetTimer()
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var v T
+			for q.Recv(&v) {
+			}
+		}()
+
+		var zero T
+		for i := 0; i < b.N; i++ {
+			for i := 0; i < 100; i++ {
+				q.SendPriority(zero, priority(i%levels))
+			}
+		}
+		q.Close()
+		wg.Wait()
+	})
+}
@@ -1,6 +1,9 @@
 package testsuite
 
-type Value = int64
+import (
+	"context"
+	"time"
+)
 
 // Queue is the general interface
 type Queue interface {
@@ -32,76 +35,244 @@ type Closer interface {
 	Close()
 }
 
-// TODO:
-// type BatchReceiver interface {
-// 	// BatchRecv receives a batch
-// 	BatchRecv(func(v Value)) bool
-// }
+// BatchSender is implemented by queues that can send multiple values
+// with a single synchronization.
+type BatchSender[T any] interface {
+	// SendBatch puts values to the queue and waits when it is full,
+	// unless an overflow policy is set, returns number of values sent or dropped,
+	// which is less than len(vs) only when the queue has been closed or
+	// the policy is OverflowFail
+	SendBatch(vs []T) int
+}
+
+// BatchReceiver is implemented by queues that can receive multiple values
+// with a single synchronization.
+type BatchReceiver[T any] interface {
+	// RecvBatch takes up to len(vs) values from the queue and waits when it is empty,
+	// returns number of values received, 0 when the queue has been closed
+	RecvBatch(vs []T) int
+	// RecvBatchFunc takes all available values from the queue and waits when it is empty,
+	// returns number of values received, 0 when the queue has been closed
+	RecvBatchFunc(fn func(v T)) int
+}
 
 // SPSC is a blocking single-producer and single-consumer queue,
 // which waits until Send or Recv succeeds
-type SPSC interface {
+type SPSC[T any] interface {
 	Queue
 	// Send puts a value to a queue,
 	// returns false when the queue has been closed
-	Send(v Value) bool
+	Send(v T) bool
 	// Recv takes a value from the queue
 	// returns false when the queue has been closed
-	Recv(v *Value) bool
+	Recv(v *T) bool
+
+	// SendContext puts a value to a queue,
+	// returns false when the queue has been closed or ctx is done
+	SendContext(ctx context.Context, v T) bool
+	// RecvContext takes a value from the queue,
+	// returns false when the queue has been closed or ctx is done
+	RecvContext(ctx context.Context, v *T) bool
+
+	// SendTimeout puts a value to a queue,
+	// returns false when the queue has been closed or timeout elapsed
+	SendTimeout(v T, timeout time.Duration) bool
+	// RecvTimeout takes a value from the queue,
+	// returns false when the queue has been closed or timeout elapsed
+	RecvTimeout(v *T, timeout time.Duration) bool
 }
 
 // MPSC is a blocking multi-producer and single-consumer queue,
 // which waits until Send or Recv succeeds
-type MPSC interface {
-	SPSC
+type MPSC[T any] interface {
+	SPSC[T]
 	MultipleProducers()
 }
 
 // SPMC is a blocking single-producer and multi-consumer queue,
 // which waits until Send or Recv succeeds
-type SPMC interface {
-	SPSC
+type SPMC[T any] interface {
+	SPSC[T]
 	MultipleConsumers()
 }
 
 // MPMC is a blocking multi-producer and multi-consumer queue,
 // which waits until Send or Recv succeeds
-type MPMC interface {
-	SPSC
+type MPMC[T any] interface {
+	SPSC[T]
 	MultipleProducers()
 	MultipleConsumers()
 }
 
 // NonblockingSPSC is a non-blocking single-producer and single-consumer queue, which
 // which returns in case Send or Recv cannot be completed
-type NonblockingSPSC interface {
+type NonblockingSPSC[T any] interface {
 	Queue
 	// TrySend tries to put a value to a queue
 	// returns false when the queue if full or closed
-	TrySend(v Value) bool
+	TrySend(v T) bool
 	// TrySend tries to take a value to a queue
 	// returns false when the queue if empty or closed
-	TryRecv(v *Value) bool
+	TryRecv(v *T) bool
 }
 
 // NonblockingMPSC is a non-blocking multi-producer and single-consumer queue, which
 // which returns in case Send or Recv cannot be completed
-type NonblockingMPSC interface {
-	NonblockingSPSC
+type NonblockingMPSC[T any] interface {
+	NonblockingSPSC[T]
 	MultipleProducers()
 }
 
 // NonblockingSPMC is a non-blocking single-producer and multi-consumer queue, which
 // which returns in case Send or Recv cannot be completed
-type NonblockingSPMC interface {
-	NonblockingSPSC
+type NonblockingSPMC[T any] interface {
+	NonblockingSPSC[T]
 	MultipleConsumers()
 }
 
 // NonblockingMPMC is a non-blocking multi-producer and multi-consumer queue, which
 // which returns in case Send or Recv cannot be completed
-type NonblockingMPMC interface {
-	NonblockingSPSC
+type NonblockingMPMC[T any] interface {
+	NonblockingSPSC[T]
 	MultipleProducers()
 	MultipleConsumers()
 }
+
+// Deque is a work-stealing deque, where a single owner pushes and pops values
+// at the bottom and any number of thieves steal values from the top
+type Deque[T any] interface {
+	Queue
+	// PushBottom puts a value to the bottom of the deque,
+	// must only be called by the owner
+	PushBottom(v T)
+	// PopBottom takes the most recently pushed value from the bottom of the deque,
+	// must only be called by the owner,
+	// returns false when the deque is empty
+	PopBottom(v *T) bool
+	// Steal takes the least recently pushed value from the top of the deque,
+	// returns false when the deque is empty
+	Steal(v *T) bool
+}
+
+// Subscriber receives values from a Broadcast queue,
+// it must only be used by a single consumer
+type Subscriber[T any] interface {
+	// Recv takes the next value,
+	// returns false when the queue has been closed and drained or the subscriber has been closed
+	Recv(v *T) bool
+	// RecvContext takes the next value,
+	// returns false when the queue has been closed and drained, the subscriber has been closed or ctx is done
+	RecvContext(ctx context.Context, v *T) bool
+	// RecvTimeout takes the next value,
+	// returns false when the queue has been closed and drained, the subscriber has been closed or timeout elapsed
+	RecvTimeout(v *T, timeout time.Duration) bool
+	// TryRecv tries to take the next value,
+	// returns false when there is none or the subscriber has been closed
+	TryRecv(v *T) bool
+	// Close unsubscribes, must be called by the consumer
+	Close()
+}
+
+// Broadcast is a single-producer queue, where every subscriber
+// receives every value sent after it subscribed
+type Broadcast[T any] interface {
+	Queue
+	// Send puts a value to a queue,
+	// returns false when the queue has been closed
+	Send(v T) bool
+	// SendContext puts a value to a queue,
+	// returns false when the queue has been closed or ctx is done
+	SendContext(ctx context.Context, v T) bool
+	// SendTimeout puts a value to a queue,
+	// returns false when the queue has been closed or timeout elapsed
+	SendTimeout(v T, timeout time.Duration) bool
+	// TrySend tries to put a value to a queue
+	// returns false when the slowest subscriber is full or the queue is closed
+	TrySend(v T) bool
+	// Close the queue for sending
+	Close()
+	// Subscribe adds a subscriber
+	Subscribe() Subscriber[T]
+}
+
+// Lossy is a bounded queue, which drops the oldest value instead of
+// blocking the producer when it is full
+type Lossy[T any] interface {
+	Queue
+	Bounded
+	// Send puts a value to a queue and never blocks,
+	// returns false when the queue has been closed
+	Send(v T) bool
+	// Recv takes a value from the queue
+	// returns false when the queue has been closed and drained
+	Recv(v *T) bool
+	// TryRecv tries to take a value from the queue
+	// returns false when the queue is empty
+	TryRecv(v *T) bool
+	// Dropped returns number of values that were dropped between
+	// the previous and the last received value
+	Dropped() uint64
+	// Close the queue for sending
+	Close()
+}
+
+// Overflow is implemented by bounded queues with a configurable policy
+// for sending to a full queue
+type Overflow interface {
+	Bounded
+	// OverflowPolicy returns the name of the policy:
+	// "Block", "Fail", "DropNewest", "DropOldest" or "Handler"
+	OverflowPolicy() string
+	// Overflowed returns number of values that were dropped because the queue was full
+	Overflowed() uint64
+}
+
+// Priority is a queue, where values with a lower priority are received first
+// and values with the same priority are received in FIFO order
+type Priority[T any] interface {
+	Queue
+	// SendPriority puts a value with the priority to a queue,
+	// returns false when the queue has been closed
+	SendPriority(v T, priority int) bool
+	// Recv takes the value with the lowest priority from the queue
+	// returns false when the queue has been closed and drained
+	Recv(v *T) bool
+	// TryRecv tries to take the value with the lowest priority from the queue
+	// returns false when the queue is empty
+	TryRecv(v *T) bool
+	// Close the queue for sending
+	Close()
+	// Priorities returns number of priorities, which are numbered from 0,
+	// 0 means that any int can be used as a priority
+	Priorities() int
+}
+
+// WeightedPriority is a priority queue, where the priorities are received
+// in proportion to their weights instead of always the lowest first
+type WeightedPriority interface {
+	// Weights returns weights for each priority,
+	// nil when the lowest priority is always received first
+	Weights() []int
+}
+
+// Delay is a queue, where values can be received
+// only after their deadline has passed
+type Delay[T any] interface {
+	Queue
+	// SendAt puts a value to a queue, which can be received after at,
+	// returns false when the queue has been closed
+	SendAt(v T, at time.Time) bool
+	// SendAfter puts a value to a queue, which can be received after d has elapsed,
+	// returns false when the queue has been closed
+	SendAfter(v T, d time.Duration) bool
+	// Recv takes the value with the earliest deadline and waits until it passes
+	// returns false when the queue has been closed and drained
+	Recv(v *T) bool
+	// TryRecv tries to take a value whose deadline has passed
+	// returns false when there is none
+	TryRecv(v *T) bool
+	// Close the queue for sending
+	Close()
+	// FakeClock returns the clock used by the queue, so that tests can control time
+	FakeClock() *FakeClock
+}
@@ -0,0 +1,120 @@
+package testsuite
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// RetentionCount is the maximum number of values sent in retention tests
+var RetentionCount = 16
+
+// Tracked is a heap allocated value, which counts when it has been
+// garbage collected.
+type Tracked struct {
+	Value     int64
+	collected *int64
+}
+
+// NewTracked allocates a value, which increments collected after
+// it has become unreachable and garbage collected.
+func NewTracked(v int64, collected *int64) *Tracked {
+	x := &Tracked{Value: v, collected: collected}
+	runtime.SetFinalizer(x, func(x *Tracked) { atomic.AddInt64(x.collected, 1) })
+	return x
+}
+
+// waitCollected runs garbage collection until n values have been collected,
+// returns the number of collected values.
+func waitCollected(collected *int64, n int) int {
+	for i := 0; i < 100; i++ {
+		runtime.GC()
+		if got := atomic.LoadInt64(collected); got >= int64(n) {
+			return int(got)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return int(atomic.LoadInt64(collected))
+}
+
+// Retention verifies that a queue with *Tracked values doesn't keep
+// received values reachable, i.e. it clears the slots after receiving.
+//
+// Otherwise large values or values holding resources stay alive
+// until they are overwritten, which may take arbitrarily long.
+func Retention(t *testing.T, ctor func() Queue) {
+	t.Helper()
+
+	q := ctor()
+	caps := Detect[*Tracked](q)
+
+	count := Cap(q)
+	if count > RetentionCount {
+		count = RetentionCount
+	}
+
+	var send func(v *Tracked) bool
+	var recv func() bool
+	switch {
+	case caps.Has(CapBlockSPSC):
+		q := q.(SPSC[*Tracked])
+		send = q.Send
+		recv = func() bool { var v *Tracked; return q.Recv(&v) }
+	case caps.Has(CapNonblockSPSC):
+		q := q.(NonblockingSPSC[*Tracked])
+		send = q.TrySend
+		recv = func() bool { var v *Tracked; return q.TryRecv(&v) }
+	case caps.Has(CapLossy):
+		q := q.(Lossy[*Tracked])
+		send = q.Send
+		recv = func() bool { var v *Tracked; return q.TryRecv(&v) }
+	case caps.Has(CapPriority):
+		q := q.(Priority[*Tracked])
+		send = func(v *Tracked) bool { return q.SendPriority(v, 0) }
+		recv = func() bool { var v *Tracked; return q.TryRecv(&v) }
+	case caps.Has(CapDeque):
+		q := q.(Deque[*Tracked])
+		send = func(v *Tracked) bool { q.PushBottom(v); return true }
+		steal := false
+		recv = func() bool {
+			var v *Tracked
+			steal = !steal
+			if steal {
+				return q.Steal(&v)
+			}
+			return q.PopBottom(&v)
+		}
+	case caps.Has(CapBroadcast):
+		// other subscribers may still need the value,
+		// hence it stays in the ring until it is overwritten
+		t.Skip("broadcast keeps values until overwritten")
+	default:
+		t.Skip("unsupported queue")
+	}
+
+	var collected int64
+	// the values must not be referenced from this stack frame
+	func() {
+		for i := 0; i < count; i++ {
+			if !send(NewTracked(int64(i), &collected)) {
+				t.Fatalf("failed to send %v", i)
+			}
+		}
+		FlushSend(q)
+	}()
+	func() {
+		for i := 0; i < count; i++ {
+			if !recv() {
+				t.Fatalf("failed to recv %v", i)
+			}
+		}
+		FlushRecv(q)
+	}()
+
+	if got := waitCollected(&collected, count); got < count {
+		t.Errorf("%d of %d received values are still reachable", count-got, count)
+	}
+
+	runtime.KeepAlive(q)
+}
@@ -1,6 +1,7 @@
 package testsuite
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 	"sync/atomic"
@@ -8,19 +9,20 @@ import (
 	"time"
 )
 
-func testSPSC(t *testing.T, caps Capability, ctor func() Queue) {
+func testSPSC[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
 	t.Run("Single", func(t *testing.T) {
 		for _, count := range TestCount {
-			q := ctor().(SPSC)
+			q := ctor().(SPSC[T])
 			if skipRedundant(q, count) {
 				continue
 			}
 			for i := 0; i < count; i++ {
-				exp := Value(i)
-				q.Send(exp)
+				exp := int64(i)
+				q.Send(codec.Encode(exp))
 				FlushSend(q)
-				var got Value
-				q.Recv(&got)
+				var v T
+				q.Recv(&v)
+				got := codec.Decode(v)
 				FlushRecv(q)
 				if exp != got {
 					t.Fatalf("expected %v got %v", exp, got)
@@ -31,14 +33,15 @@ func testSPSC(t *testing.T, caps Capability, ctor func() Queue) {
 
 	t.Run("Basic", func(t *testing.T) {
 		for _, count := range TestCount {
-			q := ctor().(SPSC)
+			q := ctor().(SPSC[T])
 			if skipRedundant(q, count) {
 				continue
 			}
 
 			ProducerConsumer(t, 1, 1, func(int) error {
 				for i := 0; i < count; i++ {
-					if !q.Send(Value(i + 1)) {
+					Jitter()
+					if !q.Send(codec.Encode(int64(i + 1))) {
 						return fmt.Errorf("failed to send %v", i)
 					}
 				}
@@ -46,12 +49,14 @@ func testSPSC(t *testing.T, caps Capability, ctor func() Queue) {
 				return nil
 			}, func(int) error {
 				for i := 0; i < count; i++ {
-					exp := Value(i + 1)
+					Jitter()
+					exp := int64(i + 1)
 
-					var got Value
-					if !q.Recv(&got) {
+					var v T
+					if !q.Recv(&v) {
 						return fmt.Errorf("recv failed")
 					}
+					got := codec.Decode(v)
 
 					if got != exp {
 						return fmt.Errorf("invalid value got %v, expected %v", got, exp)
@@ -65,13 +70,13 @@ func testSPSC(t *testing.T, caps Capability, ctor func() Queue) {
 	if caps.Has(CapBounded) {
 		t.Run("BlockOnFull", func(t *testing.T) {
 			q := ctor().(interface {
-				SPSC
+				SPSC[T]
 				Bounded
 			})
 			capacity := q.Cap()
 
 			for i := 0; i < capacity; i++ {
-				if !q.Send(0) {
+				if !q.Send(codec.Encode(0)) {
 					t.Fatal("failed to send")
 				}
 			}
@@ -79,8 +84,9 @@ func testSPSC(t *testing.T, caps Capability, ctor func() Queue) {
 			FlushSend(q)
 			sent := uint32(0)
 			go func() {
-				if !q.Send(0) {
-					t.Fatal("failed to send")
+				if !q.Send(codec.Encode(0)) {
+					t.Error("failed to send")
+					return
 				}
 				FlushSend(q)
 				atomic.StoreUint32(&sent, 1)
@@ -91,7 +97,7 @@ func testSPSC(t *testing.T, caps Capability, ctor func() Queue) {
 				t.Fatalf("send to full queue")
 			}
 
-			var v Value
+			var v T
 			if !q.Recv(&v) {
 				t.Fatal("failed to recv from full")
 			}
@@ -110,10 +116,10 @@ func testSPSC(t *testing.T, caps Capability, ctor func() Queue) {
 	}
 }
 
-func testMPSC(t *testing.T, caps Capability, ctor func() Queue) {
+func testMPSC[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
 	t.Run("Basic", func(t *testing.T) {
 		for _, count := range TestCount {
-			q := ctor().(MPSC)
+			q := ctor().(MPSC[T])
 			if skipRedundant(q, count) {
 				continue
 			}
@@ -121,18 +127,21 @@ func testMPSC(t *testing.T, caps Capability, ctor func() Queue) {
 				TestProcs, 1,
 				func(id int) error {
 					for i := 0; i < count; i++ {
-						if !q.Send(Value(id)<<32 | Value(i)) {
+						Jitter()
+						if !q.Send(codec.Encode(int64(id)<<32 | int64(i))) {
 							return fmt.Errorf("failed to send %v", i)
 						}
 					}
 					return nil
 				}, func(int) error {
-					exps := make([]Value, TestProcs)
+					exps := make([]int64, TestProcs)
 					for i := 0; i < count*TestProcs; i++ {
-						var val Value
-						if !q.Recv(&val) {
+						Jitter()
+						var v T
+						if !q.Recv(&v) {
 							return fmt.Errorf("failed to get")
 						}
+						val := codec.Decode(v)
 						id, got := val>>32, val&0xFFFFFFFF
 						exp := exps[id]
 						exps[id]++
@@ -146,30 +155,35 @@ func testMPSC(t *testing.T, caps Capability, ctor func() Queue) {
 	})
 }
 
-func testSPMC(t *testing.T, caps Capability, ctor func() Queue) {
+func testSPMC[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
 	t.Run("Basic", func(t *testing.T) {
 		for _, count := range TestCount {
-			q := ctor().(SPMC)
+			q := ctor().(SPMC[T])
 			if skipRedundant(q, count) {
 				continue
 			}
+			received := NewDelivery(1 + TestProcs)
 			ProducerConsumer(t,
 				1, TestProcs,
 				func(int) error {
 					for i := 0; i < count*TestProcs; i++ {
-						if !q.Send(Value(i + 1)) {
+						Jitter()
+						if !q.Send(codec.Encode(int64(i + 1))) {
 							return fmt.Errorf("failed to send %v", i)
 						}
 					}
 					FlushSend(q)
 					return nil
-				}, func(int) error {
-					var lastexp Value
+				}, func(id int) error {
+					var lastexp int64
 					for i := 0; i < count; i++ {
-						var got Value
-						if !q.Recv(&got) {
+						Jitter()
+						var v T
+						if !q.Recv(&v) {
 							return fmt.Errorf("failed to get")
 						}
+						got := codec.Decode(v)
+						received.Add(id, got)
 						exp := lastexp
 						lastexp = got
 						if got <= exp {
@@ -178,32 +192,39 @@ func testSPMC(t *testing.T, caps Capability, ctor func() Queue) {
 					}
 					return nil
 				})
+			if err := received.Check(sequence(count * TestProcs)); err != nil {
+				t.Fatal(err)
+			}
 		}
 	})
 }
 
-func testMPMC(t *testing.T, caps Capability, ctor func() Queue) {
+func testMPMC[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
 	t.Run("SendRecv", func(t *testing.T) {
 		for _, count := range TestCount {
-			q := ctor().(MPMC)
+			q := ctor().(MPMC[T])
 			if skipRedundant(q, count) {
 				continue
 			}
+			received := NewDelivery(TestProcs)
 			ProducerConsumer(t,
 				TestProcs, 0,
 				func(id int) error {
-					latest := make([]Value, TestProcs)
+					latest := make([]int64, TestProcs)
 					for i := 0; i < count; i++ {
-						if !q.Send(Value(id)<<32 | Value(i+1)) {
+						Jitter()
+						if !q.Send(codec.Encode(int64(id)<<32 | int64(i+1))) {
 							return fmt.Errorf("failed to send %v", i)
 						}
 						FlushSend(q)
 
-						var val Value
-						if !q.Recv(&val) {
+						var v T
+						if !q.Recv(&v) {
 							return fmt.Errorf("failed to get")
 						}
+						val := codec.Decode(v)
 						FlushRecv(q)
+						received.Add(id, val)
 
 						id, got := int(val>>32), val&0xFFFFFFFF
 						exp := latest[id]
@@ -215,32 +236,40 @@ func testMPMC(t *testing.T, caps Capability, ctor func() Queue) {
 					}
 					return nil
 				}, nil)
+			if err := received.Check(producerSequences(TestProcs, count)); err != nil {
+				t.Fatal(err)
+			}
 		}
 	})
 
 	t.Run("Basic", func(t *testing.T) {
 		for _, count := range TestCount {
-			q := ctor().(MPMC)
+			q := ctor().(MPMC[T])
 			if skipRedundant(q, count) {
 				continue
 			}
+			received := NewDelivery(2 * TestProcs)
 			ProducerConsumer(t,
 				TestProcs, TestProcs,
 				func(id int) error {
 					for i := 0; i < count; i++ {
-						if !q.Send(Value(id)<<32 | Value(i+1)) {
+						Jitter()
+						if !q.Send(codec.Encode(int64(id)<<32 | int64(i+1))) {
 							return fmt.Errorf("failed to send %v", i)
 						}
 					}
 					FlushSend(q)
 					return nil
 				}, func(id int) error {
-					latest := make([]Value, TestProcs)
+					latest := make([]int64, TestProcs)
 					for i := 0; i < count; i++ {
-						var val Value
-						if !q.Recv(&val) {
+						Jitter()
+						var v T
+						if !q.Recv(&v) {
 							return fmt.Errorf("failed to get")
 						}
+						val := codec.Decode(v)
+						received.Add(id, val)
 
 						id, got := val>>32, val&0xFFFFFFFF
 						exp := latest[id]
@@ -251,27 +280,31 @@ func testMPMC(t *testing.T, caps Capability, ctor func() Queue) {
 					}
 					return nil
 				})
+			if err := received.Check(producerSequences(TestProcs, count)); err != nil {
+				t.Fatal(err)
+			}
 		}
 	})
 }
 
-func testNonblockSPSC(t *testing.T, caps Capability, ctor func() Queue) {
+func testNonblockSPSC[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
 	t.Run("Single", func(t *testing.T) {
 		for _, count := range TestCount {
-			q := ctor().(NonblockingSPSC)
+			q := ctor().(NonblockingSPSC[T])
 			if skipRedundant(q, count) {
 				continue
 			}
 			for i := 0; i < count; i++ {
-				exp := Value(i)
-				if !q.TrySend(exp) {
+				exp := int64(i)
+				if !q.TrySend(codec.Encode(exp)) {
 					t.Fatalf("send failed")
 				}
 				FlushSend(q)
-				var got Value
-				if !q.TryRecv(&got) {
+				var v T
+				if !q.TryRecv(&v) {
 					t.Fatalf("recv failed")
 				}
+				got := codec.Decode(v)
 				FlushRecv(q)
 				if exp != got {
 					t.Fatalf("expected %v got %v", exp, got)
@@ -282,7 +315,7 @@ func testNonblockSPSC(t *testing.T, caps Capability, ctor func() Queue) {
 
 	t.Run("Basic", func(t *testing.T) {
 		for _, count := range TestCount {
-			q := ctor().(NonblockingSPSC)
+			q := ctor().(NonblockingSPSC[T])
 			if skipRedundant(q, count) {
 				continue
 			}
@@ -290,7 +323,8 @@ func testNonblockSPSC(t *testing.T, caps Capability, ctor func() Queue) {
 				1, 1,
 				func(id int) error {
 					for i := 0; i < count; i++ {
-						if !MustSendIn(q, Value(i+1), NonblockThreshold) {
+						Jitter()
+						if !MustSendIn[T](q, codec.Encode(int64(i+1)), nonblockThreshold()) {
 							return fmt.Errorf("failed to send %v", i)
 						}
 					}
@@ -299,12 +333,14 @@ func testNonblockSPSC(t *testing.T, caps Capability, ctor func() Queue) {
 				},
 				func(id int) error {
 					for i := 0; i < count; i++ {
-						exp := Value(i + 1)
+						Jitter()
+						exp := int64(i + 1)
 
-						var got Value
-						if !MustRecvIn(q, &got, NonblockThreshold) {
+						var v T
+						if !MustRecvIn[T](q, &v, nonblockThreshold()) {
 							return fmt.Errorf("recv timed out")
 						}
+						got := codec.Decode(v)
 
 						if got != exp {
 							return fmt.Errorf("invalid value got %v, expected %v", got, exp)
@@ -317,15 +353,15 @@ func testNonblockSPSC(t *testing.T, caps Capability, ctor func() Queue) {
 
 	if caps.Has(CapBounded) {
 		t.Run("NonblockOnFull", func(t *testing.T) {
-			q := ctor().(NonblockingSPSC)
+			q := ctor().(NonblockingSPSC[T])
 			capacity := Cap(q)
 			for i := 0; i < capacity; i++ {
-				if !q.TrySend(0) {
+				if !q.TrySend(codec.Encode(0)) {
 					t.Fatal("failed to send")
 				}
 			}
 			FlushSend(q)
-			if q.TrySend(0) {
+			if q.TrySend(codec.Encode(0)) {
 				t.Fatal("send succeeded")
 			}
 			FlushSend(q)
@@ -333,10 +369,10 @@ func testNonblockSPSC(t *testing.T, caps Capability, ctor func() Queue) {
 	}
 }
 
-func testNonblockMPSC(t *testing.T, caps Capability, ctor func() Queue) {
+func testNonblockMPSC[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
 	t.Run("Basic", func(t *testing.T) {
 		for _, count := range TestCount {
-			q := ctor().(NonblockingMPSC)
+			q := ctor().(NonblockingMPSC[T])
 			if skipRedundant(q, count) {
 				continue
 			}
@@ -345,18 +381,21 @@ func testNonblockMPSC(t *testing.T, caps Capability, ctor func() Queue) {
 				TestProcs, 1,
 				func(id int) error {
 					for i := 0; i < count; i++ {
-						if !MustSendIn(q, Value(id)<<32|Value(i), NonblockThreshold) {
+						Jitter()
+						if !MustSendIn[T](q, codec.Encode(int64(id)<<32|int64(i)), nonblockThreshold()) {
 							return fmt.Errorf("failed to send %v", i)
 						}
 					}
 					return nil
 				}, func(int) error {
-					exps := make([]Value, TestProcs)
+					exps := make([]int64, TestProcs)
 					for i := 0; i < count*TestProcs; i++ {
-						var val Value
-						if !MustRecvIn(q, &val, NonblockThreshold) {
+						Jitter()
+						var v T
+						if !MustRecvIn[T](q, &v, nonblockThreshold()) {
 							return fmt.Errorf("failed to get")
 						}
+						val := codec.Decode(v)
 						id, got := val>>32, val&0xFFFFFFFF
 						exp := exps[id]
 						exps[id]++
@@ -370,31 +409,36 @@ func testNonblockMPSC(t *testing.T, caps Capability, ctor func() Queue) {
 	})
 }
 
-func testNonblockSPMC(t *testing.T, caps Capability, ctor func() Queue) {
+func testNonblockSPMC[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
 	t.Run("Basic", func(t *testing.T) {
 		for _, count := range TestCount {
-			q := ctor().(NonblockingSPMC)
+			q := ctor().(NonblockingSPMC[T])
 			if skipRedundant(q, count) {
 				continue
 			}
+			received := NewDelivery(1 + TestProcs)
 
 			ProducerConsumer(t,
 				1, TestProcs,
 				func(int) error {
 					for i := 0; i < count*TestProcs; i++ {
-						if !MustSendIn(q, Value(i+1), NonblockThreshold) {
+						Jitter()
+						if !MustSendIn[T](q, codec.Encode(int64(i+1)), nonblockThreshold()) {
 							return fmt.Errorf("failed to send %v", i)
 						}
 					}
 					FlushSend(q)
 					return nil
-				}, func(int) error {
-					var lastexp Value
+				}, func(id int) error {
+					var lastexp int64
 					for i := 0; i < count; i++ {
-						var got Value
-						if !MustRecvIn(q, &got, NonblockThreshold) {
+						Jitter()
+						var v T
+						if !MustRecvIn[T](q, &v, nonblockThreshold()) {
 							return fmt.Errorf("failed to get")
 						}
+						got := codec.Decode(v)
+						received.Add(id, got)
 						exp := lastexp
 						lastexp = got
 						if got <= exp {
@@ -403,32 +447,39 @@ func testNonblockSPMC(t *testing.T, caps Capability, ctor func() Queue) {
 					}
 					return nil
 				})
+			if err := received.Check(sequence(count * TestProcs)); err != nil {
+				t.Fatal(err)
+			}
 		}
 	})
 }
 
-func testNonblockMPMC(t *testing.T, caps Capability, ctor func() Queue) {
+func testNonblockMPMC[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
 	t.Run("SendRecv", func(t *testing.T) {
 		for _, count := range TestCount {
-			q := ctor().(NonblockingMPMC)
+			q := ctor().(NonblockingMPMC[T])
 			if skipRedundant(q, count) {
 				continue
 			}
+			received := NewDelivery(TestProcs)
 			ProducerConsumer(t,
 				TestProcs, 0,
 				func(id int) error {
-					latest := make([]Value, TestProcs)
+					latest := make([]int64, TestProcs)
 					for i := 0; i < count; i++ {
-						if !MustSendIn(q, Value(id)<<32|Value(i+1), NonblockThreshold) {
+						Jitter()
+						if !MustSendIn[T](q, codec.Encode(int64(id)<<32|int64(i+1)), nonblockThreshold()) {
 							return fmt.Errorf("failed to send %v", i)
 						}
 						FlushSend(q)
 
-						var val Value
-						if !MustRecvIn(q, &val, NonblockThreshold) {
+						var v T
+						if !MustRecvIn[T](q, &v, nonblockThreshold()) {
 							return fmt.Errorf("failed to get")
 						}
+						val := codec.Decode(v)
 						FlushRecv(q)
+						received.Add(id, val)
 
 						id, got := int(val>>32), val&0xFFFFFFFF
 						exp := latest[id]
@@ -440,32 +491,40 @@ func testNonblockMPMC(t *testing.T, caps Capability, ctor func() Queue) {
 					}
 					return nil
 				}, nil)
+			if err := received.Check(producerSequences(TestProcs, count)); err != nil {
+				t.Fatal(err)
+			}
 		}
 	})
 
 	t.Run("Basic", func(t *testing.T) {
 		for _, count := range TestCount {
-			q := ctor().(NonblockingMPMC)
+			q := ctor().(NonblockingMPMC[T])
 			if skipRedundant(q, count) {
 				continue
 			}
+			received := NewDelivery(2 * TestProcs)
 			ProducerConsumer(t,
 				TestProcs, TestProcs,
 				func(id int) error {
 					for i := 0; i < count; i++ {
-						if !MustSendIn(q, Value(id)<<32|Value(i+1), NonblockThreshold) {
+						Jitter()
+						if !MustSendIn[T](q, codec.Encode(int64(id)<<32|int64(i+1)), nonblockThreshold()) {
 							return fmt.Errorf("failed to send %v", i)
 						}
 					}
 					FlushSend(q)
 					return nil
 				}, func(id int) error {
-					latest := make([]Value, TestProcs)
+					latest := make([]int64, TestProcs)
 					for i := 0; i < count; i++ {
-						var val Value
-						if !MustRecvIn(q, &val, NonblockThreshold) {
+						Jitter()
+						var v T
+						if !MustRecvIn[T](q, &v, nonblockThreshold()) {
 							return fmt.Errorf("failed to get")
 						}
+						val := codec.Decode(v)
+						received.Add(id, val)
 
 						id, got := val>>32, val&0xFFFFFFFF
 						exp := latest[id]
@@ -476,6 +535,668 @@ func testNonblockMPMC(t *testing.T, caps Capability, ctor func() Queue) {
 					}
 					return nil
 				})
+			if err := received.Check(producerSequences(TestProcs, count)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+func testClose[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
+	t.Run("Drain", func(t *testing.T) {
+		q := ctor().(interface {
+			SPSC[T]
+			Closer
+		})
+		count := Cap(q)
+		if count > 8 {
+			count = 8
+		}
+
+		for i := 0; i < count; i++ {
+			if !q.Send(codec.Encode(int64(i))) {
+				t.Fatal("failed to send")
+			}
+		}
+		FlushSend(q)
+		q.Close()
+
+		if q.Send(codec.Encode(-1)) {
+			t.Fatal("send succeeded after close")
+		}
+
+		for i := 0; i < count; i++ {
+			var v T
+			if !q.Recv(&v) {
+				t.Fatalf("failed to drain %v", i)
+			}
+			if got := codec.Decode(v); got != int64(i) {
+				t.Fatalf("invalid value got %v, expected %v", got, i)
+			}
+		}
+		FlushRecv(q)
+
+		var v T
+		if q.Recv(&v) {
+			t.Fatal("recv succeeded after drain")
+		}
+	})
+
+	t.Run("FlushAfterClose", func(t *testing.T) {
+		q := ctor().(interface {
+			SPSC[T]
+			Closer
+		})
+		if _, ok := q.(Flusher); !ok {
+			t.Skip("doesn't have pending sends")
+		}
+		count := Cap(q)
+		if count > 8 {
+			count = 8
+		}
+
+		// sends were acknowledged, hence the values must not be lost
+		for i := 0; i < count; i++ {
+			if !q.Send(codec.Encode(int64(i))) {
+				t.Fatal("failed to send")
+			}
+		}
+		q.Close()
+		FlushSend(q)
+
+		for i := 0; i < count; i++ {
+			var v T
+			if !q.Recv(&v) {
+				t.Fatalf("failed to drain %v", i)
+			}
+			if got := codec.Decode(v); got != int64(i) {
+				t.Fatalf("invalid value got %v, expected %v", got, i)
+			}
+		}
+	})
+
+	t.Run("UnblockRecv", func(t *testing.T) {
+		q := ctor().(interface {
+			SPSC[T]
+			Closer
+		})
+
+		done := make(chan bool, 1)
+		go func() {
+			var v T
+			done <- q.Recv(&v)
+		}()
+		runtime.Gosched()
+		time.Sleep(time.Millisecond)
+
+		q.Close()
+		select {
+		case ok := <-done:
+			if ok {
+				t.Fatal("recv succeeded on closed queue")
+			}
+		case <-time.After(nonblockThreshold()):
+			t.Fatal("close did not unblock recv")
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		q := ctor().(interface {
+			SPSC[T]
+			Closer
+		})
+		if _, ok := q.(Flusher); ok {
+			t.Skip("sent values are visible only after flushing")
+		}
+		// sends racing with Close are more likely with parallel goroutines
+		defer pinProcs(ParallelProcs)()
+
+		np, nc := 1, 1
+		if caps.Has(CapBlockMPSC) {
+			np = LinearizeProcs
+		}
+		if caps.Has(CapBlockSPMC) {
+			nc = LinearizeProcs
+		}
+
+		// values that were successfully sent must be received,
+		// even when the consumers see the queue closed
+		sent := make([][]int64, np)
+		received := NewDelivery(np + nc)
+		var count int64
+		ProducerConsumer(t, np, nc, func(id int) error {
+			for i := 1; ; i++ {
+				v := int64(id)<<32 | int64(i)
+				if !q.Send(codec.Encode(v)) {
+					return nil
+				}
+				sent[id] = append(sent[id], v)
+			}
+		}, func(id int) error {
+			for {
+				var v T
+				if !q.Recv(&v) {
+					return nil
+				}
+				received.Add(id, codec.Decode(v))
+				if atomic.AddInt64(&count, 1) == int64(LinearizeCount) {
+					q.Close()
+				}
+			}
+		})
+
+		var all []int64
+		for _, values := range sent {
+			all = append(all, values...)
+		}
+		if err := received.Check(all); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if caps.Has(CapBounded) {
+		t.Run("UnblockSend", func(t *testing.T) {
+			q := ctor().(interface {
+				SPSC[T]
+				Bounded
+				Closer
+			})
+			capacity := q.Cap()
+
+			for i := 0; i < capacity; i++ {
+				if !q.Send(codec.Encode(0)) {
+					t.Fatal("failed to send")
+				}
+			}
+			FlushSend(q)
+
+			done := make(chan bool, 1)
+			go func() {
+				ok := q.Send(codec.Encode(0))
+				FlushSend(q)
+				done <- ok
+			}()
+			runtime.Gosched()
+			time.Sleep(time.Millisecond)
+
+			q.Close()
+			select {
+			case ok := <-done:
+				if ok {
+					t.Fatal("send succeeded on full closed queue")
+				}
+			case <-time.After(nonblockThreshold()):
+				t.Fatal("close did not unblock send")
+			}
+		})
+	}
+}
+
+func testNonblockClose[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
+	t.Run("Drain", func(t *testing.T) {
+		q := ctor().(interface {
+			NonblockingSPSC[T]
+			Closer
+		})
+		count := Cap(q)
+		if count > 8 {
+			count = 8
+		}
+
+		for i := 0; i < count; i++ {
+			if !q.TrySend(codec.Encode(int64(i))) {
+				t.Fatal("failed to send")
+			}
+		}
+		FlushSend(q)
+		q.Close()
+
+		if q.TrySend(codec.Encode(-1)) {
+			t.Fatal("send succeeded after close")
+		}
+
+		for i := 0; i < count; i++ {
+			var v T
+			if !q.TryRecv(&v) {
+				t.Fatalf("failed to drain %v", i)
+			}
+			if got := codec.Decode(v); got != int64(i) {
+				t.Fatalf("invalid value got %v, expected %v", got, i)
+			}
+		}
+		FlushRecv(q)
+
+		var v T
+		if q.TryRecv(&v) {
+			t.Fatal("recv succeeded after drain")
+		}
+	})
+}
+
+func testContext[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
+	const timeout = 10 * time.Millisecond
+
+	t.Run("RecvTimeout", func(t *testing.T) {
+		q := ctor().(SPSC[T])
+
+		var v T
+		start := time.Now()
+		if q.RecvTimeout(&v, timeout) {
+			t.Fatal("recv from empty succeeded")
+		}
+		if elapsed := time.Since(start); elapsed < timeout {
+			t.Fatalf("recv returned before timeout, after %v", elapsed)
+		}
+
+		if !q.Send(codec.Encode(1)) {
+			t.Fatal("failed to send")
+		}
+		FlushSend(q)
+		if !q.RecvTimeout(&v, nonblockThreshold()) {
+			t.Fatal("failed to recv")
+		}
+		if got := codec.Decode(v); got != 1 {
+			t.Fatalf("invalid value got %v, expected %v", got, 1)
+		}
+	})
+
+	t.Run("RecvCancel", func(t *testing.T) {
+		q := ctor().(SPSC[T])
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan bool, 1)
+		go func() {
+			var v T
+			done <- q.RecvContext(ctx, &v)
+		}()
+		runtime.Gosched()
+		time.Sleep(time.Millisecond)
+
+		cancel()
+		select {
+		case ok := <-done:
+			if ok {
+				t.Fatal("recv from empty succeeded")
+			}
+		case <-time.After(nonblockThreshold()):
+			t.Fatal("cancel did not unblock recv")
+		}
+
+		if !q.Send(codec.Encode(1)) {
+			t.Fatal("failed to send")
+		}
+		FlushSend(q)
+		var v T
+		if !q.Recv(&v) {
+			t.Fatal("failed to recv")
+		}
+		if got := codec.Decode(v); got != 1 {
+			t.Fatalf("invalid value got %v, expected %v", got, 1)
+		}
+	})
+
+	if !caps.Has(CapBounded) {
+		return
+	}
+
+	fill := func(t *testing.T, q SPSC[T]) int {
+		capacity := Cap(q)
+		for i := 0; i < capacity; i++ {
+			if !q.Send(codec.Encode(int64(i))) {
+				t.Fatal("failed to send")
+			}
+		}
+		FlushSend(q)
+		return capacity
+	}
+
+	t.Run("SendTimeout", func(t *testing.T) {
+		q := ctor().(SPSC[T])
+		fill(t, q)
+
+		start := time.Now()
+		if q.SendTimeout(codec.Encode(-1), timeout) {
+			t.Fatal("send to full succeeded")
+		}
+		if elapsed := time.Since(start); elapsed < timeout {
+			t.Fatalf("send returned before timeout, after %v", elapsed)
+		}
+
+		var v T
+		if !q.Recv(&v) {
+			t.Fatal("failed to recv")
+		}
+		FlushRecv(q)
+		if !q.SendTimeout(codec.Encode(-1), nonblockThreshold()) {
+			t.Fatal("failed to send")
+		}
+	})
+
+	t.Run("SendCancel", func(t *testing.T) {
+		q := ctor().(SPSC[T])
+		capacity := fill(t, q)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan bool, 1)
+		go func() {
+			done <- q.SendContext(ctx, codec.Encode(-1))
+		}()
+		runtime.Gosched()
+		time.Sleep(time.Millisecond)
+
+		cancel()
+		select {
+		case ok := <-done:
+			if ok {
+				t.Fatal("send to full succeeded")
+			}
+		case <-time.After(nonblockThreshold()):
+			t.Fatal("cancel did not unblock send")
+		}
+
+		// the canceled send must not leave a gap in the queue
+		var v T
+		if !q.Recv(&v) {
+			t.Fatal("failed to recv")
+		}
+		FlushRecv(q)
+		if got := codec.Decode(v); got != 0 {
+			t.Fatalf("invalid value got %v, expected %v", got, 0)
+		}
+		if !q.Send(codec.Encode(int64(capacity))) {
+			t.Fatal("failed to send")
+		}
+		FlushSend(q)
+		for i := 1; i <= capacity; i++ {
+			if !q.Recv(&v) {
+				t.Fatal("failed to recv")
+			}
+			FlushRecv(q)
+			if got := codec.Decode(v); got != int64(i) {
+				t.Fatalf("invalid value got %v, expected %v", got, i)
+			}
+		}
+	})
+}
+
+func testBatch[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
+	// send uses SendBatch with varying batch sizes, when supported
+	send := func(q SPSC[T], values []int64) error {
+		if !caps.Has(CapBatchSend) {
+			for _, v := range values {
+				if !q.Send(codec.Encode(v)) {
+					return fmt.Errorf("failed to send %v", v)
+				}
+			}
+			FlushSend(q)
+			return nil
+		}
+
+		batch := make([]T, 0, 13)
+		for len(values) > 0 {
+			n := len(values)%cap(batch) + 1
+			if n > len(values) {
+				n = len(values)
+			}
+			batch = batch[:0]
+			for _, v := range values[:n] {
+				batch = append(batch, codec.Encode(v))
+			}
+			if sent := q.(BatchSender[T]).SendBatch(batch); sent != n {
+				return fmt.Errorf("failed to send batch, sent %v expected %v", sent, n)
+			}
+			values = values[n:]
+		}
+		return nil
+	}
+
+	// recv uses RecvBatch and RecvBatchFunc alternatingly, when supported
+	recvs := 0
+	recv := func(q SPSC[T], fn func(v int64)) error {
+		if !caps.Has(CapBatchRecv) {
+			var v T
+			if !q.Recv(&v) {
+				return fmt.Errorf("failed to recv")
+			}
+			fn(codec.Decode(v))
+			return nil
+		}
+
+		recvs++
+		batch := q.(BatchReceiver[T])
+		if recvs%2 == 0 {
+			if batch.RecvBatchFunc(func(v T) { fn(codec.Decode(v)) }) == 0 {
+				return fmt.Errorf("failed to recv batch func")
+			}
+			return nil
+		}
+
+		var buf [5]T
+		n := batch.RecvBatch(buf[:])
+		if n == 0 {
+			return fmt.Errorf("failed to recv batch")
+		}
+		for _, v := range buf[:n] {
+			fn(codec.Decode(v))
+		}
+		return nil
+	}
+
+	t.Run("Basic", func(t *testing.T) {
+		for _, count := range TestCount {
+			q := ctor().(SPSC[T])
+			if skipRedundant(q, count) {
+				continue
+			}
+
+			ProducerConsumer(t, 1, 1, func(int) error {
+				values := make([]int64, count)
+				for i := range values {
+					values[i] = int64(i + 1)
+				}
+				return send(q, values)
+			}, func(int) error {
+				exp := int64(1)
+				for exp <= int64(count) {
+					var err error
+					if rerr := recv(q, func(got int64) {
+						if got != exp && err == nil {
+							err = fmt.Errorf("invalid value got %v, expected %v", got, exp)
+						}
+						exp++
+					}); rerr != nil {
+						return rerr
+					}
+					if err != nil {
+						return err
+					}
+				}
+				if exp != int64(count)+1 {
+					return fmt.Errorf("received %v values, expected %v", exp-1, count)
+				}
+				return nil
+			})
 		}
 	})
+
+	if caps.Has(CapBlockMPSC) {
+		t.Run("MPSC", func(t *testing.T) {
+			for _, count := range TestCount {
+				q := ctor().(SPSC[T])
+				if skipRedundant(q, count) {
+					continue
+				}
+
+				ProducerConsumer(t, TestProcs, 1, func(id int) error {
+					values := make([]int64, count)
+					for i := range values {
+						values[i] = int64(id)<<32 | int64(i)
+					}
+					return send(q, values)
+				}, func(int) error {
+					exps := make([]int64, TestProcs)
+					total := 0
+					for total < count*TestProcs {
+						var err error
+						if rerr := recv(q, func(val int64) {
+							total++
+							id, got := val>>32, val&0xFFFFFFFF
+							if err != nil {
+								return
+							}
+							if id < 0 || id >= int64(TestProcs) {
+								err = fmt.Errorf("invalid producer %v", id)
+								return
+							}
+							if exps[id] != got {
+								err = fmt.Errorf("invalid value got %v, expected %v", got, exps[id])
+								return
+							}
+							exps[id]++
+						}); rerr != nil {
+							return rerr
+						}
+						if err != nil {
+							return err
+						}
+					}
+					if total != count*TestProcs {
+						return fmt.Errorf("received %v values, expected %v", total, count*TestProcs)
+					}
+					return nil
+				})
+			}
+		})
+	}
+
+	if caps.Has(CapClose | CapBatchRecv) {
+		t.Run("Close", func(t *testing.T) {
+			q := ctor().(interface {
+				SPSC[T]
+				BatchReceiver[T]
+				Closer
+			})
+			count := Cap(q)
+			if count > 8 {
+				count = 8
+			}
+
+			values := make([]int64, count)
+			for i := range values {
+				values[i] = int64(i)
+			}
+			if err := send(q, values); err != nil {
+				t.Fatal(err)
+			}
+			q.Close()
+
+			if caps.Has(CapBatchSend) {
+				if sent := q.(BatchSender[T]).SendBatch([]T{codec.Encode(-1)}); sent != 0 {
+					t.Fatal("send succeeded after close")
+				}
+			}
+
+			received := 0
+			for received < count {
+				n := q.RecvBatchFunc(func(v T) {
+					if got := codec.Decode(v); got != int64(received) {
+						t.Errorf("invalid value got %v, expected %v", got, received)
+					}
+					received++
+				})
+				if n == 0 {
+					t.Fatalf("failed to drain %v", received)
+				}
+			}
+
+			var buf [4]T
+			if n := q.RecvBatch(buf[:]); n != 0 {
+				t.Fatal("recv succeeded after drain")
+			}
+		})
+	}
+}
+
+func testLinearizable[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
+	np, nc := 1, 1
+	if caps.Has(CapBlockMPSC) {
+		np = LinearizeProcs
+	}
+	if caps.Has(CapBlockSPMC) {
+		nc = LinearizeProcs
+	}
+	total := LinearizeCount * np * nc
+
+	h := NewHistory(np + nc)
+	q := ctor()
+	ProducerConsumer(t,
+		np, nc,
+		func(id int) error {
+			r := Record(h, id, q, codec)
+			for i := 0; i < total/np; i++ {
+				Jitter()
+				if !r.Send(int64(id)<<32 | int64(i+1)) {
+					return fmt.Errorf("failed to send %v", i)
+				}
+			}
+			return nil
+		}, func(id int) error {
+			r := Record(h, id, q, codec)
+			for i := 0; i < total/nc; i++ {
+				Jitter()
+				if _, ok := r.Recv(); !ok {
+					return fmt.Errorf("failed to get")
+				}
+			}
+			return nil
+		})
+
+	if err := CheckFIFO(h.Ops()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testNonblockLinearizable[T any](t *testing.T, caps Capability, codec Codec[T], ctor func() Queue) {
+	np, nc := 1, 1
+	if caps.Has(CapNonblockMPSC) {
+		np = LinearizeProcs
+	}
+	if caps.Has(CapNonblockSPMC) {
+		nc = LinearizeProcs
+	}
+	total := LinearizeCount * np * nc
+
+	h := NewHistory(np + nc)
+	q := ctor()
+	ProducerConsumer(t,
+		np, nc,
+		func(id int) error {
+			r := Record(h, id, q, codec)
+			for i := 0; i < total/np; i++ {
+				Jitter()
+				for !r.TrySend(int64(id)<<32 | int64(i+1)) {
+					runtime.Gosched()
+				}
+			}
+			return nil
+		}, func(id int) error {
+			r := Record(h, id, q, codec)
+			for i := 0; i < total/nc; i++ {
+				Jitter()
+				for {
+					// failed receives behind a stalled send are not checked,
+					// see CheckFIFO
+					if _, ok := r.TryRecv(); ok {
+						break
+					}
+					// keep the history small
+					runtime.Gosched()
+				}
+			}
+			return nil
+		})
+
+	if err := CheckFIFO(h.Ops()); err != nil {
+		t.Fatal(err)
+	}
 }
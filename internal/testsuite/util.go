@@ -40,7 +40,8 @@ func FlushRecv(q Queue) {
 	}
 }
 
-func MustSendIn(q NonblockingSPSC, v Value, dur time.Duration) bool {
+// MustSendIn retries TrySend until it succeeds or dur has elapsed.
+func MustSendIn[T any](q NonblockingSPSC[T], v T, dur time.Duration) bool {
 	if q.TrySend(v) {
 		return true
 	}
@@ -61,7 +62,8 @@ func MustSendIn(q NonblockingSPSC, v Value, dur time.Duration) bool {
 	}
 }
 
-func MustRecvIn(q NonblockingSPSC, v *Value, dur time.Duration) bool {
+// MustRecvIn retries TryRecv until it succeeds or dur has elapsed.
+func MustRecvIn[T any](q NonblockingSPSC[T], v *T, dur time.Duration) bool {
 	if q.TryRecv(v) {
 		return true
 	}
@@ -102,6 +104,7 @@ func ProducerConsumer(t *testing.T, NP, NC int, producer, consumer func(id int)
 				errs <- err
 			}()
 
+			Jitter()
 			if id < NP {
 				err = producer(id)
 			} else {
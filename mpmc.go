@@ -0,0 +1,60 @@
+package queue
+
+import "loov.dev/queue/internal/extqueue"
+
+// MPMCqGo is a bounded lock-free MPMC queue, which sleeps when it is full or empty.
+type MPMCqGo[T any] struct{ *extqueue.MPMCqGo[T] }
+
+// NewMPMCqGo creates a new MPMCqGo queue
+func NewMPMCqGo[T any](size int, opts ...Option) MPMCqGo[T] {
+	return MPMCqGo[T]{extqueue.NewMPMCqGo[T](size, opts...)}
+}
+
+// MPMCqpGo is a bounded lock-free MPMC queue, which sleeps when it is full or empty.
+// Values are padded to a cacheline.
+type MPMCqpGo[T any] struct{ *extqueue.MPMCqpGo[T] }
+
+// NewMPMCqpGo creates a new MPMCqpGo queue
+func NewMPMCqpGo[T any](size int, opts ...Option) MPMCqpGo[T] {
+	return MPMCqpGo[T]{extqueue.NewMPMCqpGo[T](size, opts...)}
+}
+
+// MPMCqsDV is a bounded spinning MPMC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+type MPMCqsDV[T any] struct{ *extqueue.MPMCqsDV[T] }
+
+// NewMPMCqsDV creates a new MPMCqsDV queue
+func NewMPMCqsDV[T any](size int, opts ...Option) MPMCqsDV[T] {
+	return MPMCqsDV[T]{extqueue.NewMPMCqsDV[T](size, opts...)}
+}
+
+// MPMCqspDV is a bounded spinning MPMC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// Values are padded to a cacheline.
+type MPMCqspDV[T any] struct{ *extqueue.MPMCqspDV[T] }
+
+// NewMPMCqspDV creates a new MPMCqspDV queue
+func NewMPMCqspDV[T any](size int, opts ...Option) MPMCqspDV[T] {
+	return MPMCqspDV[T]{extqueue.NewMPMCqspDV[T](size, opts...)}
+}
+
+// MPMCqDV is a bounded MPMC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// which spins briefly and then parks when it is full or empty.
+type MPMCqDV[T any] struct{ *extqueue.MPMCqDV[T] }
+
+// NewMPMCqDV creates a new MPMCqDV queue
+func NewMPMCqDV[T any](size int, opts ...Option) MPMCqDV[T] {
+	return MPMCqDV[T]{extqueue.NewMPMCqDV[T](size, opts...)}
+}
+
+// MPMCqpDV is a bounded MPMC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// which spins briefly and then parks when it is full or empty.
+// Values are padded to a cacheline.
+type MPMCqpDV[T any] struct{ *extqueue.MPMCqpDV[T] }
+
+// NewMPMCqpDV creates a new MPMCqpDV queue
+func NewMPMCqpDV[T any](size int, opts ...Option) MPMCqpDV[T] {
+	return MPMCqpDV[T]{extqueue.NewMPMCqpDV[T](size, opts...)}
+}
@@ -0,0 +1,61 @@
+package queue
+
+import "loov.dev/queue/internal/extqueue"
+
+// MPSCqsDV is a bounded spinning MPSC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+type MPSCqsDV[T any] struct{ *extqueue.MPSCqsDV[T] }
+
+// NewMPSCqsDV creates a new MPSCqsDV queue
+func NewMPSCqsDV[T any](size int, opts ...Option) MPSCqsDV[T] {
+	return MPSCqsDV[T]{extqueue.NewMPSCqsDV[T](size, opts...)}
+}
+
+// MPSCqspDV is a bounded spinning MPSC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// Values are padded to a cacheline.
+type MPSCqspDV[T any] struct{ *extqueue.MPSCqspDV[T] }
+
+// NewMPSCqspDV creates a new MPSCqspDV queue
+func NewMPSCqspDV[T any](size int, opts ...Option) MPSCqspDV[T] {
+	return MPSCqspDV[T]{extqueue.NewMPSCqspDV[T](size, opts...)}
+}
+
+// MPSCqDV is a bounded MPSC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// which spins briefly and then parks when it is full or empty.
+type MPSCqDV[T any] struct{ *extqueue.MPSCqDV[T] }
+
+// NewMPSCqDV creates a new MPSCqDV queue
+func NewMPSCqDV[T any](size int, opts ...Option) MPSCqDV[T] {
+	return MPSCqDV[T]{extqueue.NewMPSCqDV[T](size, opts...)}
+}
+
+// MPSCqpDV is a bounded MPSC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// which spins briefly and then parks when it is full or empty.
+// Values are padded to a cacheline.
+type MPSCqpDV[T any] struct{ *extqueue.MPSCqpDV[T] }
+
+// NewMPSCqpDV creates a new MPSCqpDV queue
+func NewMPSCqpDV[T any](size int, opts ...Option) MPSCqpDV[T] {
+	return MPSCqpDV[T]{extqueue.NewMPSCqpDV[T](size, opts...)}
+}
+
+// MPSCnsDV is an unbounded spinning MPSC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/non-intrusive-mpsc-node-based-queue
+type MPSCnsDV[T any] struct{ *extqueue.MPSCnsDV[T] }
+
+// NewMPSCnsDV creates a new MPSCnsDV queue
+func NewMPSCnsDV[T any]() MPSCnsDV[T] {
+	return MPSCnsDV[T]{extqueue.NewMPSCnsDV[T]()}
+}
+
+// MPSCnsSG is an unbounded spinning MPSC queue, which stores values
+// in linked fixed-size segments and reuses drained segments.
+type MPSCnsSG[T any] struct{ *extqueue.MPSCnsSG[T] }
+
+// NewMPSCnsSG creates a new MPSCnsSG queue
+func NewMPSCnsSG[T any]() MPSCnsSG[T] {
+	return MPSCnsSG[T]{extqueue.NewMPSCnsSG[T]()}
+}
@@ -0,0 +1,36 @@
+package queue
+
+import "loov.dev/queue/internal/extqueue"
+
+// Overflow decides what Send does when a bounded queue is full.
+//
+// TrySend is not affected by the policy, it always fails when the queue is full.
+type Overflow = extqueue.Overflow
+
+const (
+	// OverflowBlock waits until the queue has space, this is the default.
+	OverflowBlock = extqueue.OverflowBlock
+	// OverflowFail makes Send return false.
+	OverflowFail = extqueue.OverflowFail
+	// OverflowDropNewest drops the value being sent.
+	OverflowDropNewest = extqueue.OverflowDropNewest
+	// OverflowDropOldest drops the oldest value in the queue to make space,
+	// it's only supported by multi-consumer queues.
+	OverflowDropOldest = extqueue.OverflowDropOldest
+)
+
+// WithOverflow sets what Send does when a bounded queue is full.
+//
+// It is supported by MPMCqGo, MPMCqpGo, MPMCqsDV and MPMCqDV,
+// other queues panic when a policy other than OverflowBlock is set.
+func WithOverflow(policy Overflow) Option {
+	return extqueue.WithOverflow(policy)
+}
+
+// WithOverflowHandler makes Send drop values that don't fit in a full queue and
+// call fn with them. fn must match the value type of the queue.
+//
+// It is supported by the same queues as WithOverflow.
+func WithOverflowHandler[T any](fn func(v T)) Option {
+	return extqueue.WithOverflowHandler(fn)
+}
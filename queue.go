@@ -0,0 +1,136 @@
+// Package queue contains concurrent queue implementations.
+//
+// The implementations are selected from loov.dev/queue/internal/extqueue,
+// which contains many more experimental algorithms, tests and benchmarks.
+// Only the implementations that have proven to be reliable are exported here.
+//
+// All names follow a convention: "[SM]P[SM]C[sw]?i?p?[rnacq]<variant>"
+//
+//	[SM]P:
+//	   supports either single `S` or multiple `M` concurrent producers
+//
+//	[SM]C:
+//	   supports either single `S` or multiple `M` concurrent consumers
+//
+//	[rnacq]: buffer implementation
+//	   `r` dynamically sized ring buffer
+//	   `n` node based,
+//	   `a` fixed size array based,
+//	   `c` channel based,
+//	   `q` dynamically sized ring buffer with sequence number.
+//
+//	[sw]?: waiting behavior
+//	   `` : when it is a waiting implementation (no CPU burn)
+//	   `s`: when it is a spinning implementation,
+//	   `w`: when it is partially spinning and partially waiting
+//
+//	p?: memory usage
+//	   `` : usually one value per bounded size (sometimes with one uint64 or uint32)
+//	   `p`: value padded to a cacheline
+//
+//	<variant>:
+//	   special variant identifier for a particular implementation,
+//	   which indicates either base implementation author / paper / code.
+//
+// See loov.dev/queue/internal/extqueue for a guideline on selecting an implementation.
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// Bounded returns number of elements that can be added until the queue
+// either Send blocks or TrySend fails.
+type Bounded interface {
+	Cap() int
+}
+
+// Closer is implemented by queues that can be closed.
+//
+// After Close, Send and TrySend return false, values that are already
+// in the queue can still be received, and Recv returns false once the
+// queue has been drained.
+type Closer interface {
+	Close()
+}
+
+// SPSC is a blocking single-producer and single-consumer queue,
+// which waits until Send or Recv succeeds
+type SPSC[T any] interface {
+	// Send puts a value to a queue,
+	// returns false when the queue has been closed
+	Send(v T) bool
+	// Recv takes a value from the queue
+	// returns false when the queue has been closed
+	Recv(v *T) bool
+
+	// SendContext puts a value to a queue,
+	// returns false when the queue has been closed or ctx is done
+	SendContext(ctx context.Context, v T) bool
+	// RecvContext takes a value from the queue,
+	// returns false when the queue has been closed or ctx is done
+	RecvContext(ctx context.Context, v *T) bool
+
+	// SendTimeout puts a value to a queue,
+	// returns false when the queue has been closed or timeout elapsed
+	SendTimeout(v T, timeout time.Duration) bool
+	// RecvTimeout takes a value from the queue,
+	// returns false when the queue has been closed or timeout elapsed
+	RecvTimeout(v *T, timeout time.Duration) bool
+}
+
+// MPSC is a blocking multi-producer and single-consumer queue,
+// which waits until Send or Recv succeeds
+type MPSC[T any] interface {
+	SPSC[T]
+	MultipleProducers()
+}
+
+// SPMC is a blocking single-producer and multi-consumer queue,
+// which waits until Send or Recv succeeds
+type SPMC[T any] interface {
+	SPSC[T]
+	MultipleConsumers()
+}
+
+// MPMC is a blocking multi-producer and multi-consumer queue,
+// which waits until Send or Recv succeeds
+type MPMC[T any] interface {
+	SPSC[T]
+	MultipleProducers()
+	MultipleConsumers()
+}
+
+// NonblockingSPSC is a non-blocking single-producer and single-consumer queue,
+// which returns in case Send or Recv cannot be completed
+type NonblockingSPSC[T any] interface {
+	// TrySend tries to put a value to a queue
+	// returns false when the queue if full or closed
+	TrySend(v T) bool
+	// TryRecv tries to take a value from a queue
+	// returns false when the queue if empty or closed
+	TryRecv(v *T) bool
+}
+
+// NonblockingMPSC is a non-blocking multi-producer and single-consumer queue,
+// which returns in case Send or Recv cannot be completed
+type NonblockingMPSC[T any] interface {
+	NonblockingSPSC[T]
+	MultipleProducers()
+}
+
+// NonblockingSPMC is a non-blocking single-producer and multi-consumer queue,
+// which returns in case Send or Recv cannot be completed
+type NonblockingSPMC[T any] interface {
+	NonblockingSPSC[T]
+	MultipleConsumers()
+}
+
+// NonblockingMPMC is a non-blocking multi-producer and multi-consumer queue,
+// which returns in case Send or Recv cannot be completed
+type NonblockingMPMC[T any] interface {
+	NonblockingSPSC[T]
+	MultipleProducers()
+	MultipleConsumers()
+}
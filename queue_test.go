@@ -0,0 +1,132 @@
+package queue_test
+
+import (
+	"fmt"
+	"sync"
+
+	"loov.dev/queue"
+)
+
+var (
+	_ queue.MPMC[int]            = queue.NewMPMCqGo[int](8)
+	_ queue.NonblockingMPMC[int] = queue.NewMPMCqGo[int](8)
+	_ queue.MPMC[int]            = queue.NewMPMCqpGo[int](8)
+	_ queue.NonblockingMPMC[int] = queue.NewMPMCqpGo[int](8)
+	_ queue.MPMC[int]            = queue.NewMPMCqsDV[int](8)
+	_ queue.NonblockingMPMC[int] = queue.NewMPMCqsDV[int](8)
+	_ queue.MPMC[int]            = queue.NewMPMCqspDV[int](8)
+	_ queue.NonblockingMPMC[int] = queue.NewMPMCqspDV[int](8)
+	_ queue.MPMC[int]            = queue.NewMPMCqDV[int](8)
+	_ queue.NonblockingMPMC[int] = queue.NewMPMCqDV[int](8)
+	_ queue.MPMC[int]            = queue.NewMPMCqpDV[int](8)
+	_ queue.NonblockingMPMC[int] = queue.NewMPMCqpDV[int](8)
+
+	_ queue.MPSC[int]            = queue.NewMPSCqsDV[int](8)
+	_ queue.NonblockingMPSC[int] = queue.NewMPSCqsDV[int](8)
+	_ queue.MPSC[int]            = queue.NewMPSCqspDV[int](8)
+	_ queue.NonblockingMPSC[int] = queue.NewMPSCqspDV[int](8)
+	_ queue.MPSC[int]            = queue.NewMPSCqDV[int](8)
+	_ queue.NonblockingMPSC[int] = queue.NewMPSCqDV[int](8)
+	_ queue.MPSC[int]            = queue.NewMPSCqpDV[int](8)
+	_ queue.NonblockingMPSC[int] = queue.NewMPSCqpDV[int](8)
+	_ queue.MPSC[int]            = queue.NewMPSCnsDV[int]()
+	_ queue.NonblockingMPSC[int] = queue.NewMPSCnsDV[int]()
+	_ queue.MPSC[int]            = queue.NewMPSCnsSG[int]()
+	_ queue.NonblockingMPSC[int] = queue.NewMPSCnsSG[int]()
+
+	_ queue.SPMC[int]            = queue.NewSPMCqsDV[int](8)
+	_ queue.NonblockingSPMC[int] = queue.NewSPMCqsDV[int](8)
+	_ queue.SPMC[int]            = queue.NewSPMCqspDV[int](8)
+	_ queue.NonblockingSPMC[int] = queue.NewSPMCqspDV[int](8)
+	_ queue.SPMC[int]            = queue.NewSPMCqDV[int](8)
+	_ queue.NonblockingSPMC[int] = queue.NewSPMCqDV[int](8)
+	_ queue.SPMC[int]            = queue.NewSPMCqpDV[int](8)
+	_ queue.NonblockingSPMC[int] = queue.NewSPMCqpDV[int](8)
+
+	_ queue.SPSC[int]            = queue.NewSPSCqsDV[int](8)
+	_ queue.NonblockingSPSC[int] = queue.NewSPSCqsDV[int](8)
+	_ queue.SPSC[int]            = queue.NewSPSCqspDV[int](8)
+	_ queue.NonblockingSPSC[int] = queue.NewSPSCqspDV[int](8)
+	_ queue.SPSC[int]            = queue.NewSPSCqDV[int](8)
+	_ queue.NonblockingSPSC[int] = queue.NewSPSCqDV[int](8)
+	_ queue.SPSC[int]            = queue.NewSPSCqpDV[int](8)
+	_ queue.NonblockingSPSC[int] = queue.NewSPSCqpDV[int](8)
+	_ queue.SPSC[int]            = queue.NewSPSCnsDV[int]()
+	_ queue.NonblockingSPSC[int] = queue.NewSPSCnsDV[int]()
+	_ queue.SPSC[int]            = queue.NewSPSCnsSG[int]()
+	_ queue.NonblockingSPSC[int] = queue.NewSPSCnsSG[int]()
+
+	_ queue.Bounded = queue.NewMPMCqGo[int](8)
+	_ queue.Bounded = queue.NewSPSCqsDV[int](8)
+
+	_ queue.Closer = queue.NewMPMCqGo[int](8)
+	_ queue.Closer = queue.NewMPMCqsDV[int](8)
+	_ queue.Closer = queue.NewMPMCqDV[int](8)
+	_ queue.Closer = queue.NewMPSCnsDV[int]()
+	_ queue.Closer = queue.NewSPSCnsDV[int]()
+	_ queue.Closer = queue.NewMPSCnsSG[int]()
+	_ queue.Closer = queue.NewSPSCnsSG[int]()
+)
+
+func ExampleNewMPMCqGo() {
+	q := queue.NewMPMCqGo[int](16)
+
+	var wg sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= 100; i++ {
+				q.Send(i)
+			}
+		}()
+	}
+
+	total := 0
+	for i := 0; i < 4*100; i++ {
+		var v int
+		q.Recv(&v)
+		total += v
+	}
+	wg.Wait()
+
+	fmt.Println(total)
+	// Output: 20200
+}
+
+func ExampleWithWaitStrategy() {
+	q := queue.NewSPSCqsDV[int](4, queue.WithWaitStrategy(queue.Hybrid(100)))
+
+	go func() {
+		for i := 1; i <= 100; i++ {
+			q.Send(i)
+		}
+		q.Close()
+	}()
+
+	total := 0
+	var v int
+	for q.Recv(&v) {
+		total += v
+	}
+
+	fmt.Println(total)
+	// Output: 5050
+}
+
+func ExampleWithOverflow() {
+	q := queue.NewMPMCqGo[int](4, queue.WithOverflow(queue.OverflowDropOldest))
+
+	for i := 1; i <= 10; i++ {
+		q.Send(i)
+	}
+	q.Close()
+
+	var values []int
+	var v int
+	for q.Recv(&v) {
+		values = append(values, v)
+	}
+	fmt.Println(values, "dropped", q.Overflowed())
+	// Output: [7 8 9 10] dropped 6
+}
@@ -0,0 +1,43 @@
+package queue
+
+import "loov.dev/queue/internal/extqueue"
+
+// SPMCqsDV is a bounded spinning SPMC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+type SPMCqsDV[T any] struct{ *extqueue.SPMCqsDV[T] }
+
+// NewSPMCqsDV creates a new SPMCqsDV queue
+func NewSPMCqsDV[T any](size int, opts ...Option) SPMCqsDV[T] {
+	return SPMCqsDV[T]{extqueue.NewSPMCqsDV[T](size, opts...)}
+}
+
+// SPMCqspDV is a bounded spinning SPMC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// Values are padded to a cacheline.
+type SPMCqspDV[T any] struct{ *extqueue.SPMCqspDV[T] }
+
+// NewSPMCqspDV creates a new SPMCqspDV queue
+func NewSPMCqspDV[T any](size int, opts ...Option) SPMCqspDV[T] {
+	return SPMCqspDV[T]{extqueue.NewSPMCqspDV[T](size, opts...)}
+}
+
+// SPMCqDV is a bounded SPMC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// which spins briefly and then parks when it is full or empty.
+type SPMCqDV[T any] struct{ *extqueue.SPMCqDV[T] }
+
+// NewSPMCqDV creates a new SPMCqDV queue
+func NewSPMCqDV[T any](size int, opts ...Option) SPMCqDV[T] {
+	return SPMCqDV[T]{extqueue.NewSPMCqDV[T](size, opts...)}
+}
+
+// SPMCqpDV is a bounded SPMC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// which spins briefly and then parks when it is full or empty.
+// Values are padded to a cacheline.
+type SPMCqpDV[T any] struct{ *extqueue.SPMCqpDV[T] }
+
+// NewSPMCqpDV creates a new SPMCqpDV queue
+func NewSPMCqpDV[T any](size int, opts ...Option) SPMCqpDV[T] {
+	return SPMCqpDV[T]{extqueue.NewSPMCqpDV[T](size, opts...)}
+}
@@ -0,0 +1,61 @@
+package queue
+
+import "loov.dev/queue/internal/extqueue"
+
+// SPSCqsDV is a bounded spinning SPSC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+type SPSCqsDV[T any] struct{ *extqueue.SPSCqsDV[T] }
+
+// NewSPSCqsDV creates a new SPSCqsDV queue
+func NewSPSCqsDV[T any](size int, opts ...Option) SPSCqsDV[T] {
+	return SPSCqsDV[T]{extqueue.NewSPSCqsDV[T](size, opts...)}
+}
+
+// SPSCqspDV is a bounded spinning SPSC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// Values are padded to a cacheline.
+type SPSCqspDV[T any] struct{ *extqueue.SPSCqspDV[T] }
+
+// NewSPSCqspDV creates a new SPSCqspDV queue
+func NewSPSCqspDV[T any](size int, opts ...Option) SPSCqspDV[T] {
+	return SPSCqspDV[T]{extqueue.NewSPSCqspDV[T](size, opts...)}
+}
+
+// SPSCqDV is a bounded SPSC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// which spins briefly and then parks when it is full or empty.
+type SPSCqDV[T any] struct{ *extqueue.SPSCqDV[T] }
+
+// NewSPSCqDV creates a new SPSCqDV queue
+func NewSPSCqDV[T any](size int, opts ...Option) SPSCqDV[T] {
+	return SPSCqDV[T]{extqueue.NewSPSCqDV[T](size, opts...)}
+}
+
+// SPSCqpDV is a bounded SPSC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+// which spins briefly and then parks when it is full or empty.
+// Values are padded to a cacheline.
+type SPSCqpDV[T any] struct{ *extqueue.SPSCqpDV[T] }
+
+// NewSPSCqpDV creates a new SPSCqpDV queue
+func NewSPSCqpDV[T any](size int, opts ...Option) SPSCqpDV[T] {
+	return SPSCqpDV[T]{extqueue.NewSPSCqpDV[T](size, opts...)}
+}
+
+// SPSCnsDV is an unbounded spinning SPSC queue based on
+// http://www.1024cores.net/home/lock-free-algorithms/queues/unbounded-spsc-queue
+type SPSCnsDV[T any] struct{ *extqueue.SPSCnsDV[T] }
+
+// NewSPSCnsDV creates a new SPSCnsDV queue
+func NewSPSCnsDV[T any]() SPSCnsDV[T] {
+	return SPSCnsDV[T]{extqueue.NewSPSCnsDV[T]()}
+}
+
+// SPSCnsSG is an unbounded spinning SPSC queue, which stores values
+// in linked fixed-size segments and reuses drained segments.
+type SPSCnsSG[T any] struct{ *extqueue.SPSCnsSG[T] }
+
+// NewSPSCnsSG creates a new SPSCnsSG queue
+func NewSPSCnsSG[T any]() SPSCnsSG[T] {
+	return SPSCnsSG[T]{extqueue.NewSPSCnsSG[T]()}
+}
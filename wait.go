@@ -0,0 +1,42 @@
+package queue
+
+import "loov.dev/queue/internal/extqueue"
+
+// WaitStrategy decides how a blocking operation waits for the queue
+// to become non-full or non-empty.
+type WaitStrategy = extqueue.WaitStrategy
+
+// Waiter waits for a single queue condition, e.g. "not full" or "not empty".
+type Waiter = extqueue.Waiter
+
+// WaitFunc is a spinning wait strategy that calls the func on every iteration.
+type WaitFunc = extqueue.WaitFunc
+
+// Option configures a queue.
+type Option = extqueue.Option
+
+var (
+	// BusySpin spins without ever yielding the processor.
+	BusySpin = extqueue.BusySpin
+	// Spin spins and yields the processor every 256 iterations.
+	Spin = extqueue.Spin
+	// Yield yields the processor on every iteration.
+	Yield = extqueue.Yield
+	// Backoff spins, then yields and finally sleeps with exponentially
+	// increasing durations up to 128µs.
+	Backoff = extqueue.Backoff
+	// Park parks the goroutine until the condition is notified.
+	Park = extqueue.Park
+)
+
+// Hybrid spins the specified number of iterations and then parks the goroutine
+// until the condition is notified.
+func Hybrid(spins int) WaitStrategy { return extqueue.Hybrid(spins) }
+
+// WithWaitStrategy sets how a bounded queue waits when it is full or empty.
+//
+// MPMCqGo and MPMCqpGo always sleep until the opposite side wakes them up,
+// hence they panic when a wait strategy is set.
+func WithWaitStrategy(strategy WaitStrategy) Option {
+	return extqueue.WithWaitStrategy(strategy)
+}